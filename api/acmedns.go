@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/internal/secret"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"github.com/miekg/dns"
+)
+
+// acmeDNSAnswers is the number of TXT records kept per zone. acme-dns keeps the two most recent values so that a
+// certificate covering both example.com and *.example.com can be validated, and clients never remove values.
+const acmeDNSAnswers = 2
+
+// acmeDNSTXTLength is the length of a base64url-encoded SHA-256 digest, which is what ACME DNS-01 challenges use.
+const acmeDNSTXTLength = 43
+
+// acmeDNSRegisterRequest is the optional request body of /register.
+type acmeDNSRegisterRequest struct {
+	AllowFrom []string `json:"allowfrom"`
+}
+
+// acmeDNSAccount is the response of /register. The field names match acme-dns so that existing clients can use it.
+type acmeDNSAccount struct {
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	FullDomain string   `json:"fulldomain"`
+	Subdomain  string   `json:"subdomain"`
+	AllowFrom  []string `json:"allowfrom"`
+}
+
+// acmeDNSUpdateRequest is the request body of /update.
+type acmeDNSUpdateRequest struct {
+	Subdomain string `json:"subdomain"`
+	TXT       string `json:"txt"`
+}
+
+// acmeDNSUpdateResponse is the response of /update.
+type acmeDNSUpdateResponse struct {
+	TXT string `json:"txt"`
+}
+
+// acmeDNSErrorResponse is the error body acme-dns clients expect.
+type acmeDNSErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// acmeDNSErrors maps error codes to the HTTP status codes and error strings used by acme-dns.
+var acmeDNSErrors = map[string]struct {
+	status  int
+	message string
+}{
+	ErrUnauthorized.GetCode():             {http.StatusUnauthorized, "forbidden"},
+	ErrInvalidRequest.GetCode():           {http.StatusBadRequest, "malformed_json_payload"},
+	ErrInvalidSubdomain.GetCode():         {http.StatusBadRequest, "bad_subdomain"},
+	ErrInvalidTXT.GetCode():               {http.StatusBadRequest, "bad_txt"},
+	backend.ErrInvalidAllowFrom.GetCode(): {http.StatusBadRequest, "invalid_allowfrom_cidr"},
+}
+
+func (s *server) registerACMEDNS(mux *http.ServeMux) {
+	handle := func(pattern string, handler func(w http.ResponseWriter, r *http.Request) error) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if err := handler(w, r); err != nil {
+				s.writeACMEDNSError(r.Context(), w, err)
+			}
+		})
+	}
+	if s.config.ACMEDNS.Registration {
+		handle("POST /register", s.acmeDNSRegister)
+	}
+	handle("POST /update", s.acmeDNSUpdate)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// acmeDNSZone returns the zone name of an acme-dns subdomain.
+func (s *server) acmeDNSZone(subdomain string) string {
+	return subdomain + "." + strings.TrimSuffix(s.config.ACMEDNS.Domain, ".")
+}
+
+func (s *server) acmeDNSRegister(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	var request acmeDNSRegisterRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	if err := backend.ValidateAllowFrom(request.AllowFrom); err != nil {
+		return err
+	}
+	subdomain, err := secret.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	username, err := secret.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	password, err := secret.Generate()
+	if err != nil {
+		return err
+	}
+	zone := s.acmeDNSZone(subdomain)
+
+	if err := s.provider.CreateZone(ctx, zone); err != nil {
+		return err
+	}
+	if err := s.createACMEDNSKey(ctx, username, password, zone, request.AllowFrom); err != nil {
+		if err := s.provider.DeleteZone(ctx, zone); err != nil {
+			s.logger.WarnContext(ctx, "Cannot clean up zone after acme-dns registration failed", E.ToSLogAttr(err, slog.String("zone", zone))...)
+		}
+		return err
+	}
+	s.logger.InfoContext(ctx, "acme-dns account registered", slog.String("zone", zone), slog.String("key", username))
+
+	allowFrom := request.AllowFrom
+	if allowFrom == nil {
+		allowFrom = []string{}
+	}
+	writeJSON(w, http.StatusCreated, acmeDNSAccount{
+		Username:   username,
+		Password:   password,
+		FullDomain: "_acme-challenge." + zone,
+		Subdomain:  subdomain,
+		AllowFrom:  allowFrom,
+	})
+	return nil
+}
+
+// createACMEDNSKey creates the update key of an acme-dns account and binds it to its zone. The key is removed again if
+// any step fails.
+func (s *server) createACMEDNSKey(ctx context.Context, keyName string, keySecret string, zone string, allowFrom []string) error {
+	if err := s.provider.CreateKey(ctx, keyName, keySecret); err != nil {
+		return err
+	}
+	err := s.provider.BindKey(ctx, keyName, zone)
+	if err == nil && len(allowFrom) > 0 {
+		err = s.provider.SetKeyAllowFrom(ctx, keyName, allowFrom)
+	}
+	if err != nil {
+		if err := s.provider.DeleteKey(ctx, keyName); err != nil {
+			s.logger.WarnContext(ctx, "Cannot clean up key after acme-dns registration failed", E.ToSLogAttr(err, slog.String("key", keyName))...)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *server) acmeDNSUpdate(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	var request acmeDNSUpdateRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	if _, ok := dns.IsDomainName(request.Subdomain); !ok || request.Subdomain == "" || strings.Contains(request.Subdomain, ".") {
+		return ErrInvalidSubdomain
+	}
+	if !isACMEChallengeDigest(request.TXT) {
+		return ErrInvalidTXT
+	}
+	zone := s.acmeDNSZone(request.Subdomain)
+	if err := s.authenticateKey(r, r.Header.Get("X-Api-User"), r.Header.Get("X-Api-Key"), zone); err != nil {
+		return err
+	}
+	if err := s.updateAnswers(ctx, zone, func(answers []string) []string {
+		answers = append(answers, request.TXT)
+		if len(answers) > acmeDNSAnswers {
+			answers = answers[len(answers)-acmeDNSAnswers:]
+		}
+		return answers
+	}); err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, acmeDNSUpdateResponse{TXT: request.TXT})
+	return nil
+}
+
+// isACMEChallengeDigest returns true if value is a base64url-encoded SHA-256 digest without padding.
+func isACMEChallengeDigest(value string) bool {
+	if len(value) != acmeDNSTXTLength {
+		return false
+	}
+	for _, c := range value {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// writeACMEDNSError writes errors in the format acme-dns clients expect. Unknown errors are logged and returned as a
+// generic internal error.
+func (s *server) writeACMEDNSError(ctx context.Context, w http.ResponseWriter, err error) {
+	if E.Is(err, ErrUnauthorized) {
+		s.logger.DebugContext(ctx, "acme-dns request denied", E.ToSLogAttr(err)...)
+	}
+	if _, response, ok := findKnownCause(err, acmeDNSErrors); ok {
+		writeJSON(w, response.status, acmeDNSErrorResponse{Error: response.message})
+		return
+	}
+	s.logger.ErrorContext(ctx, "acme-dns request failed", E.ToSLogAttr(err)...)
+	writeJSON(w, http.StatusInternalServerError, acmeDNSErrorResponse{Error: strings.ToLower(ErrInternal.GetCode())})
+}
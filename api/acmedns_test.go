@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/api"
+)
+
+const acmeDNSDomain = "acme.example.com"
+
+type acmeDNSAccount struct {
+	Username   string   `json:"username"`
+	Password   string   `json:"password"`
+	FullDomain string   `json:"fulldomain"`
+	Subdomain  string   `json:"subdomain"`
+	AllowFrom  []string `json:"allowfrom"`
+}
+
+func acmeDNSHeaders(account acmeDNSAccount) http.Header {
+	headers := http.Header{}
+	headers.Set("X-Api-User", account.Username)
+	headers.Set("X-Api-Key", account.Password)
+	return headers
+}
+
+func TestACMEDNS(t *testing.T) {
+	url, provider := startAPI(t, api.Config{ACMEDNS: api.ACMEDNSConfig{Domain: acmeDNSDomain, Registration: true}})
+
+	var account acmeDNSAccount
+	if status := requestWithHeaders(t, http.MethodPost, url+"/register", http.Header{}, nil, &account); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when registering: %d", status)
+	}
+	if account.FullDomain != "_acme-challenge."+account.Subdomain+"."+acmeDNSDomain {
+		t.Fatalf("Incorrect full domain: %s", account.FullDomain)
+	}
+	zone := strings.TrimPrefix(account.FullDomain, "_acme-challenge.")
+
+	values := []string{strings.Repeat("a", 43), strings.Repeat("b", 43), strings.Repeat("c", 43)}
+	for _, value := range values {
+		var response map[string]string
+		body := map[string]string{"subdomain": account.Subdomain, "txt": value}
+		if status := requestWithHeaders(t, http.MethodPost, url+"/update", acmeDNSHeaders(account), body, &response); status != http.StatusOK {
+			t.Fatalf("Incorrect status when updating: %d", status)
+		}
+		if response["txt"] != value {
+			t.Fatalf("Incorrect update response: %v", response)
+		}
+	}
+	zoneData, err := provider.GetZone(t.Context(), zone)
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zoneData.ACMEChallengeAnswers, values[1:]) {
+		t.Fatalf("Incorrect answers, expected the two most recent values: %v", zoneData.ACMEChallengeAnswers)
+	}
+	if zoneData.Serial != uint32(len(values)) {
+		t.Fatalf("Incorrect serial: %d", zoneData.Serial)
+	}
+
+	var errResponse map[string]string
+	body := map[string]string{"subdomain": account.Subdomain, "txt": "invalid"}
+	if status := requestWithHeaders(t, http.MethodPost, url+"/update", acmeDNSHeaders(account), body, &errResponse); status != http.StatusBadRequest {
+		t.Fatalf("Incorrect status for an invalid TXT value: %d", status)
+	}
+	if errResponse["error"] != "bad_txt" {
+		t.Fatalf("Incorrect error: %v", errResponse)
+	}
+
+	wrongPassword := account
+	wrongPassword.Password = "wrong"
+	body = map[string]string{"subdomain": account.Subdomain, "txt": values[0]}
+	if status := requestWithHeaders(t, http.MethodPost, url+"/update", acmeDNSHeaders(wrongPassword), body, &errResponse); status != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status for a wrong password: %d", status)
+	}
+	if errResponse["error"] != "forbidden" {
+		t.Fatalf("Incorrect error: %v", errResponse)
+	}
+
+	// Accounts must not be able to update each other's zones.
+	var otherAccount acmeDNSAccount
+	if status := requestWithHeaders(t, http.MethodPost, url+"/register", http.Header{}, nil, &otherAccount); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when registering: %d", status)
+	}
+	if status := requestWithHeaders(t, http.MethodPost, url+"/update", acmeDNSHeaders(otherAccount), body, nil); status != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status when updating another account's zone: %d", status)
+	}
+}
+
+func TestACMEDNSAllowFrom(t *testing.T) {
+	url, _ := startAPI(t, api.Config{ACMEDNS: api.ACMEDNSConfig{Domain: acmeDNSDomain, Registration: true}})
+
+	var errResponse map[string]string
+	invalid := map[string][]string{"allowfrom": {"192.0.2.1"}}
+	if status := requestWithHeaders(t, http.MethodPost, url+"/register", http.Header{}, invalid, &errResponse); status != http.StatusBadRequest {
+		t.Fatalf("Incorrect status for an invalid CIDR: %d", status)
+	}
+	if errResponse["error"] != "invalid_allowfrom_cidr" {
+		t.Fatalf("Incorrect error: %v", errResponse)
+	}
+
+	var account acmeDNSAccount
+	allowed := map[string][]string{"allowfrom": {"192.0.2.0/24"}}
+	if status := requestWithHeaders(t, http.MethodPost, url+"/register", http.Header{}, allowed, &account); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when registering: %d", status)
+	}
+	if !slices.Equal(account.AllowFrom, []string{"192.0.2.0/24"}) {
+		t.Fatalf("Incorrect allowfrom: %v", account.AllowFrom)
+	}
+	// The test client connects from 127.0.0.1, which is not in the allowed network.
+	body := map[string]string{"subdomain": account.Subdomain, "txt": strings.Repeat("a", 43)}
+	if status := requestWithHeaders(t, http.MethodPost, url+"/update", acmeDNSHeaders(account), body, nil); status != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status when updating from a disallowed address: %d", status)
+	}
+}
+
+func TestACMEDNSRegistrationDisabled(t *testing.T) {
+	url, _ := startAPI(t, api.Config{ACMEDNS: api.ACMEDNSConfig{Domain: acmeDNSDomain}})
+
+	if status := requestWithHeaders(t, http.MethodPost, url+"/register", http.Header{}, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("Incorrect status with registration disabled: %d", status)
+	}
+}
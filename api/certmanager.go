@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// The types below mirror the webhook.acme.cert-manager.io/v1alpha1 API, so that DNS4ACME does not need to depend on
+// cert-manager and the Kubernetes API server libraries.
+
+const certManagerVersion = "v1alpha1"
+const certManagerAPIVersion = "webhook.acme.cert-manager.io/" + certManagerVersion
+const certManagerKind = "ChallengePayload"
+
+const (
+	challengeActionPresent = "Present"
+	challengeActionCleanUp = "CleanUp"
+)
+
+// challengePayload is the request and response body of the webhook solver.
+type challengePayload struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *challengeRequest  `json:"request,omitempty"`
+	Response   *challengeResponse `json:"response,omitempty"`
+}
+
+type challengeRequest struct {
+	UID                     string          `json:"uid"`
+	Action                  string          `json:"action"`
+	Type                    string          `json:"type"`
+	DNSName                 string          `json:"dnsName"`
+	Key                     string          `json:"key"`
+	ResourceNamespace       string          `json:"resourceNamespace"`
+	ResolvedFQDN            string          `json:"resolvedFQDN"`
+	ResolvedZone            string          `json:"resolvedZone"`
+	AllowAmbientCredentials bool            `json:"allowAmbientCredentials"`
+	Config                  json.RawMessage `json:"config,omitempty"`
+}
+
+type challengeResponse struct {
+	UID     string      `json:"uid"`
+	Success bool        `json:"success"`
+	Status  *kubeStatus `json:"status,omitempty"`
+}
+
+// kubeStatus is the Kubernetes Status object used to describe failures.
+type kubeStatus struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Status     string `json:"status"`
+	Message    string `json:"message"`
+	Reason     string `json:"reason"`
+	Code       int    `json:"code"`
+}
+
+// apiResourceList is the discovery document the Kubernetes API aggregator fetches to check the APIService.
+type apiResourceList struct {
+	Kind         string        `json:"kind"`
+	APIVersion   string        `json:"apiVersion"`
+	GroupVersion string        `json:"groupVersion"`
+	Resources    []apiResource `json:"resources"`
+}
+
+type apiResource struct {
+	Name         string   `json:"name"`
+	SingularName string   `json:"singularName"`
+	Namespaced   bool     `json:"namespaced"`
+	Kind         string   `json:"kind"`
+	Verbs        []string `json:"verbs"`
+}
+
+func (s *server) registerCertManager(mux *http.ServeMux, clientCA *x509.CertPool) {
+	prefix := "/apis/" + s.config.CertManager.GroupName + "/" + certManagerVersion
+	handle := func(pattern string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			// Only the Kubernetes API server may call the webhook solver. It authenticates using its front proxy client
+			// certificate.
+			if !hasClientCert(r, clientCA) {
+				status := newKubeStatus(http.StatusUnauthorized, ErrUnauthorized.GetMessage())
+				writeJSON(w, status.Code, status)
+				return
+			}
+			handler(w, r)
+		})
+	}
+	handle("GET "+prefix, func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, apiResourceList{
+			Kind:         "APIResourceList",
+			APIVersion:   "v1",
+			GroupVersion: s.config.CertManager.GroupName + "/" + certManagerVersion,
+			Resources: []apiResource{
+				{
+					Name:         s.config.CertManager.SolverName,
+					SingularName: s.config.CertManager.SolverName,
+					Namespaced:   false,
+					Kind:         certManagerKind,
+					Verbs:        []string{"create"},
+				},
+			},
+		})
+	})
+	handle("POST "+prefix+"/"+s.config.CertManager.SolverName, s.certManagerSolve)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// remoteUserHeader is the header the Kubernetes API server passes the authenticated user in when proxying requests to
+// aggregated APIs.
+const remoteUserHeader = "X-Remote-User"
+
+func (s *server) certManagerSolve(w http.ResponseWriter, r *http.Request) {
+	// The request names the namespace to act for, so anyone allowed to create challenges could act for any namespace.
+	// Only accept requests cert-manager sent through the API server.
+	if user := r.Header.Get(remoteUserHeader); !slices.Contains(s.config.CertManager.AllowedUsers, user) {
+		s.logger.DebugContext(r.Context(), "cert-manager webhook request from a user that is not allowed", slog.String("user", user))
+		status := newKubeStatus(http.StatusForbidden, "user "+user+" is not allowed to use the webhook solver")
+		writeJSON(w, status.Code, status)
+		return
+	}
+	var payload challengePayload
+	// Unknown fields are allowed since newer cert-manager versions may add fields to the request.
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&payload); err != nil || payload.Request == nil {
+		status := newKubeStatus(http.StatusBadRequest, "invalid ChallengePayload")
+		writeJSON(w, status.Code, status)
+		return
+	}
+	response := &challengeResponse{UID: payload.Request.UID, Success: true}
+	if err := s.certManagerSolveChallenge(r.Context(), payload.Request); err != nil {
+		response.Success = false
+		response.Status = s.certManagerStatus(r.Context(), err)
+	}
+	writeJSON(w, http.StatusOK, challengePayload{
+		APIVersion: certManagerAPIVersion,
+		Kind:       certManagerKind,
+		Response:   response,
+	})
+}
+
+func (s *server) certManagerSolveChallenge(ctx context.Context, request *challengeRequest) error {
+	if request.Type != "" && request.Type != "dns-01" {
+		return ErrInvalidRequest.Wrap(fmt.Errorf("unsupported challenge type %s", request.Type))
+	}
+	if request.Key == "" {
+		return ErrInvalidRequest.Wrap(fmt.Errorf("the challenge key must not be empty"))
+	}
+	zone, ok := strings.CutPrefix(strings.ToLower(strings.TrimSuffix(request.ResolvedFQDN, ".")), "_acme-challenge.")
+	if !ok || zone == "" {
+		return ErrInvalidRequest.Wrap(fmt.Errorf("resolvedFQDN %s does not start with _acme-challenge.", request.ResolvedFQDN))
+	}
+
+	// Namespaces are authorized for zones by binding the update key named after the namespace to the zone. The
+	// secret of the key is not needed since the Kubernetes API server has already authenticated cert-manager.
+	keyName := s.config.CertManager.KeyPrefix + request.ResourceNamespace
+	keyData, err := s.provider.GetKey(ctx, keyName)
+	if err != nil && !E.Is(err, backend.ErrKeyNotFoundInBackend) {
+		return err
+	}
+	if err != nil || !slices.Contains(keyData.Zones, zone) {
+		return ErrUnauthorized.Wrap(fmt.Errorf(
+			"namespace %s is not authorized for zone %s, bind the update key %s to the zone",
+			request.ResourceNamespace,
+			zone,
+			keyName,
+		))
+	}
+
+	switch request.Action {
+	case challengeActionPresent:
+		return s.updateAnswers(ctx, zone, func(answers []string) []string {
+			if slices.Contains(answers, request.Key) {
+				return answers
+			}
+			return append(answers, request.Key)
+		})
+	case challengeActionCleanUp:
+		return s.updateAnswers(ctx, zone, func(answers []string) []string {
+			return slices.DeleteFunc(answers, func(answer string) bool {
+				return answer == request.Key
+			})
+		})
+	default:
+		return ErrInvalidRequest.Wrap(fmt.Errorf("unsupported action %s", request.Action))
+	}
+}
+
+// certManagerStatus converts an error into the Status object cert-manager reports on the Challenge. Unknown errors are
+// logged and reported as a generic internal error.
+func (s *server) certManagerStatus(ctx context.Context, err error) *kubeStatus {
+	cause, status, ok := findKnownCause(err, errorStatuses)
+	if !ok {
+		s.logger.ErrorContext(ctx, "cert-manager webhook request failed", E.ToSLogAttr(err)...)
+		return newKubeStatus(http.StatusInternalServerError, ErrInternal.GetMessage())
+	}
+	s.logger.DebugContext(ctx, "cert-manager webhook request failed", E.ToSLogAttr(err)...)
+	message := cause.GetMessage()
+	if cause.Unwrap() != nil {
+		message += ": " + cause.Unwrap().Error()
+	}
+	return newKubeStatus(status, message)
+}
+
+func newKubeStatus(code int, message string) *kubeStatus {
+	return &kubeStatus{
+		APIVersion: "v1",
+		Kind:       "Status",
+		Status:     "Failure",
+		Message:    message,
+		Reason:     strings.ReplaceAll(http.StatusText(code), " ", ""),
+		Code:       code,
+	}
+}
@@ -0,0 +1,360 @@
+package api_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/api"
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+const certManagerGroup = "acme.example.com"
+
+// certManagerUser is the user the Kubernetes API server reports for requests from cert-manager.
+const certManagerUser = "system:serviceaccount:cert-manager:cert-manager"
+
+// testCA is a certificate authority for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse CA certificate: %v", err)
+	}
+	return testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate signed by the CA with the specified extended key usage.
+func (ca testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return file
+}
+
+// certManagerFixture is an HTTP API with the webhook solver enabled and client certificates signed by the front proxy
+// CA, the management CA and an untrusted CA.
+type certManagerFixture struct {
+	url        string
+	provider   backend.ExtendedProvider
+	roots      *x509.CertPool
+	frontProxy tls.Certificate
+	management tls.Certificate
+	untrusted  tls.Certificate
+}
+
+// client returns an HTTP client presenting cert, or no client certificate if cert is empty.
+func (f certManagerFixture) client(cert tls.Certificate) *http.Client {
+	tlsConfig := &tls.Config{RootCAs: f.roots, MinVersion: tls.VersionTLS12}
+	if cert.Certificate != nil {
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+}
+
+func startCertManagerAPI(t *testing.T) certManagerFixture {
+	t.Helper()
+	serverCA := newTestCA(t, "server")
+	frontProxyCA := newTestCA(t, "front-proxy")
+	managementCA := newTestCA(t, "management")
+	untrustedCA := newTestCA(t, "untrusted")
+
+	serverCert := serverCA.issue(t, "dns4acme", x509.ExtKeyUsageServerAuth)
+	serverKey, err := x509.MarshalPKCS8PrivateKey(serverCert.PrivateKey)
+	if err != nil {
+		t.Fatalf("Failed to encode server key: %v", err)
+	}
+	config := api.Config{
+		TLSCert:  writeFile(t, "tls.crt", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverCert.Certificate[0]})),
+		TLSKey:   writeFile(t, "tls.key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: serverKey})),
+		ClientCA: writeFile(t, "management-ca.crt", managementCA.pem),
+		CertManager: api.CertManagerConfig{
+			GroupName:    certManagerGroup,
+			SolverName:   "dns4acme",
+			ClientCA:     writeFile(t, "front-proxy-ca.crt", frontProxyCA.pem),
+			KeyPrefix:    "cert-manager-",
+			AllowedUsers: []string{certManagerUser},
+		},
+	}
+	url, provider := startAPI(t, config)
+	ctx := t.Context()
+	for _, zone := range []string{"example.com", "example.org"} {
+		if err := provider.CreateZone(ctx, zone); err != nil {
+			t.Fatalf("Failed to create zone: %v", err)
+		}
+	}
+	if err := provider.CreateKey(ctx, "cert-manager-team-a", "secret"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := provider.BindKey(ctx, "cert-manager-team-a", "example.com"); err != nil {
+		t.Fatalf("Failed to bind key: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(serverCA.cert)
+	return certManagerFixture{
+		url:        strings.Replace(url, "http://", "https://", 1),
+		provider:   provider,
+		roots:      roots,
+		frontProxy: frontProxyCA.issue(t, "front-proxy-client", x509.ExtKeyUsageClientAuth),
+		management: managementCA.issue(t, "admin", x509.ExtKeyUsageClientAuth),
+		untrusted:  untrustedCA.issue(t, "untrusted", x509.ExtKeyUsageClientAuth),
+	}
+}
+
+// challengeResult is the part of the ChallengePayload response the tests check.
+type challengeResult struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Response   struct {
+		UID     string `json:"uid"`
+		Success bool   `json:"success"`
+		Status  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"status"`
+	} `json:"response"`
+}
+
+func solve(t *testing.T, client *http.Client, url string, action string, namespace string, fqdn string, key string) (int, challengeResult) {
+	t.Helper()
+	return solveAs(t, client, url, certManagerUser, action, namespace, fqdn, key)
+}
+
+// solveAs sends a challenge as if the Kubernetes API server had authenticated the specified user.
+func solveAs(t *testing.T, client *http.Client, url string, user string, action string, namespace string, fqdn string, key string) (int, challengeResult) {
+	t.Helper()
+	payload := map[string]any{
+		"apiVersion": "webhook.acme.cert-manager.io/v1alpha1",
+		"kind":       "ChallengePayload",
+		"request": map[string]any{
+			"uid":               "uid-" + action,
+			"action":            action,
+			"type":              "dns-01",
+			"dnsName":           strings.TrimPrefix(strings.TrimSuffix(fqdn, "."), "_acme-challenge."),
+			"key":               key,
+			"resourceNamespace": namespace,
+			"resolvedFQDN":      fqdn,
+			"resolvedZone":      "example.com.",
+			"config":            map[string]any{},
+		},
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, url+"/apis/"+certManagerGroup+"/v1alpha1/dns4acme", bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if user != "" {
+		req.Header.Set("X-Remote-User", user)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var result challengeResult
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return resp.StatusCode, result
+}
+
+func TestCertManager(t *testing.T) {
+	fixture := startCertManagerAPI(t)
+	apiServer := fixture.client(fixture.frontProxy)
+
+	resp, err := apiServer.Get(fixture.url + "/apis/" + certManagerGroup + "/v1alpha1")
+	if err != nil {
+		t.Fatalf("Failed to fetch discovery document: %v", err)
+	}
+	var discovery struct {
+		GroupVersion string `json:"groupVersion"`
+		Resources    []struct {
+			Name string `json:"name"`
+		} `json:"resources"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&discovery)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Failed to decode discovery document: %v", err)
+	}
+	if discovery.GroupVersion != certManagerGroup+"/v1alpha1" || len(discovery.Resources) != 1 || discovery.Resources[0].Name != "dns4acme" {
+		t.Fatalf("Incorrect discovery document: %v", discovery)
+	}
+
+	for _, key := range []string{"first", "second", "second"} {
+		status, result := solve(t, apiServer, fixture.url, "Present", "team-a", "_acme-challenge.example.com.", key)
+		if status != http.StatusOK || !result.Response.Success {
+			t.Fatalf("Present failed: %d %v", status, result)
+		}
+		if result.Response.UID != "uid-Present" || result.Kind != "ChallengePayload" {
+			t.Fatalf("Incorrect response: %v", result)
+		}
+	}
+	if status, result := solve(t, apiServer, fixture.url, "CleanUp", "team-a", "_acme-challenge.example.com.", "first"); status != http.StatusOK || !result.Response.Success {
+		t.Fatalf("CleanUp failed: %d %v", status, result)
+	}
+	zoneData, err := fixture.provider.GetZone(t.Context(), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zoneData.ACMEChallengeAnswers, []string{"second"}) {
+		t.Fatalf("Incorrect answers: %v", zoneData.ACMEChallengeAnswers)
+	}
+}
+
+func TestCertManagerUnauthorizedNamespace(t *testing.T) {
+	fixture := startCertManagerAPI(t)
+	apiServer := fixture.client(fixture.frontProxy)
+
+	for _, testCase := range []struct {
+		name      string
+		namespace string
+		fqdn      string
+	}{
+		{"namespace without key", "team-b", "_acme-challenge.example.com."},
+		{"zone not bound to the key", "team-a", "_acme-challenge.example.org."},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			status, result := solve(t, apiServer, fixture.url, "Present", testCase.namespace, testCase.fqdn, "value")
+			if status != http.StatusOK {
+				t.Fatalf("Incorrect status: %d", status)
+			}
+			if result.Response.Success || result.Response.Status == nil || result.Response.Status.Code != http.StatusUnauthorized {
+				t.Fatalf("Incorrect response: %v", result)
+			}
+		})
+	}
+	zoneData, err := fixture.provider.GetZone(t.Context(), "example.org")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if len(zoneData.ACMEChallengeAnswers) != 0 {
+		t.Fatalf("Zone modified by unauthorized request")
+	}
+}
+
+func TestCertManagerUnauthorizedUser(t *testing.T) {
+	fixture := startCertManagerAPI(t)
+	apiServer := fixture.client(fixture.frontProxy)
+
+	for _, user := range []string{"", "system:serviceaccount:team-b:default"} {
+		if status, _ := solveAs(t, apiServer, fixture.url, user, "Present", "team-a", "_acme-challenge.example.com.", "value"); status != http.StatusForbidden {
+			t.Fatalf("Incorrect status for user %q: %d", user, status)
+		}
+	}
+	zoneData, err := fixture.provider.GetZone(t.Context(), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if len(zoneData.ACMEChallengeAnswers) != 0 {
+		t.Fatalf("Zone modified by a user that is not allowed")
+	}
+}
+
+func TestCertManagerClientCertificates(t *testing.T) {
+	fixture := startCertManagerAPI(t)
+
+	for _, testCase := range []struct {
+		name string
+		cert tls.Certificate
+	}{
+		{"no certificate", tls.Certificate{}},
+		{"untrusted certificate", fixture.untrusted},
+		{"management certificate", fixture.management},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			if status, _ := solve(t, fixture.client(testCase.cert), fixture.url, "Present", "team-a", "_acme-challenge.example.com.", "value"); status != http.StatusUnauthorized {
+				t.Fatalf("Incorrect status: %d", status)
+			}
+		})
+	}
+
+	// The front proxy certificate must not grant access to the management API.
+	resp, err := fixture.client(fixture.frontProxy).Get(fixture.url + "/api/v1/zones")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status for the management API with the front proxy certificate: %d", resp.StatusCode)
+	}
+	resp, err = fixture.client(fixture.management).Get(fixture.url + "/api/v1/zones")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Incorrect status for the management API with the management certificate: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type Config struct {
+	Listen string `config:"listen" description:"Address and port to listen on for HTTP API requests, e.g. 0.0.0.0:8080. The HTTP API is disabled if empty."`
+
+	TLSCert  string `config:"tls-cert" description:"File containing the PEM-encoded TLS certificate for the HTTP API. Plain HTTP is used if empty."`
+	TLSKey   string `config:"tls-key" description:"File containing the PEM-encoded private key for the TLS certificate."`
+	ClientCA string `config:"client-ca" description:"File containing the PEM-encoded CA certificates to verify client certificates against. Clients presenting a valid certificate may use the management API. Requires TLS."`
+
+	Tokens []string `config:"tokens" sensitive:"true" file:"true" description:"Comma-separated list of bearer tokens allowed to use the management API."`
+
+	Timeout time.Duration `config:"timeout" default:"30s" description:"Maximum time to read a request and write the response."`
+
+	ACMEDNS ACMEDNSConfig `config:"acme-dns"`
+
+	HTTPReq bool `config:"httpreq" description:"Serve the /present and /cleanup endpoints of the lego httpreq DNS provider, authenticated with update keys."`
+
+	CertManager CertManagerConfig `config:"cert-manager"`
+}
+
+// CertManagerConfig configures the cert-manager webhook solver, which the Kubernetes API server calls through an
+// APIService.
+type CertManagerConfig struct {
+	GroupName    string   `config:"group-name" description:"API group name of the cert-manager webhook solver, e.g. acme.dns4acme.github.io. The webhook solver is disabled if empty."`
+	SolverName   string   `config:"solver-name" default:"dns4acme" description:"Solver name Issuers reference in the webhook solver configuration."`
+	ClientCA     string   `config:"client-ca" description:"File containing the CA certificate the Kubernetes API server's front proxy client certificate is signed with (requestheader-client-ca-file). Requires TLS."`
+	KeyPrefix    string   `config:"key-prefix" description:"Prefix of the update key names that authorize namespaces for zones. The update key <prefix><namespace> must be bound to a zone for Issuers in that namespace to use it."`
+	AllowedUsers []string `config:"allowed-users" default:"system:serviceaccount:cert-manager:cert-manager" description:"Comma-separated list of Kubernetes users allowed to call the webhook solver, as reported by the Kubernetes API server in the X-Remote-User header. Only list the service account of cert-manager."`
+}
+
+// Enabled returns true if the cert-manager webhook solver should be served.
+func (c CertManagerConfig) Enabled() bool {
+	return c.GroupName != ""
+}
+
+// ACMEDNSConfig configures the acme-dns compatible /register and /update endpoints.
+type ACMEDNSConfig struct {
+	Domain       string `config:"domain" description:"Domain under which acme-dns registrations create their zones, e.g. acme.example.com. The acme-dns compatible API is disabled if empty."`
+	Registration bool   `config:"registration" description:"Allow anyone who can reach the HTTP API to register new acme-dns accounts."`
+}
+
+// Enabled returns true if the acme-dns compatible endpoints should be served.
+func (c ACMEDNSConfig) Enabled() bool {
+	return c.Domain != ""
+}
+
+func (c ACMEDNSConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if _, ok := dns.IsDomainName(c.Domain); !ok || strings.HasPrefix(c.Domain, ".") {
+		return ErrInvalidACMEDNSDomain.WithAttr(slog.String("domain", c.Domain))
+	}
+	return nil
+}
+
+// Enabled returns true if the HTTP API should be started.
+func (c Config) Enabled() bool {
+	return c.Listen != ""
+}
+
+// TLSEnabled returns true if the HTTP API is served over TLS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCert != ""
+}
+
+// ManagementEnabled returns true if the management API is enabled, which requires a way to authenticate clients.
+func (c Config) ManagementEnabled() bool {
+	return len(c.Tokens) > 0 || c.ClientCA != ""
+}
+
+func (c Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+	listen, err := netip.ParseAddrPort(c.Listen)
+	if err != nil {
+		return ErrInvalidListenAddress.Wrap(err).WithAttr(slog.String("listen", c.Listen))
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return ErrIncompleteTLSConfig
+	}
+	if c.ClientCA != "" && !c.TLSEnabled() {
+		return ErrClientCARequiresTLS
+	}
+	// Bearer tokens would be sent in plain text. Allow this on loopback only, e.g. behind a local TLS proxy.
+	if len(c.Tokens) > 0 && !c.TLSEnabled() && !listen.Addr().IsLoopback() {
+		return ErrTokensRequireTLS.WithAttr(slog.String("listen", c.Listen))
+	}
+	for i, token := range c.Tokens {
+		if token == "" {
+			return ErrEmptyToken.WithAttr(slog.Int("item", i))
+		}
+	}
+	if c.Timeout <= 0 {
+		return ErrInvalidTimeout.WithAttr(slog.Duration("timeout", c.Timeout))
+	}
+	if err := c.ACMEDNS.Validate(); err != nil {
+		return err
+	}
+	if c.CertManager.Enabled() {
+		if _, ok := dns.IsDomainName(c.CertManager.GroupName); !ok {
+			return ErrInvalidCertManagerConfig.
+				Wrap(fmt.Errorf("invalid group name")).
+				WithAttr(slog.String("group_name", c.CertManager.GroupName))
+		}
+		if c.CertManager.SolverName == "" {
+			return ErrInvalidCertManagerConfig.Wrap(fmt.Errorf("the solver name must not be empty"))
+		}
+		if c.CertManager.ClientCA == "" || !c.TLSEnabled() {
+			return ErrInvalidCertManagerConfig.Wrap(fmt.Errorf("the webhook solver requires TLS and a client CA"))
+		}
+		if len(c.CertManager.AllowedUsers) == 0 {
+			return ErrInvalidCertManagerConfig.Wrap(fmt.Errorf("at least one user must be allowed to call the webhook solver"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+var ErrInvalidListenAddress = E.New("API_INVALID_LISTEN_ADDRESS", "invalid HTTP API listen address, must be in the form of ip:port")
+var ErrIncompleteTLSConfig = E.New("API_INCOMPLETE_TLS_CONFIG", "both the TLS certificate and the private key must be set for the HTTP API")
+var ErrClientCARequiresTLS = E.New("API_CLIENT_CA_REQUIRES_TLS", "client certificate authentication requires TLS to be enabled")
+var ErrTokensRequireTLS = E.New("API_TOKENS_REQUIRE_TLS", "bearer tokens require TLS to be enabled unless the HTTP API listens on a loopback address")
+var ErrEmptyToken = E.New("API_EMPTY_TOKEN", "HTTP API bearer tokens must not be empty")
+var ErrInvalidTimeout = E.New("API_INVALID_TIMEOUT", "HTTP API timeout must be positive")
+var ErrTLSSetupFailed = E.New("API_TLS_SETUP_FAILED", "failed to load the HTTP API TLS configuration")
+var ErrListenFailed = E.New("API_LISTEN_FAILED", "failed to listen for HTTP API requests")
+var ErrInvalidACMEDNSDomain = E.New("API_INVALID_ACME_DNS_DOMAIN", "invalid acme-dns domain, must be a valid domain name")
+var ErrInvalidCertManagerConfig = E.New("API_INVALID_CERT_MANAGER_CONFIG", "invalid cert-manager webhook solver configuration")
+var ErrShutdownFailed = E.New("API_SHUTDOWN_FAILED", "HTTP API shutdown failed")
+
+var ErrUnauthorized = E.New("UNAUTHORIZED", "missing or invalid credentials")
+var ErrInvalidRequest = E.New("INVALID_REQUEST", "invalid request")
+var ErrInternal = E.New("INTERNAL_ERROR", "internal server error")
+
+var ErrInvalidSubdomain = E.New("INVALID_SUBDOMAIN", "invalid acme-dns subdomain")
+var ErrInvalidTXT = E.New("INVALID_TXT", "invalid TXT value, must be a 43 character base64url-encoded ACME challenge digest")
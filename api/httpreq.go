@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// httpReqRequest is the request body the lego httpreq DNS provider sends in its default mode.
+type httpReqRequest struct {
+	FQDN  string `json:"fqdn"`
+	Value string `json:"value"`
+}
+
+func (s *server) registerHTTPReq(mux *http.ServeMux) {
+	handle := func(pattern string, handler func(w http.ResponseWriter, r *http.Request) error) {
+		mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+			if err := handler(w, r); err != nil {
+				if E.Is(err, ErrUnauthorized) {
+					s.logger.DebugContext(r.Context(), "httpreq request denied", E.ToSLogAttr(err)...)
+					w.Header().Set("WWW-Authenticate", `Basic realm="dns4acme"`)
+				}
+				s.writeError(r.Context(), w, err)
+			}
+		})
+	}
+	handle("POST /present", s.httpReqPresent)
+	handle("POST /cleanup", s.httpReqCleanup)
+}
+
+// httpReqZone authenticates the request using basic auth and returns the zone the challenge answer belongs to.
+func (s *server) httpReqZone(w http.ResponseWriter, r *http.Request) (httpReqRequest, string, error) {
+	var request httpReqRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return request, "", err
+	}
+	if request.Value == "" {
+		return request, "", ErrInvalidRequest.Wrap(fmt.Errorf("value must not be empty"))
+	}
+	zone, ok := strings.CutPrefix(strings.ToLower(strings.TrimSuffix(request.FQDN, ".")), "_acme-challenge.")
+	if !ok || zone == "" {
+		return request, "", ErrInvalidRequest.Wrap(fmt.Errorf("fqdn must start with _acme-challenge."))
+	}
+	keyName, password, _ := r.BasicAuth()
+	if err := s.authenticateKey(r, keyName, password, zone); err != nil {
+		return request, "", err
+	}
+	return request, zone, nil
+}
+
+func (s *server) httpReqPresent(w http.ResponseWriter, r *http.Request) error {
+	request, zone, err := s.httpReqZone(w, r)
+	if err != nil {
+		return err
+	}
+	if err := s.updateAnswers(r.Context(), zone, func(answers []string) []string {
+		// Clients retry on errors, adding the same answer twice would be pointless.
+		if slices.Contains(answers, request.Value) {
+			return answers
+		}
+		return append(answers, request.Value)
+	}); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func (s *server) httpReqCleanup(w http.ResponseWriter, r *http.Request) error {
+	request, zone, err := s.httpReqZone(w, r)
+	if err != nil {
+		return err
+	}
+	if err := s.updateAnswers(r.Context(), zone, func(answers []string) []string {
+		return slices.DeleteFunc(answers, func(answer string) bool {
+			return answer == request.Value
+		})
+	}); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
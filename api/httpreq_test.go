@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/api"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+)
+
+func TestHTTPReq(t *testing.T) {
+	url, provider := startAPI(t, api.Config{HTTPReq: true})
+	ctx := t.Context()
+	for _, zone := range []string{"example.com", "example.org"} {
+		if err := provider.CreateZone(ctx, zone); err != nil {
+			t.Fatalf("Failed to create zone: %v", err)
+		}
+	}
+	if err := provider.CreateKey(ctx, "lego", "secret"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := provider.BindKey(ctx, "lego", "example.com"); err != nil {
+		t.Fatalf("Failed to bind key: %v", err)
+	}
+
+	send := func(path string, username string, password string, fqdn string, value string) int {
+		t.Helper()
+		headers := http.Header{}
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+		return requestWithHeaders(t, http.MethodPost, url+path, headers, map[string]string{"fqdn": fqdn, "value": value}, nil)
+	}
+	answers := func(zone string) []string {
+		t.Helper()
+		zoneData, err := provider.GetZone(ctx, zone)
+		if err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		return zoneData.ACMEChallengeAnswers
+	}
+
+	for _, value := range []string{"first", "second", "second"} {
+		if status := send("/present", "lego", "secret", "_acme-challenge.example.com.", value); status != http.StatusOK {
+			t.Fatalf("Incorrect status for present: %d", status)
+		}
+	}
+	if result := answers("example.com"); !slices.Equal(result, []string{"first", "second"}) {
+		t.Fatalf("Incorrect answers after present: %v", result)
+	}
+	if status := send("/cleanup", "lego", "secret", "_acme-challenge.example.com.", "first"); status != http.StatusOK {
+		t.Fatalf("Incorrect status for cleanup: %d", status)
+	}
+	if result := answers("example.com"); !slices.Equal(result, []string{"second"}) {
+		t.Fatalf("Incorrect answers after cleanup: %v", result)
+	}
+
+	// Key names are case-insensitive and may end with a dot, like in the management API.
+	if status := send("/present", "Lego.", "secret", "_acme-challenge.example.com.", "third"); status != http.StatusOK {
+		t.Fatalf("Incorrect status for a key name in upper case with a trailing dot: %d", status)
+	}
+	if status := send("/present", "lego", "wrong", "_acme-challenge.example.com.", "value"); status != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status for a wrong password: %d", status)
+	}
+	if status := send("/present", "lego", "secret", "_acme-challenge.example.org.", "value"); status != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status for a zone the key is not bound to: %d", status)
+	}
+	if status := send("/present", "lego", "secret", "example.com.", "value"); status != http.StatusBadRequest {
+		t.Fatalf("Incorrect status for an fqdn without _acme-challenge: %d", status)
+	}
+	if len(answers("example.org")) != 0 {
+		t.Fatalf("Zone modified by unauthorized request")
+	}
+}
+
+// slowProvider delays returning zones after reading them so that concurrent updates overlap.
+type slowProvider struct {
+	backend.ExtendedProvider
+}
+
+func (p slowProvider) GetZone(ctx context.Context, zone string) (backend.ProviderZoneResponse, error) {
+	zoneData, err := p.ExtendedProvider.GetZone(ctx, zone)
+	time.Sleep(10 * time.Millisecond)
+	return zoneData, err
+}
+
+func TestHTTPReqConcurrentPresent(t *testing.T) {
+	provider, err := inmemory.Config{}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	ctx := t.Context()
+	if err := provider.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	if err := provider.CreateKey(ctx, "lego", "secret"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := provider.BindKey(ctx, "lego", "example.com"); err != nil {
+		t.Fatalf("Failed to bind key: %v", err)
+	}
+	url := startAPIWithProvider(t, api.Config{HTTPReq: true}, slowProvider{provider})
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("lego:secret")))
+	const requests = 10
+	statuses := make([]int, requests)
+	wg := sync.WaitGroup{}
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := map[string]string{"fqdn": "_acme-challenge.example.com.", "value": fmt.Sprintf("value-%d", i)}
+			statuses[i] = requestWithHeaders(t, http.MethodPost, url+"/present", headers.Clone(), body, nil)
+		}()
+	}
+	wg.Wait()
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Fatalf("Incorrect status for request %d: %d", i, status)
+		}
+	}
+	zoneData, err := provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if len(zoneData.ACMEChallengeAnswers) != requests {
+		t.Fatalf("Answers lost by concurrent updates: %v", zoneData.ACMEChallengeAnswers)
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// CompactResult is the response of the compact endpoint.
+type CompactResult struct {
+	SizeBefore int64 `json:"size_before"`
+	SizeAfter  int64 `json:"size_after"`
+}
+
+func (s *server) maintenanceProvider() (backend.MaintenanceProvider, error) {
+	provider, ok := s.provider.(backend.MaintenanceProvider)
+	if !ok {
+		return nil, backend.ErrMaintenanceNotSupported
+	}
+	return provider, nil
+}
+
+func (s *server) backup(w http.ResponseWriter, r *http.Request) error {
+	provider, err := s.maintenanceProvider()
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="dns4acme-backup.db"`)
+	if _, err := provider.Backup(r.Context(), w); err != nil {
+		// The status has already been sent. Aborting the response keeps the client from mistaking the truncated body for
+		// a complete backup.
+		s.logger.ErrorContext(r.Context(), "Backup failed", E.ToSLogAttr(err)...)
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+func (s *server) compact(w http.ResponseWriter, r *http.Request) error {
+	provider, err := s.maintenanceProvider()
+	if err != nil {
+		return err
+	}
+	response, err := provider.Compact(r.Context())
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, CompactResult{SizeBefore: response.SizeBefore, SizeAfter: response.SizeAfter})
+	return nil
+}
@@ -0,0 +1,82 @@
+package api_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/api"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+)
+
+// maintenanceProvider adds fixed backup and compaction results to the in-memory backend.
+type maintenanceProvider struct {
+	backend.ExtendedProvider
+}
+
+func (p maintenanceProvider) Backup(_ context.Context, w io.Writer) (int64, error) {
+	n, err := w.Write([]byte("snapshot"))
+	return int64(n), err
+}
+
+func (p maintenanceProvider) Compact(_ context.Context) (backend.CompactResponse, error) {
+	return backend.CompactResponse{SizeBefore: 32768, SizeAfter: 16384}, nil
+}
+
+func TestMaintenance(t *testing.T) {
+	provider, err := inmemory.Config{}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	url := startAPIWithProvider(t, api.Config{Tokens: []string{testToken}}, maintenanceProvider{provider})
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, url+"/api/v1/backup", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "snapshot" {
+		t.Fatalf("Incorrect backup response: %d %q", resp.StatusCode, body)
+	}
+
+	var result api.CompactResult
+	if status := request(t, http.MethodPost, url+"/api/v1/compact", testToken, nil, &result); status != http.StatusOK {
+		t.Fatalf("Incorrect status when compacting: %d", status)
+	}
+	if result.SizeBefore != 32768 || result.SizeAfter != 16384 {
+		t.Fatalf("Incorrect compaction result: %v", result)
+	}
+}
+
+func TestMaintenanceNotSupported(t *testing.T) {
+	url, _ := startAPI(t, api.Config{Tokens: []string{testToken}})
+
+	for _, endpoint := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/v1/backup"},
+		{http.MethodPost, "/api/v1/compact"},
+	} {
+		var errResponse map[string]string
+		if status := request(t, endpoint.method, url+endpoint.path, testToken, nil, &errResponse); status != http.StatusNotImplemented {
+			t.Fatalf("Incorrect status for %s: %d", endpoint.path, status)
+		}
+		if errResponse["code"] != backend.ErrMaintenanceNotSupported.GetCode() {
+			t.Fatalf("Incorrect error code: %v", errResponse)
+		}
+	}
+}
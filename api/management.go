@@ -0,0 +1,426 @@
+package api
+
+import (
+	"context"
+	"crypto/x509"
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/internal/secret"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// managementPrefix is the path prefix of the management API.
+const managementPrefix = "/api/v1"
+
+//go:embed openapi.yaml
+var openAPIDescription []byte
+
+// Zone is the JSON representation of a zone in the management API.
+type Zone struct {
+	Name                 string   `json:"name"`
+	Serial               uint32   `json:"serial"`
+	Debug                bool     `json:"debug"`
+	ACMEChallengeAnswers []string `json:"acme_challenge_answers"`
+}
+
+// ZoneList is a single page of zones.
+type ZoneList struct {
+	Zones    []Zone `json:"zones"`
+	Continue string `json:"continue,omitempty"`
+}
+
+// CreateZoneRequest is the request body for creating a zone.
+type CreateZoneRequest struct {
+	Name string `json:"name"`
+}
+
+// SetZoneDebugRequest is the request body for turning debugging on or off for a zone.
+type SetZoneDebugRequest struct {
+	Debug bool `json:"debug"`
+}
+
+// Key is the JSON representation of an update key in the management API. The secret is only returned when it is set
+// by the request.
+type Key struct {
+	Name      string   `json:"name"`
+	Secret    string   `json:"secret,omitempty"`
+	Zones     []string `json:"zones"`
+	AllowFrom []string `json:"allow_from"`
+}
+
+// KeyList is a single page of update keys.
+type KeyList struct {
+	Keys     []Key  `json:"keys"`
+	Continue string `json:"continue,omitempty"`
+}
+
+// CreateKeyRequest is the request body for creating an update key. A random secret is generated if none is passed.
+type CreateKeyRequest struct {
+	Name      string   `json:"name"`
+	Secret    string   `json:"secret,omitempty"`
+	Zones     []string `json:"zones,omitempty"`
+	AllowFrom []string `json:"allow_from,omitempty"`
+}
+
+// RotateKeyRequest is the request body for replacing the secret of an update key. A random secret is generated if
+// none is passed.
+type RotateKeyRequest struct {
+	Secret string `json:"secret,omitempty"`
+}
+
+// SetKeyAllowFromRequest is the request body for restricting the networks an update key may be used from.
+type SetKeyAllowFromRequest struct {
+	AllowFrom []string `json:"allow_from"`
+}
+
+func (s *server) registerManagement(mux *http.ServeMux, clientCA *x509.CertPool) {
+	handle := func(pattern string, handler func(w http.ResponseWriter, r *http.Request) error) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.Handle(method+" "+managementPrefix+path, s.requireManagementAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := handler(w, r); err != nil {
+				s.writeError(r.Context(), w, err)
+			}
+		}), clientCA))
+	}
+	mux.HandleFunc("GET "+managementPrefix+"/openapi.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(openAPIDescription)
+	})
+
+	handle("GET /zones", s.listZones)
+	handle("POST /zones", s.createZone)
+	handle("GET /zones/{zone}", s.getZone)
+	handle("DELETE /zones/{zone}", s.deleteZone)
+	handle("PUT /zones/{zone}/debug", s.setZoneDebug)
+
+	handle("GET /keys", s.listKeys)
+	handle("POST /keys", s.createKey)
+	handle("GET /keys/{key}", s.getKey)
+	handle("DELETE /keys/{key}", s.deleteKey)
+	handle("POST /keys/{key}/rotate", s.rotateKey)
+	handle("PUT /keys/{key}/allow-from", s.setKeyAllowFrom)
+	handle("PUT /keys/{key}/zones/{zone}", s.bindKey)
+	handle("DELETE /keys/{key}/zones/{zone}", s.unbindKey)
+
+	handle("GET /backup", s.backup)
+	handle("POST /compact", s.compact)
+}
+
+// normalizeName lowercases zone and key names and removes the trailing dot, matching how the DNS server looks them up.
+func normalizeName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == "" {
+		return "", ErrInvalidRequest.Wrap(fmt.Errorf("name must not be empty"))
+	}
+	return name, nil
+}
+
+// requestSecret returns the secret sent in a request, or a newly generated one if the request doesn't contain one.
+func requestSecret(keySecret string) (string, error) {
+	if keySecret == "" {
+		return secret.Generate()
+	}
+	if err := secret.Validate(keySecret); err != nil {
+		return "", ErrInvalidRequest.Wrap(err)
+	}
+	return keySecret, nil
+}
+
+func listOptions(r *http.Request) (backend.ListOptions, error) {
+	options := backend.ListOptions{
+		Continue: r.URL.Query().Get("continue"),
+	}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		var err error
+		if options.Limit, err = strconv.Atoi(limit); err != nil {
+			return options, ErrInvalidRequest.Wrap(fmt.Errorf("invalid limit: %w", err))
+		}
+	}
+	return options, nil
+}
+
+func newZone(name string, zoneData backend.ProviderZoneResponse) Zone {
+	result := Zone{
+		Name:                 name,
+		Serial:               zoneData.Serial,
+		Debug:                zoneData.Debug,
+		ACMEChallengeAnswers: zoneData.ACMEChallengeAnswers,
+	}
+	if result.ACMEChallengeAnswers == nil {
+		result.ACMEChallengeAnswers = []string{}
+	}
+	return result
+}
+
+func (s *server) listZones(w http.ResponseWriter, r *http.Request) error {
+	options, err := listOptions(r)
+	if err != nil {
+		return err
+	}
+	response, err := s.provider.ListZones(r.Context(), options)
+	if err != nil {
+		return err
+	}
+	result := ZoneList{
+		Zones:    make([]Zone, len(response.Zones)),
+		Continue: response.Continue,
+	}
+	for i, zoneData := range response.Zones {
+		result.Zones[i] = newZone(zoneData.Name, zoneData.ProviderZoneResponse)
+	}
+	writeJSON(w, http.StatusOK, result)
+	return nil
+}
+
+func (s *server) createZone(w http.ResponseWriter, r *http.Request) error {
+	var request CreateZoneRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	name, err := normalizeName(request.Name)
+	if err != nil {
+		return err
+	}
+	if err := s.provider.CreateZone(r.Context(), name); err != nil {
+		return err
+	}
+	zoneData, err := s.provider.GetZone(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, newZone(name, zoneData))
+	return nil
+}
+
+func (s *server) getZone(w http.ResponseWriter, r *http.Request) error {
+	name, err := normalizeName(r.PathValue("zone"))
+	if err != nil {
+		return err
+	}
+	zoneData, err := s.provider.GetZone(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, newZone(name, zoneData))
+	return nil
+}
+
+func (s *server) deleteZone(w http.ResponseWriter, r *http.Request) error {
+	name, err := normalizeName(r.PathValue("zone"))
+	if err != nil {
+		return err
+	}
+	if err := s.provider.DeleteZone(r.Context(), name); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) setZoneDebug(w http.ResponseWriter, r *http.Request) error {
+	name, err := normalizeName(r.PathValue("zone"))
+	if err != nil {
+		return err
+	}
+	var request SetZoneDebugRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	if err := s.provider.SetZoneDebug(r.Context(), name, request.Debug); err != nil {
+		return err
+	}
+	zoneData, err := s.provider.GetZone(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, newZone(name, zoneData))
+	return nil
+}
+
+func (s *server) listKeys(w http.ResponseWriter, r *http.Request) error {
+	options, err := listOptions(r)
+	if err != nil {
+		return err
+	}
+	response, err := s.provider.ListKeys(r.Context(), options)
+	if err != nil {
+		return err
+	}
+	result := KeyList{
+		Keys:     make([]Key, len(response.Keys)),
+		Continue: response.Continue,
+	}
+	for i, keyData := range response.Keys {
+		result.Keys[i] = Key{Name: keyData.Name, Zones: nonNil(keyData.Zones), AllowFrom: nonNil(keyData.AllowFrom)}
+	}
+	writeJSON(w, http.StatusOK, result)
+	return nil
+}
+
+func (s *server) createKey(w http.ResponseWriter, r *http.Request) error {
+	var request CreateKeyRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	name, err := normalizeName(request.Name)
+	if err != nil {
+		return err
+	}
+	zones := make([]string, len(request.Zones))
+	for i, zone := range request.Zones {
+		if zones[i], err = normalizeName(zone); err != nil {
+			return err
+		}
+	}
+	if err := backend.ValidateAllowFrom(request.AllowFrom); err != nil {
+		return err
+	}
+	keySecret, err := requestSecret(request.Secret)
+	if err != nil {
+		return err
+	}
+	if err := s.provider.CreateKey(r.Context(), name, keySecret); err != nil {
+		return err
+	}
+	if err := s.configureKey(r.Context(), name, request.AllowFrom, zones); err != nil {
+		// Don't leave a half-configured key behind, otherwise retrying the request fails with a conflict.
+		if err := s.provider.DeleteKey(r.Context(), name); err != nil {
+			s.logger.WarnContext(r.Context(), "Cannot clean up key after creating it failed", E.ToSLogAttr(err, slog.String("key", name))...)
+		}
+		return err
+	}
+	writeJSON(w, http.StatusCreated, Key{Name: name, Secret: keySecret, Zones: zones, AllowFrom: nonNil(request.AllowFrom)})
+	return nil
+}
+
+// configureKey sets the allowed networks of a newly created key and binds it to the zones.
+func (s *server) configureKey(ctx context.Context, name string, allowFrom []string, zones []string) error {
+	if len(allowFrom) > 0 {
+		if err := s.provider.SetKeyAllowFrom(ctx, name, allowFrom); err != nil {
+			return err
+		}
+	}
+	for _, zone := range zones {
+		if err := s.provider.BindKey(ctx, name, zone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *server) getKey(w http.ResponseWriter, r *http.Request) error {
+	name, err := normalizeName(r.PathValue("key"))
+	if err != nil {
+		return err
+	}
+	keyData, err := s.provider.GetKey(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, Key{Name: name, Zones: nonNil(keyData.Zones), AllowFrom: nonNil(keyData.AllowFrom)})
+	return nil
+}
+
+func (s *server) deleteKey(w http.ResponseWriter, r *http.Request) error {
+	name, err := normalizeName(r.PathValue("key"))
+	if err != nil {
+		return err
+	}
+	if err := s.provider.DeleteKey(r.Context(), name); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) rotateKey(w http.ResponseWriter, r *http.Request) error {
+	name, err := normalizeName(r.PathValue("key"))
+	if err != nil {
+		return err
+	}
+	var request RotateKeyRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	keySecret, err := requestSecret(request.Secret)
+	if err != nil {
+		return err
+	}
+	if err := s.provider.SetKeySecret(r.Context(), name, keySecret); err != nil {
+		return err
+	}
+	keyData, err := s.provider.GetKey(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, Key{Name: name, Secret: keySecret, Zones: nonNil(keyData.Zones), AllowFrom: nonNil(keyData.AllowFrom)})
+	return nil
+}
+
+func (s *server) setKeyAllowFrom(w http.ResponseWriter, r *http.Request) error {
+	name, err := normalizeName(r.PathValue("key"))
+	if err != nil {
+		return err
+	}
+	var request SetKeyAllowFromRequest
+	if err := decodeJSON(w, r, &request); err != nil {
+		return err
+	}
+	if err := backend.ValidateAllowFrom(request.AllowFrom); err != nil {
+		return err
+	}
+	if err := s.provider.SetKeyAllowFrom(r.Context(), name, request.AllowFrom); err != nil {
+		return err
+	}
+	keyData, err := s.provider.GetKey(r.Context(), name)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, Key{Name: name, Zones: nonNil(keyData.Zones), AllowFrom: nonNil(keyData.AllowFrom)})
+	return nil
+}
+
+func (s *server) bindKey(w http.ResponseWriter, r *http.Request) error {
+	keyName, err := normalizeName(r.PathValue("key"))
+	if err != nil {
+		return err
+	}
+	zoneName, err := normalizeName(r.PathValue("zone"))
+	if err != nil {
+		return err
+	}
+	if err := s.provider.BindKey(r.Context(), keyName, zoneName); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) unbindKey(w http.ResponseWriter, r *http.Request) error {
+	keyName, err := normalizeName(r.PathValue("key"))
+	if err != nil {
+		return err
+	}
+	zoneName, err := normalizeName(r.PathValue("zone"))
+	if err != nil {
+		return err
+	}
+	if err := s.provider.UnbindKey(r.Context(), keyName, zoneName); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func nonNil(items []string) []string {
+	if items == nil {
+		return []string{}
+	}
+	return items
+}
@@ -0,0 +1,303 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/api"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/internal/testlogger"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+const testToken = "test-token"
+
+// startAPI starts the HTTP API on a random port on top of an empty in-memory backend and returns its base URL and the
+// backend.
+func startAPI(t *testing.T, config api.Config) (string, backend.ExtendedProvider) {
+	t.Helper()
+	provider, err := inmemory.Config{}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	return startAPIWithProvider(t, config, provider), provider
+}
+
+// startAPIWithProvider starts the HTTP API on a random port on top of the specified backend and returns its base URL.
+func startAPIWithProvider(t *testing.T, config api.Config, provider backend.ExtendedProvider) string {
+	t.Helper()
+	config.Listen = "127.0.0.1:0"
+	config.Timeout = 10 * time.Second
+	srv, err := api.New(config, provider, testlogger.New(t))
+	if err != nil {
+		t.Fatalf("Failed to create HTTP API: %v", err)
+	}
+	running, err := srv.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to start HTTP API: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := running.Stop(context.Background()); err != nil {
+			t.Fatalf("Failed to stop HTTP API: %v", err)
+		}
+	})
+	return "http://" + running.Addr().String()
+}
+
+// request sends a request with the bearer token and decodes the JSON response into result if it is not nil.
+func request(t *testing.T, method string, url string, token string, body any, result any) int {
+	t.Helper()
+	headers := http.Header{}
+	if token != "" {
+		headers.Set("Authorization", "Bearer "+token)
+	}
+	return requestWithHeaders(t, method, url, headers, body, result)
+}
+
+// requestWithHeaders sends a request with the specified headers and decodes the JSON response into result if it is
+// not nil.
+func requestWithHeaders(t *testing.T, method string, url string, headers http.Header, body any, result any) int {
+	t.Helper()
+	var requestBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("Failed to encode request: %v", err)
+		}
+		requestBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(t.Context(), method, url, requestBody)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header = headers
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			t.Fatalf("Failed to decode response for %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestManagementAuth(t *testing.T) {
+	url, _ := startAPI(t, api.Config{Tokens: []string{testToken}})
+
+	var errResponse map[string]string
+	if status := request(t, http.MethodGet, url+"/api/v1/zones", "", nil, &errResponse); status != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status without token: %d", status)
+	}
+	if errResponse["code"] != api.ErrUnauthorized.GetCode() {
+		t.Fatalf("Incorrect error code: %v", errResponse)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/zones", "invalid", nil, nil); status != http.StatusUnauthorized {
+		t.Fatalf("Incorrect status with invalid token: %d", status)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/zones", testToken, nil, nil); status != http.StatusOK {
+		t.Fatalf("Incorrect status with valid token: %d", status)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/openapi.yaml", "", nil, nil); status != http.StatusOK {
+		t.Fatalf("Incorrect status for the OpenAPI description: %d", status)
+	}
+}
+
+func TestManagementDisabled(t *testing.T) {
+	url, _ := startAPI(t, api.Config{})
+
+	if status := request(t, http.MethodGet, url+"/api/v1/zones", testToken, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("Incorrect status with the management API disabled: %d", status)
+	}
+}
+
+func TestManagementZones(t *testing.T) {
+	url, _ := startAPI(t, api.Config{Tokens: []string{testToken}})
+
+	var zone api.Zone
+	if status := request(t, http.MethodPost, url+"/api/v1/zones", testToken, api.CreateZoneRequest{Name: "Example.COM."}, &zone); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when creating zone: %d", status)
+	}
+	if zone.Name != "example.com" {
+		t.Fatalf("Incorrect zone name: %s", zone.Name)
+	}
+
+	var errResponse map[string]string
+	if status := request(t, http.MethodPost, url+"/api/v1/zones", testToken, api.CreateZoneRequest{Name: "example.com"}, &errResponse); status != http.StatusConflict {
+		t.Fatalf("Incorrect status when creating a duplicate zone: %d", status)
+	}
+	if errResponse["code"] != backend.ErrObjectBackendConflict.GetCode() {
+		t.Fatalf("Incorrect error code: %v", errResponse)
+	}
+
+	if status := request(t, http.MethodPut, url+"/api/v1/zones/example.com/debug", testToken, api.SetZoneDebugRequest{Debug: true}, &zone); status != http.StatusOK {
+		t.Fatalf("Incorrect status when enabling debug: %d", status)
+	}
+	if !zone.Debug {
+		t.Fatalf("Debug not enabled")
+	}
+
+	if status := request(t, http.MethodPost, url+"/api/v1/zones", testToken, api.CreateZoneRequest{Name: "example.org"}, nil); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when creating zone: %d", status)
+	}
+	var zones api.ZoneList
+	if status := request(t, http.MethodGet, url+"/api/v1/zones?limit=1", testToken, nil, &zones); status != http.StatusOK {
+		t.Fatalf("Incorrect status when listing zones: %d", status)
+	}
+	if len(zones.Zones) != 1 || zones.Zones[0].Name != "example.com" || zones.Continue == "" {
+		t.Fatalf("Incorrect first page: %v", zones)
+	}
+	var nextZones api.ZoneList
+	if status := request(t, http.MethodGet, url+"/api/v1/zones?limit=1&continue="+zones.Continue, testToken, nil, &nextZones); status != http.StatusOK {
+		t.Fatalf("Incorrect status when listing zones: %d", status)
+	}
+	if len(nextZones.Zones) != 1 || nextZones.Zones[0].Name != "example.org" || nextZones.Continue != "" {
+		t.Fatalf("Incorrect second page: %v", nextZones)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/zones?limit=x", testToken, nil, &errResponse); status != http.StatusBadRequest {
+		t.Fatalf("Incorrect status for an invalid limit: %d", status)
+	}
+
+	if status := request(t, http.MethodDelete, url+"/api/v1/zones/example.com", testToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Incorrect status when deleting zone: %d", status)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/zones/example.com", testToken, nil, &errResponse); status != http.StatusNotFound {
+		t.Fatalf("Incorrect status for a deleted zone: %d", status)
+	}
+	if errResponse["code"] != backend.ErrZoneNotInBackend.GetCode() {
+		t.Fatalf("Incorrect error code: %v", errResponse)
+	}
+}
+
+func TestManagementKeys(t *testing.T) {
+	url, _ := startAPI(t, api.Config{Tokens: []string{testToken}})
+
+	for _, zone := range []string{"example.com", "example.org"} {
+		if status := request(t, http.MethodPost, url+"/api/v1/zones", testToken, api.CreateZoneRequest{Name: zone}, nil); status != http.StatusCreated {
+			t.Fatalf("Incorrect status when creating zone: %d", status)
+		}
+	}
+
+	var key api.Key
+	createRequest := api.CreateKeyRequest{Name: "test", Zones: []string{"example.com"}}
+	if status := request(t, http.MethodPost, url+"/api/v1/keys", testToken, createRequest, &key); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when creating key: %d", status)
+	}
+	if key.Secret == "" {
+		t.Fatalf("No secret generated")
+	}
+	firstSecret := key.Secret
+
+	if status := request(t, http.MethodPut, url+"/api/v1/keys/test/zones/example.org", testToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Incorrect status when binding key: %d", status)
+	}
+	var getKey api.Key
+	if status := request(t, http.MethodGet, url+"/api/v1/keys/test", testToken, nil, &getKey); status != http.StatusOK {
+		t.Fatalf("Incorrect status when getting key: %d", status)
+	}
+	if getKey.Secret != "" {
+		t.Fatalf("Secret returned when getting key")
+	}
+	if !slices.Equal(getKey.Zones, []string{"example.com", "example.org"}) {
+		t.Fatalf("Incorrect zones: %v", getKey.Zones)
+	}
+
+	if status := request(t, http.MethodDelete, url+"/api/v1/keys/test/zones/example.com", testToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Incorrect status when unbinding key: %d", status)
+	}
+	var keys api.KeyList
+	if status := request(t, http.MethodGet, url+"/api/v1/keys", testToken, nil, &keys); status != http.StatusOK {
+		t.Fatalf("Incorrect status when listing keys: %d", status)
+	}
+	if len(keys.Keys) != 1 || !slices.Equal(keys.Keys[0].Zones, []string{"example.org"}) {
+		t.Fatalf("Incorrect key list: %v", keys)
+	}
+
+	if status := request(t, http.MethodPost, url+"/api/v1/keys/test/rotate", testToken, nil, &key); status != http.StatusOK {
+		t.Fatalf("Incorrect status when rotating key: %d", status)
+	}
+	if key.Secret == "" || key.Secret == firstSecret {
+		t.Fatalf("Secret not rotated")
+	}
+
+	var errResponse map[string]string
+	if status := request(t, http.MethodPost, url+"/api/v1/keys", testToken, map[string]string{"nam": "typo"}, &errResponse); status != http.StatusBadRequest {
+		t.Fatalf("Incorrect status for an unknown field: %d", status)
+	}
+	if errResponse["code"] != api.ErrInvalidRequest.GetCode() || errResponse["detail"] == "" {
+		t.Fatalf("Incorrect error response: %v", errResponse)
+	}
+
+	if status := request(t, http.MethodDelete, url+"/api/v1/keys/test", testToken, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("Incorrect status when deleting key: %d", status)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/keys/test", testToken, nil, &errResponse); status != http.StatusNotFound {
+		t.Fatalf("Incorrect status for a deleted key: %d", status)
+	}
+}
+
+func TestManagementCreateKeyBindFailed(t *testing.T) {
+	url, _ := startAPI(t, api.Config{Tokens: []string{testToken}})
+
+	if status := request(t, http.MethodPost, url+"/api/v1/zones", testToken, api.CreateZoneRequest{Name: "example.com"}, nil); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when creating zone: %d", status)
+	}
+	createRequest := api.CreateKeyRequest{Name: "test", Zones: []string{"example.com", "example.org"}}
+	if status := request(t, http.MethodPost, url+"/api/v1/keys", testToken, createRequest, nil); status != http.StatusNotFound {
+		t.Fatalf("Incorrect status when binding to a nonexistent zone: %d", status)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/keys/test", testToken, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("The key has not been deleted after the failed bind: %d", status)
+	}
+	createRequest.Zones = []string{"example.com"}
+	if status := request(t, http.MethodPost, url+"/api/v1/keys", testToken, createRequest, nil); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when retrying to create the key: %d", status)
+	}
+}
+
+func TestManagementTokensRequireTLS(t *testing.T) {
+	provider, err := inmemory.Config{}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	config := api.Config{Listen: "0.0.0.0:8080", Tokens: []string{testToken}, Timeout: 10 * time.Second}
+	if _, err := api.New(config, provider, testlogger.New(t)); !E.Is(err, api.ErrTokensRequireTLS) {
+		t.Fatalf("Incorrect error for tokens without TLS: %v", err)
+	}
+}
+
+func TestManagementInvalidSecret(t *testing.T) {
+	url, _ := startAPI(t, api.Config{Tokens: []string{testToken}})
+
+	var errResponse map[string]string
+	createRequest := api.CreateKeyRequest{Name: "test", Secret: "not base64!"}
+	if status := request(t, http.MethodPost, url+"/api/v1/keys", testToken, createRequest, &errResponse); status != http.StatusBadRequest {
+		t.Fatalf("Incorrect status when creating a key with an invalid secret: %d", status)
+	}
+	if errResponse["code"] != api.ErrInvalidRequest.GetCode() {
+		t.Fatalf("Incorrect error response: %v", errResponse)
+	}
+	if status := request(t, http.MethodGet, url+"/api/v1/keys/test", testToken, nil, nil); status != http.StatusNotFound {
+		t.Fatalf("A key has been created with an invalid secret: %d", status)
+	}
+
+	createRequest.Secret = ""
+	if status := request(t, http.MethodPost, url+"/api/v1/keys", testToken, createRequest, nil); status != http.StatusCreated {
+		t.Fatalf("Incorrect status when creating key: %d", status)
+	}
+	rotateRequest := api.RotateKeyRequest{Secret: "not base64!"}
+	if status := request(t, http.MethodPost, url+"/api/v1/keys/test/rotate", testToken, rotateRequest, nil); status != http.StatusBadRequest {
+		t.Fatalf("Incorrect status when rotating to an invalid secret: %d", status)
+	}
+}
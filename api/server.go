@@ -0,0 +1,330 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// maxRequestSize limits the size of JSON request bodies.
+const maxRequestSize = 1024 * 1024
+
+// New creates the HTTP API server on top of the specified backend provider.
+func New(config Config, provider backend.ExtendedProvider, logger *slog.Logger) (Server, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("no backend provider"))
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &server{
+		config:   config,
+		provider: provider,
+		logger:   logger,
+	}, nil
+}
+
+type Server interface {
+	Start(ctx context.Context) (RunningServer, error)
+}
+
+type RunningServer interface {
+	// Addr returns the address the HTTP API is listening on.
+	Addr() net.Addr
+	Stop(ctx context.Context) error
+}
+
+type server struct {
+	config    Config
+	provider  backend.ExtendedProvider
+	logger    *slog.Logger
+	zoneLocks zoneLocks
+}
+
+func (s *server) Start(ctx context.Context) (RunningServer, error) {
+	tlsConfig, cas, err := s.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	listenConfig := net.ListenConfig{}
+	listener, err := listenConfig.Listen(ctx, "tcp", s.config.Listen)
+	if err != nil {
+		return nil, ErrListenFailed.Wrap(err).WithAttr(slog.String("listen", s.config.Listen))
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	httpServer := &http.Server{
+		Handler:           s.handler(cas),
+		ReadHeaderTimeout: s.config.Timeout,
+		ReadTimeout:       s.config.Timeout,
+		WriteTimeout:      s.config.Timeout,
+		ErrorLog:          slog.NewLogLogger(s.logger.Handler(), slog.LevelDebug),
+		BaseContext: func(_ net.Listener) context.Context {
+			return context.WithoutCancel(ctx)
+		},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.ErrorContext(ctx, "HTTP API stopped unexpectedly", E.ToSLogAttr(err)...)
+		}
+	}()
+	s.logger.InfoContext(
+		ctx,
+		"HTTP API started",
+		slog.String("address", listener.Addr().String()),
+		slog.Bool("tls", tlsConfig != nil),
+		slog.Bool("management", s.config.ManagementEnabled()),
+		slog.Bool("acme_dns", s.config.ACMEDNS.Enabled()),
+		slog.Bool("httpreq", s.config.HTTPReq),
+		slog.Bool("cert_manager", s.config.CertManager.Enabled()),
+	)
+	return &runningServer{
+		httpServer: httpServer,
+		addr:       listener.Addr(),
+		done:       done,
+		logger:     s.logger,
+	}, nil
+}
+
+// clientCAs holds the CAs client certificates are verified against, separately for each group of endpoints.
+type clientCAs struct {
+	management  *x509.CertPool
+	certManager *x509.CertPool
+}
+
+func (s *server) tlsConfig() (*tls.Config, clientCAs, error) {
+	cas := clientCAs{}
+	if !s.config.TLSEnabled() {
+		return nil, cas, nil
+	}
+	cert, err := tls.LoadX509KeyPair(s.config.TLSCert, s.config.TLSKey)
+	if err != nil {
+		return nil, cas, ErrTLSSetupFailed.Wrap(err).
+			WithAttr(slog.String("cert", s.config.TLSCert)).
+			WithAttr(slog.String("key", s.config.TLSKey))
+	}
+	result := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	managementCA, err := readClientCA(s.config.ClientCA)
+	if err != nil {
+		return nil, cas, err
+	}
+	certManagerCA, err := readClientCA(s.config.CertManager.ClientCA)
+	if err != nil {
+		return nil, cas, err
+	}
+	if managementCA != nil || certManagerCA != nil {
+		cas.management = newCertPool(managementCA)
+		cas.certManager = newCertPool(certManagerCA)
+		// The TLS handshake accepts certificates from all CAs, the endpoints check that the certificate was issued by
+		// the CA configured for them. Client certificates are optional because the other endpoints have their own
+		// authentication.
+		result.ClientCAs = newCertPool(managementCA, certManagerCA)
+		result.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return result, cas, nil
+}
+
+// readClientCA reads and checks a file containing PEM-encoded CA certificates. An empty file name results in no data.
+func readClientCA(file string) ([]byte, error) {
+	if file == "" {
+		return nil, nil
+	}
+	caData, err := os.ReadFile(file)
+	if err != nil {
+		return nil, ErrTLSSetupFailed.Wrap(err).WithAttr(slog.String("client_ca", file))
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caData) {
+		return nil, ErrTLSSetupFailed.
+			Wrap(fmt.Errorf("no certificates found")).
+			WithAttr(slog.String("client_ca", file))
+	}
+	return caData, nil
+}
+
+// newCertPool creates a certificate pool from PEM-encoded certificates. It returns nil if there are none.
+func newCertPool(pemData ...[]byte) *x509.CertPool {
+	var pool *x509.CertPool
+	for _, data := range pemData {
+		if data == nil {
+			continue
+		}
+		if pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pool.AppendCertsFromPEM(data)
+	}
+	return pool
+}
+
+func (s *server) handler(cas clientCAs) http.Handler {
+	mux := http.NewServeMux()
+	if s.config.ManagementEnabled() {
+		s.registerManagement(mux, cas.management)
+	}
+	if s.config.ACMEDNS.Enabled() {
+		s.registerACMEDNS(mux)
+	}
+	if s.config.HTTPReq {
+		s.registerHTTPReq(mux)
+	}
+	if s.config.CertManager.Enabled() {
+		s.registerCertManager(mux, cas.certManager)
+	}
+	return mux
+}
+
+// hasClientCert returns true if the request presented a client certificate issued by a CA in pool.
+func hasClientCert(r *http.Request, pool *x509.CertPool) bool {
+	if pool == nil || r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return false
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := r.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+// requireManagementAuth only passes requests to next that present a valid bearer token or a client certificate
+// signed by the configured client CA.
+func (s *server) requireManagementAuth(next http.Handler, clientCA *x509.CertPool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hasClientCert(r, clientCA) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok {
+			for _, validToken := range s.config.Tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(validToken)) == 1 {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+		w.Header().Set("WWW-Authenticate", `Bearer realm="dns4acme"`)
+		s.writeError(r.Context(), w, ErrUnauthorized)
+	})
+}
+
+type runningServer struct {
+	httpServer *http.Server
+	addr       net.Addr
+	done       chan struct{}
+	logger     *slog.Logger
+}
+
+func (r *runningServer) Addr() net.Addr {
+	return r.addr
+}
+
+func (r *runningServer) Stop(ctx context.Context) error {
+	r.logger.InfoContext(ctx, "Stopping HTTP API...")
+	if err := r.httpServer.Shutdown(ctx); err != nil {
+		return ErrShutdownFailed.Wrap(err)
+	}
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ErrShutdownFailed.Wrap(ctx.Err())
+	}
+}
+
+// errorResponse is the JSON body returned for all errors.
+type errorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Detail describes what was wrong with an invalid request.
+	Detail string `json:"detail,omitempty"`
+}
+
+// errorStatuses maps error codes to HTTP status codes. Errors with codes not in this map are reported as internal
+// server errors.
+var errorStatuses = map[string]int{
+	ErrUnauthorized.GetCode():                       http.StatusUnauthorized,
+	ErrInvalidRequest.GetCode():                     http.StatusBadRequest,
+	backend.ErrInvalidListOptions.GetCode():         http.StatusBadRequest,
+	backend.ErrInvalidAllowFrom.GetCode():           http.StatusBadRequest,
+	backend.ErrKeyNotFoundInBackend.GetCode():       http.StatusNotFound,
+	backend.ErrZoneNotInBackend.GetCode():           http.StatusNotFound,
+	backend.ErrObjectNotInBackend.GetCode():         http.StatusNotFound,
+	backend.ErrObjectBackendConflict.GetCode():      http.StatusConflict,
+	backend.ErrZoneAlreadyExistsInBackend.GetCode(): http.StatusConflict,
+	backend.ErrMaintenanceNotSupported.GetCode():    http.StatusNotImplemented,
+	backend.ErrBackendUnavailable.GetCode():         http.StatusServiceUnavailable,
+}
+
+// writeError writes the JSON error response derived from the first error in the chain with a known code. Other errors
+// are logged and returned as a generic internal error to avoid leaking backend details.
+func (s *server) writeError(ctx context.Context, w http.ResponseWriter, err error) {
+	if cause, status, ok := findKnownCause(err, errorStatuses); ok {
+		response := errorResponse{Code: cause.GetCode(), Message: cause.GetMessage()}
+		if cause.GetCode() == ErrInvalidRequest.GetCode() && cause.Unwrap() != nil {
+			response.Detail = cause.Unwrap().Error()
+		}
+		writeJSON(w, status, response)
+		return
+	}
+	s.logger.ErrorContext(ctx, "HTTP API request failed", E.ToSLogAttr(err)...)
+	writeJSON(w, http.StatusInternalServerError, errorResponse{Code: ErrInternal.GetCode(), Message: ErrInternal.GetMessage()})
+}
+
+// findKnownCause returns the first error in the chain whose code is in known, along with the mapped value.
+func findKnownCause[T any](err error, known map[string]T) (E.Error, T, bool) {
+	var typedErr E.Error
+	if errors.As(err, &typedErr) {
+		for cause := range typedErr.Iterator {
+			if value, ok := known[cause.GetCode()]; ok {
+				return cause, value, true
+			}
+		}
+	}
+	var empty T
+	return nil, empty, false
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+// decodeJSON decodes the request body into target. An empty body leaves target unchanged.
+func decodeJSON(w http.ResponseWriter, r *http.Request, target any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target); err != nil && !errors.Is(err, io.EOF) {
+		return ErrInvalidRequest.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// authenticateKey checks HTTP credentials against the update keys and applies the same restrictions as RFC 2136
+// updates: the key must be bound to the zone and used from an allowed network. Key names are matched the way the
+// management API stores them, ignoring case and a trailing dot.
+func (s *server) authenticateKey(r *http.Request, keyName string, password string, zone string) error {
+	keyName, err := normalizeName(keyName)
+	if err != nil || password == "" {
+		return ErrUnauthorized.Wrap(fmt.Errorf("missing credentials"))
+	}
+	keyData, err := s.provider.GetKey(r.Context(), keyName)
+	if err != nil {
+		if E.Is(err, backend.ErrKeyNotFoundInBackend) {
+			return ErrUnauthorized.Wrap(err).WithAttr(slog.String("key", keyName))
+		}
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(password), []byte(keyData.Secret)) != 1 {
+		return ErrUnauthorized.Wrap(fmt.Errorf("invalid password")).WithAttr(slog.String("key", keyName))
+	}
+	remote, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil || !backend.IsAllowedFrom(keyData.AllowFrom, remote.Addr()) {
+		return ErrUnauthorized.
+			Wrap(fmt.Errorf("key is not allowed to be used from this address")).
+			WithAttr(slog.String("key", keyName)).
+			WithAttr(slog.String("remote", r.RemoteAddr))
+	}
+	if !slices.Contains(keyData.Zones, zone) {
+		return ErrUnauthorized.
+			Wrap(fmt.Errorf("key is not authorized to modify zone")).
+			WithAttr(slog.String("key", keyName)).
+			WithAttr(slog.String("zone", zone))
+	}
+	return nil
+}
+
+// updateAnswers changes the ACME challenge answers of a zone. This follows the same backend path as RFC 2136 updates:
+// read the current answers and write them back, which also increments the serial. Updates to the same zone through
+// the HTTP API of this server are serialized, so parallel requests, e.g. for a wildcard and the apex, don't overwrite
+// each other's answers.
+func (s *server) updateAnswers(ctx context.Context, zone string, update func(answers []string) []string) error {
+	unlock := s.zoneLocks.acquire(zone)
+	defer unlock()
+	zoneData, err := s.provider.GetZone(backend.BypassCache(ctx), zone)
+	if err != nil {
+		return err
+	}
+	answers := update(slices.Clone(zoneData.ACMEChallengeAnswers))
+	if err := s.provider.SetZone(ctx, zone, answers); err != nil {
+		return err
+	}
+	if zoneData.Debug {
+		s.logger.DebugContext(ctx, "Zone updated via HTTP API", slog.String("zone", zone), slog.Any("answers", answers))
+	}
+	return nil
+}
+
+// zoneLocks holds a mutex per zone that is currently being updated. Entries are removed once no request uses them.
+type zoneLocks struct {
+	lock  sync.Mutex
+	zones map[string]*zoneLock
+}
+
+type zoneLock struct {
+	sync.Mutex
+	users int
+}
+
+// acquire waits until no other request updates the zone and returns the function to release it.
+func (l *zoneLocks) acquire(zone string) func() {
+	l.lock.Lock()
+	if l.zones == nil {
+		l.zones = map[string]*zoneLock{}
+	}
+	zl, ok := l.zones[zone]
+	if !ok {
+		zl = &zoneLock{}
+		l.zones[zone] = zl
+	}
+	zl.users++
+	l.lock.Unlock()
+
+	zl.Lock()
+	return func() {
+		zl.Unlock()
+		l.lock.Lock()
+		zl.users--
+		if zl.users == 0 {
+			delete(l.zones, zone)
+		}
+		l.lock.Unlock()
+	}
+}
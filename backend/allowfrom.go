@@ -0,0 +1,35 @@
+package backend
+
+import (
+	"log/slog"
+	"net/netip"
+)
+
+// ValidateAllowFrom checks that all entries of an update key's allowed networks are valid CIDR prefixes.
+func ValidateAllowFrom(allowFrom []string) error {
+	for i, prefix := range allowFrom {
+		if _, err := netip.ParsePrefix(prefix); err != nil {
+			return ErrInvalidAllowFrom.Wrap(err).WithAttr(slog.Int("item", i)).WithAttr(slog.String("prefix", prefix))
+		}
+	}
+	return nil
+}
+
+// IsAllowedFrom returns true if an update key with the specified allowed networks may be used from addr. An empty list
+// allows all addresses, invalid prefixes never match.
+func IsAllowedFrom(allowFrom []string, addr netip.Addr) bool {
+	if len(allowFrom) == 0 {
+		return true
+	}
+	addr = addr.Unmap()
+	for _, prefix := range allowFrom {
+		parsed, err := netip.ParsePrefix(prefix)
+		if err != nil {
+			continue
+		}
+		if parsed.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
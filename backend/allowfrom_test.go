@@ -0,0 +1,37 @@
+package backend_test
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+func TestIsAllowedFrom(t *testing.T) {
+	for _, testCase := range []struct {
+		allowFrom []string
+		addr      string
+		expected  bool
+	}{
+		{nil, "192.0.2.1", true},
+		{[]string{"192.0.2.0/24"}, "192.0.2.1", true},
+		{[]string{"192.0.2.0/24"}, "::ffff:192.0.2.1", true},
+		{[]string{"192.0.2.0/24"}, "198.51.100.1", false},
+		{[]string{"2001:db8::/32", "192.0.2.0/24"}, "2001:db8::1", true},
+		{[]string{"invalid"}, "192.0.2.1", false},
+	} {
+		if result := backend.IsAllowedFrom(testCase.allowFrom, netip.MustParseAddr(testCase.addr)); result != testCase.expected {
+			t.Fatalf("Incorrect result for %s in %v: %t", testCase.addr, testCase.allowFrom, result)
+		}
+	}
+}
+
+func TestValidateAllowFrom(t *testing.T) {
+	if err := backend.ValidateAllowFrom([]string{"192.0.2.0/24", "2001:db8::/32"}); err != nil {
+		t.Fatalf("Valid prefixes rejected: %v", err)
+	}
+	if err := backend.ValidateAllowFrom([]string{"192.0.2.1"}); !E.Is(err, backend.ErrInvalidAllowFrom) {
+		t.Fatalf("Incorrect error for an address without prefix length: %v", err)
+	}
+}
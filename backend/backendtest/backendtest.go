@@ -0,0 +1,68 @@
+// Package backendtest contains the conformance test suite every backend must pass. The server, the HTTP API and the
+// management commands rely on all backends returning the same errors and keeping the same guarantees, so each backend
+// runs this suite from its own tests next to its backend-specific tests. The tests of the registry package fail for any
+// registered backend that doesn't.
+package backendtest
+
+import (
+	"errors"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"testing"
+)
+
+// ProviderFactory returns a new provider containing only the specified zones, each with serial 0, no ACME challenge
+// answers and debugging turned off, and no update keys. The factory must close the provider when the test ends.
+type ProviderFactory func(t *testing.T, zones []string) backend.Provider
+
+// ExtendedProviderFactory returns a new provider without any zones or update keys. The factory must close the provider
+// when the test ends.
+type ExtendedProviderFactory func(t *testing.T) backend.ExtendedProvider
+
+// RunProvider runs the conformance tests for the functions of backend.Provider. Use it for backends that cannot create
+// zones themselves; RunExtendedProvider includes these tests.
+func RunProvider(t *testing.T, factory ProviderFactory) {
+	t.Helper()
+	for _, test := range providerTests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory)
+		})
+	}
+}
+
+// RunExtendedProvider runs the conformance tests for the functions of backend.Provider and backend.ExtendedProvider.
+func RunExtendedProvider(t *testing.T, factory ExtendedProviderFactory) {
+	t.Helper()
+	RunProvider(t, func(t *testing.T, zones []string) backend.Provider {
+		t.Helper()
+		provider := factory(t)
+		for _, zone := range zones {
+			if err := provider.CreateZone(t.Context(), zone); err != nil {
+				t.Fatalf("Failed to create zone %s: %v", zone, err)
+			}
+		}
+		return provider
+	})
+	for _, test := range extendedProviderTests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory)
+		})
+	}
+}
+
+type providerTest struct {
+	name string
+	run  func(t *testing.T, factory ProviderFactory)
+}
+
+type extendedProviderTest struct {
+	name string
+	run  func(t *testing.T, factory ExtendedProviderFactory)
+}
+
+// is returns true if the first structured error in the chain of err has the code of expected. Unlike E.Is, it does not
+// accept the code further down the chain, since the first code is the one the HTTP API reports to clients.
+func is(err error, expected E.Error) bool {
+	var structuredErr E.Error
+	return errors.As(err, &structuredErr) && structuredErr.GetCode() == expected.GetCode()
+}
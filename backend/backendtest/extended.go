@@ -0,0 +1,443 @@
+package backendtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"sync"
+	"testing"
+	"time"
+)
+
+var extendedProviderTests = []extendedProviderTest{
+	{"CreateZone", testCreateZone},
+	{"DeleteZone", testDeleteZone},
+	{"CreateKey", testCreateKey},
+	{"MissingKey", testMissingKey},
+	{"SetKeySecret", testSetKeySecret},
+	{"SetKeyAllowFrom", testSetKeyAllowFrom},
+	{"Bindings", testBindings},
+	{"DeleteZoneRemovesBindings", testDeleteZoneRemovesBindings},
+	{"DeleteKeyRemovesBindings", testDeleteKeyRemovesBindings},
+	{"ListEmpty", testListEmpty},
+	{"ListZones", testListZones},
+	{"ListKeys", testListKeys},
+	{"ConcurrentCreateZone", testConcurrentCreateZone},
+	{"ConcurrentBindKey", testConcurrentBindKey},
+	{"Watch", testWatch},
+}
+
+// mustCreate creates the specified zones and update keys. Keys get the secret "secret".
+func mustCreate(t *testing.T, provider backend.ExtendedProvider, zones []string, keys []string) {
+	t.Helper()
+	for _, zone := range zones {
+		if err := provider.CreateZone(t.Context(), zone); err != nil {
+			t.Fatalf("Failed to create zone %s: %v", zone, err)
+		}
+	}
+	for _, key := range keys {
+		if err := provider.CreateKey(t.Context(), key, "secret"); err != nil {
+			t.Fatalf("Failed to create key %s: %v", key, err)
+		}
+	}
+}
+
+// expectKey fails the test if the update key does not have the expected zones and allowed networks.
+func expectKey(t *testing.T, provider backend.ExtendedProvider, keyName string, zones []string, allowFrom []string) {
+	t.Helper()
+	key, err := provider.GetKey(t.Context(), keyName)
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if !equal(key.Zones, zones) {
+		t.Fatalf("Incorrect zones of key %s, expected %v, got %v", keyName, zones, key.Zones)
+	}
+	if !equal(key.AllowFrom, allowFrom) {
+		t.Fatalf("Incorrect allowed networks of key %s, expected %v, got %v", keyName, allowFrom, key.AllowFrom)
+	}
+}
+
+func testCreateZone(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	mustCreate(t, provider, []string{"example.com"}, nil)
+	if err := provider.CreateZone(t.Context(), "example.com"); !is(err, backend.ErrObjectBackendConflict) {
+		t.Fatalf("CreateZone on an existing zone: expected %s, got %v", backend.ErrObjectBackendConflict.GetCode(), err)
+	}
+}
+
+func testDeleteZone(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	if err := provider.DeleteZone(ctx, "example.com"); !is(err, backend.ErrZoneNotInBackend) {
+		t.Fatalf("DeleteZone on a missing zone: expected %s, got %v", backend.ErrZoneNotInBackend.GetCode(), err)
+	}
+	mustCreate(t, provider, []string{"example.com", "example.org"}, nil)
+	if err := provider.DeleteZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to delete zone: %v", err)
+	}
+	if _, err := provider.GetZone(ctx, "example.com"); !is(err, backend.ErrZoneNotInBackend) {
+		t.Fatalf("GetZone on a deleted zone: expected %s, got %v", backend.ErrZoneNotInBackend.GetCode(), err)
+	}
+	if _, err := provider.GetZone(ctx, "example.org"); err != nil {
+		t.Fatalf("Deleting a zone deleted another zone: %v", err)
+	}
+	// A deleted zone can be created again.
+	mustCreate(t, provider, []string{"example.com"}, nil)
+}
+
+func testCreateKey(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, nil, []string{"certbot"})
+	if err := provider.CreateKey(ctx, "certbot", "other"); !is(err, backend.ErrObjectBackendConflict) {
+		t.Fatalf("CreateKey on an existing key: expected %s, got %v", backend.ErrObjectBackendConflict.GetCode(), err)
+	}
+	key, err := provider.GetKey(ctx, "certbot")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if key.Secret != "secret" || len(key.Zones) != 0 || len(key.AllowFrom) != 0 {
+		t.Fatalf("A new key must have the secret it was created with and no zones or allowed networks, got %v", key)
+	}
+}
+
+func testMissingKey(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, []string{"example.com"}, nil)
+	for name, err := range map[string]error{
+		"DeleteKey":       provider.DeleteKey(ctx, "certbot"),
+		"SetKeySecret":    provider.SetKeySecret(ctx, "certbot", "secret"),
+		"SetKeyAllowFrom": provider.SetKeyAllowFrom(ctx, "certbot", []string{"192.0.2.0/24"}),
+		"BindKey":         provider.BindKey(ctx, "certbot", "example.com"),
+		"UnbindKey":       provider.UnbindKey(ctx, "certbot", "example.com"),
+	} {
+		if !is(err, backend.ErrObjectNotInBackend) {
+			t.Errorf("%s on a missing key: expected %s, got %v", name, backend.ErrObjectNotInBackend.GetCode(), err)
+		}
+	}
+}
+
+func testSetKeySecret(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, []string{"example.com"}, []string{"certbot"})
+	if err := provider.BindKey(ctx, "certbot", "example.com"); err != nil {
+		t.Fatalf("Failed to bind key: %v", err)
+	}
+	if err := provider.SetKeySecret(ctx, "certbot", "rotated"); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	key, err := provider.GetKey(ctx, "certbot")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if key.Secret != "rotated" {
+		t.Fatalf("Incorrect secret after rotation: %s", key.Secret)
+	}
+	// Rotating the secret keeps the bindings.
+	expectKey(t, provider, "certbot", []string{"example.com"}, nil)
+}
+
+func testSetKeyAllowFrom(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, nil, []string{"certbot"})
+	allowFrom := []string{"192.0.2.0/24", "2001:db8::/32"}
+	if err := provider.SetKeyAllowFrom(ctx, "certbot", allowFrom); err != nil {
+		t.Fatalf("Failed to set allowed networks: %v", err)
+	}
+	allowFrom[0] = "198.51.100.0/24"
+	expectKey(t, provider, "certbot", nil, []string{"192.0.2.0/24", "2001:db8::/32"})
+	if err := provider.SetKeyAllowFrom(ctx, "certbot", nil); err != nil {
+		t.Fatalf("Failed to clear allowed networks: %v", err)
+	}
+	expectKey(t, provider, "certbot", nil, nil)
+}
+
+func testBindings(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, []string{"example.com", "example.org", "example.net"}, []string{"certbot", "other"})
+
+	if err := provider.BindKey(ctx, "certbot", "example.invalid"); !is(err, backend.ErrZoneNotInBackend) {
+		t.Fatalf("BindKey to a missing zone: expected %s, got %v", backend.ErrZoneNotInBackend.GetCode(), err)
+	}
+	// Binding is idempotent and the zones are returned sorted by name.
+	for _, zone := range []string{"example.org", "example.com", "example.org"} {
+		if err := provider.BindKey(ctx, "certbot", zone); err != nil {
+			t.Fatalf("Failed to bind key: %v", err)
+		}
+	}
+	expectKey(t, provider, "certbot", []string{"example.com", "example.org"}, nil)
+	expectKey(t, provider, "other", nil, nil)
+
+	if err := provider.UnbindKey(ctx, "certbot", "example.net"); !is(err, backend.ErrObjectNotInBackend) {
+		t.Fatalf("UnbindKey on a zone the key is not bound to: expected %s, got %v", backend.ErrObjectNotInBackend.GetCode(), err)
+	}
+	if err := provider.UnbindKey(ctx, "certbot", "example.org"); err != nil {
+		t.Fatalf("Failed to unbind key: %v", err)
+	}
+	expectKey(t, provider, "certbot", []string{"example.com"}, nil)
+	if err := provider.UnbindKey(ctx, "certbot", "example.org"); !is(err, backend.ErrObjectNotInBackend) {
+		t.Fatalf("UnbindKey twice: expected %s, got %v", backend.ErrObjectNotInBackend.GetCode(), err)
+	}
+}
+
+func testDeleteZoneRemovesBindings(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, []string{"example.com", "example.org"}, []string{"certbot"})
+	for _, zone := range []string{"example.com", "example.org"} {
+		if err := provider.BindKey(ctx, "certbot", zone); err != nil {
+			t.Fatalf("Failed to bind key: %v", err)
+		}
+	}
+	if err := provider.DeleteZone(ctx, "example.org"); err != nil {
+		t.Fatalf("Failed to delete zone: %v", err)
+	}
+	expectKey(t, provider, "certbot", []string{"example.com"}, nil)
+
+	// Creating the zone again does not restore the binding.
+	mustCreate(t, provider, []string{"example.org"}, nil)
+	expectKey(t, provider, "certbot", []string{"example.com"}, nil)
+}
+
+func testDeleteKeyRemovesBindings(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, []string{"example.com"}, []string{"certbot"})
+	if err := provider.BindKey(ctx, "certbot", "example.com"); err != nil {
+		t.Fatalf("Failed to bind key: %v", err)
+	}
+	if err := provider.DeleteKey(ctx, "certbot"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err := provider.GetKey(ctx, "certbot"); !is(err, backend.ErrKeyNotFoundInBackend) {
+		t.Fatalf("GetKey on a deleted key: expected %s, got %v", backend.ErrKeyNotFoundInBackend.GetCode(), err)
+	}
+
+	// Creating the key again does not restore the binding.
+	mustCreate(t, provider, nil, []string{"certbot"})
+	expectKey(t, provider, "certbot", nil, nil)
+}
+
+func testListEmpty(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	zones, err := provider.ListZones(ctx, backend.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list zones: %v", err)
+	}
+	if len(zones.Zones) != 0 || zones.Continue != "" {
+		t.Fatalf("Expected no zones, got %v", zones)
+	}
+	keys, err := provider.ListKeys(ctx, backend.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(keys.Keys) != 0 || keys.Continue != "" {
+		t.Fatalf("Expected no keys, got %v", keys)
+	}
+}
+
+func testListZones(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	names := []string{"c.example", "a.example", "e.example", "b.example", "d.example"}
+	mustCreate(t, provider, names, nil)
+	if err := provider.SetZone(ctx, "b.example", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+
+	var zones []backend.ProviderZoneListItem
+	options := backend.ListOptions{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages > len(names) {
+			t.Fatalf("Pagination does not end")
+		}
+		page, err := provider.ListZones(ctx, options)
+		if err != nil {
+			t.Fatalf("Failed to list zones: %v", err)
+		}
+		if len(page.Zones) > options.Limit {
+			t.Fatalf("Expected at most %d zones, got %d", options.Limit, len(page.Zones))
+		}
+		zones = append(zones, page.Zones...)
+		if page.Continue == "" {
+			break
+		}
+		options.Continue = page.Continue
+	}
+	var listed []string
+	for _, zone := range zones {
+		listed = append(listed, zone.Name)
+	}
+	if !equal(listed, []string{"a.example", "b.example", "c.example", "d.example", "e.example"}) {
+		t.Fatalf("Zones must be listed once each, sorted by name, got %v", listed)
+	}
+	if zones[1].Serial != 1 || !equal(zones[1].ACMEChallengeAnswers, []string{"answer"}) {
+		t.Fatalf("Incorrect zone in list: %v", zones[1])
+	}
+
+	if _, err := provider.ListZones(ctx, backend.ListOptions{Limit: -1}); !is(err, backend.ErrInvalidListOptions) {
+		t.Fatalf("ListZones with a negative limit: expected %s, got %v", backend.ErrInvalidListOptions.GetCode(), err)
+	}
+}
+
+func testListKeys(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	mustCreate(t, provider, []string{"example.com", "example.org"}, []string{"c", "a", "b"})
+	for _, zone := range []string{"example.org", "example.com"} {
+		if err := provider.BindKey(ctx, "b", zone); err != nil {
+			t.Fatalf("Failed to bind key: %v", err)
+		}
+	}
+	if err := provider.SetKeyAllowFrom(ctx, "b", []string{"192.0.2.0/24"}); err != nil {
+		t.Fatalf("Failed to set allowed networks: %v", err)
+	}
+
+	page, err := provider.ListKeys(ctx, backend.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(page.Keys) != 2 || page.Keys[0].Name != "a" || page.Keys[1].Name != "b" || page.Continue == "" {
+		t.Fatalf("Incorrect first page: %v", page)
+	}
+	if !equal(page.Keys[1].Zones, []string{"example.com", "example.org"}) || !equal(page.Keys[1].AllowFrom, []string{"192.0.2.0/24"}) {
+		t.Fatalf("Incorrect key in list: %v", page.Keys[1])
+	}
+	page, err = provider.ListKeys(ctx, backend.ListOptions{Limit: 2, Continue: page.Continue})
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(page.Keys) != 1 || page.Keys[0].Name != "c" || page.Continue != "" {
+		t.Fatalf("Incorrect last page: %v", page)
+	}
+
+	if _, err := provider.ListKeys(ctx, backend.ListOptions{Limit: -1}); !is(err, backend.ErrInvalidListOptions) {
+		t.Fatalf("ListKeys with a negative limit: expected %s, got %v", backend.ErrInvalidListOptions.GetCode(), err)
+	}
+}
+
+func testConcurrentCreateZone(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, concurrency)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- provider.CreateZone(ctx, "example.com")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !is(err, backend.ErrObjectBackendConflict):
+			t.Fatalf("Concurrent CreateZone failed: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("Exactly one concurrent CreateZone call must succeed, %d did", created)
+	}
+}
+
+func testConcurrentBindKey(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	ctx := t.Context()
+	zones := make([]string, concurrency)
+	for i := range zones {
+		zones[i] = fmt.Sprintf("zone%02d.example", i)
+	}
+	mustCreate(t, provider, zones, []string{"certbot"})
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, concurrency)
+	for _, zone := range zones {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- provider.BindKey(ctx, "certbot", zone)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent BindKey failed: %v", err)
+		}
+	}
+	expectKey(t, provider, "certbot", zones, nil)
+}
+
+// watchTimeout is the maximum time to wait for a change event.
+const watchTimeout = 10 * time.Second
+
+func testWatch(t *testing.T, factory ExtendedProviderFactory) {
+	provider := factory(t)
+	watchProvider, ok := provider.(backend.WatchProvider)
+	if !ok {
+		t.Skipf("The backend does not implement backend.WatchProvider.")
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	events, err := watchProvider.Watch(ctx)
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+
+	mustCreate(t, provider, []string{"example.com"}, []string{"certbot"})
+	if err := provider.SetZone(t.Context(), "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	if err := provider.BindKey(t.Context(), "certbot", "example.com"); err != nil {
+		t.Fatalf("Failed to bind key: %v", err)
+	}
+	if err := provider.DeleteZone(t.Context(), "example.com"); err != nil {
+		t.Fatalf("Failed to delete zone: %v", err)
+	}
+
+	// Backends may report additional events, for example when a change touches several objects in the backend, but
+	// the expected events must arrive in this order.
+	expected := []backend.ChangeEvent{
+		{Kind: backend.ChangeKindZone, Name: "example.com"},
+		{Kind: backend.ChangeKindKey, Name: "certbot"},
+		{Kind: backend.ChangeKindZone, Name: "example.com"},
+		{Kind: backend.ChangeKindKey, Name: "certbot"},
+		{Kind: backend.ChangeKindZone, Name: "example.com", Deleted: true},
+	}
+	timeout := time.After(watchTimeout)
+	for len(expected) > 0 {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("The event channel was closed while waiting for %v", expected[0])
+			}
+			if event == expected[0] {
+				expected = expected[1:]
+			}
+		case <-timeout:
+			t.Fatalf("Timeout while waiting for %v", expected[0])
+		}
+	}
+
+	// Canceling the context closes the channel.
+	cancel()
+	timeout = time.After(watchTimeout)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("The event channel was not closed after canceling the context")
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package backendtest
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// concurrency is the number of goroutines the concurrency tests run in parallel.
+const concurrency = 20
+
+var providerTests = []providerTest{
+	{"MissingObjects", testMissingObjects},
+	{"NewZone", testNewZone},
+	{"SetZone", testSetZone},
+	{"SetZoneDebug", testSetZoneDebug},
+	{"ReturnedValuesAreCopies", testReturnedValuesAreCopies},
+	{"ConcurrentSetZone", testConcurrentSetZone},
+}
+
+func testMissingObjects(t *testing.T, factory ProviderFactory) {
+	provider := factory(t, nil)
+	ctx := t.Context()
+	if _, err := provider.GetZone(ctx, "example.com"); !is(err, backend.ErrZoneNotInBackend) {
+		t.Errorf("GetZone on a missing zone: expected %s, got %v", backend.ErrZoneNotInBackend.GetCode(), err)
+	}
+	if err := provider.SetZone(ctx, "example.com", []string{"answer"}); !is(err, backend.ErrZoneNotInBackend) {
+		t.Errorf("SetZone on a missing zone: expected %s, got %v", backend.ErrZoneNotInBackend.GetCode(), err)
+	}
+	if err := provider.SetZoneDebug(ctx, "example.com", true); !is(err, backend.ErrZoneNotInBackend) {
+		t.Errorf("SetZoneDebug on a missing zone: expected %s, got %v", backend.ErrZoneNotInBackend.GetCode(), err)
+	}
+	if _, err := provider.GetKey(ctx, "certbot"); !is(err, backend.ErrKeyNotFoundInBackend) {
+		t.Errorf("GetKey on a missing key: expected %s, got %v", backend.ErrKeyNotFoundInBackend.GetCode(), err)
+	}
+}
+
+func testNewZone(t *testing.T, factory ProviderFactory) {
+	provider := factory(t, []string{"example.com"})
+	zone, err := provider.GetZone(t.Context(), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != 0 || len(zone.ACMEChallengeAnswers) != 0 || zone.Debug {
+		t.Fatalf("A new zone must have serial 0, no answers and debugging off, got %v", zone)
+	}
+}
+
+func testSetZone(t *testing.T, factory ProviderFactory) {
+	provider := factory(t, []string{"example.com", "example.org"})
+	ctx := t.Context()
+
+	// The answers are returned in the order they were set in.
+	for i, answers := range [][]string{{"second", "first"}, {"third"}, nil} {
+		if err := provider.SetZone(ctx, "example.com", answers); err != nil {
+			t.Fatalf("Failed to set zone: %v", err)
+		}
+		zone, err := provider.GetZone(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		if zone.Serial != uint32(i+1) {
+			t.Fatalf("Every SetZone call must increment the serial by one, expected %d, got %d", i+1, zone.Serial)
+		}
+		if !equal(zone.ACMEChallengeAnswers, answers) {
+			t.Fatalf("Incorrect answers, expected %v, got %v", answers, zone.ACMEChallengeAnswers)
+		}
+	}
+
+	// Other zones are not affected.
+	zone, err := provider.GetZone(ctx, "example.org")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != 0 || len(zone.ACMEChallengeAnswers) != 0 {
+		t.Fatalf("Setting a zone changed another zone: %v", zone)
+	}
+}
+
+func testSetZoneDebug(t *testing.T, factory ProviderFactory) {
+	provider := factory(t, []string{"example.com"})
+	ctx := t.Context()
+	if err := provider.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	for _, debug := range []bool{true, false} {
+		if err := provider.SetZoneDebug(ctx, "example.com", debug); err != nil {
+			t.Fatalf("Failed to set debug: %v", err)
+		}
+		zone, err := provider.GetZone(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		if zone.Debug != debug {
+			t.Fatalf("Expected debug %t, got %t", debug, zone.Debug)
+		}
+		// Backends may increment the serial when changing the debug flag, but it must never go backwards.
+		if zone.Serial < 1 {
+			t.Fatalf("Setting debug decreased the serial to %d", zone.Serial)
+		}
+		if !slices.Equal(zone.ACMEChallengeAnswers, []string{"answer"}) {
+			t.Fatalf("Setting debug changed the answers to %v", zone.ACMEChallengeAnswers)
+		}
+	}
+}
+
+func testReturnedValuesAreCopies(t *testing.T, factory ProviderFactory) {
+	provider := factory(t, []string{"example.com"})
+	ctx := t.Context()
+	answers := []string{"first", "second"}
+	if err := provider.SetZone(ctx, "example.com", answers); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	answers[0] = "changed"
+	zone, err := provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zone.ACMEChallengeAnswers, []string{"first", "second"}) {
+		t.Fatalf("Changing the slice passed to SetZone changed the stored answers to %v", zone.ACMEChallengeAnswers)
+	}
+	zone.ACMEChallengeAnswers[0] = "changed"
+	zone, err = provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zone.ACMEChallengeAnswers, []string{"first", "second"}) {
+		t.Fatalf("Changing the slice returned by GetZone changed the stored answers to %v", zone.ACMEChallengeAnswers)
+	}
+}
+
+func testConcurrentSetZone(t *testing.T, factory ProviderFactory) {
+	provider := factory(t, []string{"example.com"})
+	ctx := t.Context()
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, 2*concurrency)
+	for range concurrency {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- provider.SetZone(ctx, "example.com", []string{"answer"})
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := provider.GetZone(ctx, "example.com")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent update failed: %v", err)
+		}
+	}
+	zone, err := provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != concurrency {
+		t.Fatalf("Concurrent SetZone calls lost updates, expected serial %d, got %d", concurrency, zone.Serial)
+	}
+}
+
+// equal compares two lists, treating nil and empty lists as equal.
+func equal(a []string, b []string) bool {
+	return len(a) == 0 && len(b) == 0 || slices.Equal(a, b)
+}
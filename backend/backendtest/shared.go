@@ -0,0 +1,177 @@
+package backendtest
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventuallyTimeout is the time Eventually waits for a change to reach another provider.
+const eventuallyTimeout = 10 * time.Second
+
+// SharedStorageFactory creates a new, empty storage, such as a database file or a database server, and returns a
+// factory opening providers on it. The providers behave like DNS4ACME processes or replicas sharing the storage. The
+// factory must close the providers when the test ends.
+type SharedStorageFactory func(t *testing.T) ExtendedProviderFactory
+
+// RunPersistent runs the conformance tests for backends keeping their data outside the process, which must survive
+// closing the provider.
+func RunPersistent(t *testing.T, factory SharedStorageFactory) {
+	t.Helper()
+	for _, test := range persistentTests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory)
+		})
+	}
+}
+
+// RunReplicas runs the conformance tests for backends that can be used by several providers at the same time. Changes
+// made through one provider must reach the others.
+func RunReplicas(t *testing.T, factory SharedStorageFactory) {
+	t.Helper()
+	for _, test := range replicaTests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, factory)
+		})
+	}
+}
+
+// Eventually fails the test if condition doesn't return true in time. Use it to wait for changes that reach other
+// providers asynchronously, for example through a watch or a cache invalidation.
+func Eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(eventuallyTimeout)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("The change did not reach the other provider in time.")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+type sharedStorageTest struct {
+	name string
+	run  func(t *testing.T, factory SharedStorageFactory)
+}
+
+var persistentTests = []sharedStorageTest{
+	{"Reopen", testReopen},
+}
+
+var replicaTests = []sharedStorageTest{
+	{"ReplicaChanges", testReplicaChanges},
+	{"ReplicaConcurrentSetZone", testReplicaConcurrentSetZone},
+}
+
+func testReopen(t *testing.T, factory SharedStorageFactory) {
+	open := factory(t)
+	provider := open(t)
+	ctx := t.Context()
+	mustCreate(t, provider, []string{"example.com", "example.org"}, []string{"certbot"})
+	for _, zone := range []string{"example.com", "example.org"} {
+		if err := provider.BindKey(ctx, "certbot", zone); err != nil {
+			t.Fatalf("Failed to bind key: %v", err)
+		}
+	}
+	if err := provider.SetKeyAllowFrom(ctx, "certbot", []string{"192.0.2.0/24"}); err != nil {
+		t.Fatalf("Failed to set allowed networks: %v", err)
+	}
+	if err := provider.SetZone(ctx, "example.com", []string{"first", "second"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	if err := provider.SetZoneDebug(ctx, "example.com", true); err != nil {
+		t.Fatalf("Failed to set debug: %v", err)
+	}
+	if err := provider.Close(ctx); err != nil {
+		t.Fatalf("Failed to close provider: %v", err)
+	}
+
+	provider = open(t)
+	expectKey(t, provider, "certbot", []string{"example.com", "example.org"}, []string{"192.0.2.0/24"})
+	key, err := provider.GetKey(ctx, "certbot")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if key.Secret != "secret" {
+		t.Fatalf("Incorrect secret after reopening: %s", key.Secret)
+	}
+	zone, err := provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != 1 || !zone.Debug || !equal(zone.ACMEChallengeAnswers, []string{"first", "second"}) {
+		t.Fatalf("Incorrect zone after reopening: %v", zone)
+	}
+}
+
+func testReplicaChanges(t *testing.T, factory SharedStorageFactory) {
+	open := factory(t)
+	writer := open(t)
+	reader := open(t)
+	ctx := t.Context()
+
+	mustCreate(t, writer, []string{"example.com"}, []string{"certbot"})
+	if err := writer.BindKey(ctx, "certbot", "example.com"); err != nil {
+		t.Fatalf("Failed to bind key: %v", err)
+	}
+	if err := writer.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	Eventually(t, func() bool {
+		zone, err := reader.GetZone(ctx, "example.com")
+		return err == nil && equal(zone.ACMEChallengeAnswers, []string{"answer"})
+	})
+	Eventually(t, func() bool {
+		key, err := reader.GetKey(ctx, "certbot")
+		return err == nil && equal(key.Zones, []string{"example.com"})
+	})
+
+	// Deleting the zone also changes the bindings of the key.
+	if err := writer.DeleteZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to delete zone: %v", err)
+	}
+	Eventually(t, func() bool {
+		_, err := reader.GetZone(ctx, "example.com")
+		return is(err, backend.ErrZoneNotInBackend)
+	})
+	Eventually(t, func() bool {
+		key, err := reader.GetKey(ctx, "certbot")
+		return err == nil && len(key.Zones) == 0
+	})
+}
+
+func testReplicaConcurrentSetZone(t *testing.T, factory SharedStorageFactory) {
+	open := factory(t)
+	providers := []backend.ExtendedProvider{open(t), open(t)}
+	ctx := t.Context()
+	mustCreate(t, providers[0], []string{"example.com"}, nil)
+	Eventually(t, func() bool {
+		_, err := providers[1].GetZone(ctx, "example.com")
+		return err == nil
+	})
+
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, concurrency)
+	for i := range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- providers[i%len(providers)].SetZone(ctx, "example.com", []string{"answer"})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent update failed: %v", err)
+		}
+	}
+	// No two providers may hand out the same serial.
+	for _, provider := range providers {
+		Eventually(t, func() bool {
+			zone, err := provider.GetZone(ctx, "example.com")
+			return err == nil && zone.Serial == concurrency
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package bbolt
+
+import (
+	"context"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	bolt "go.etcd.io/bbolt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// schemaVersion is the version of the bucket layout written to the meta bucket. Increment it and migrate the data in
+// initialize when changing the layout.
+const schemaVersion = 1
+
+var (
+	metaBucket  = []byte("meta")
+	zonesBucket = []byte("zones")
+	keysBucket  = []byte("keys")
+	versionKey  = []byte("version")
+)
+
+type Config struct {
+	Path        string        `config:"path" default:"dns4acme.db" description:"Path to the bbolt database file. The file is created if it does not exist."`
+	LockTimeout time.Duration `config:"lock-timeout" default:"5s" description:"Maximum time to wait for another process to release the database file. Only one process can open the file at a time."`
+}
+
+func (c Config) Build(ctx context.Context) (backend.Provider, error) {
+	return c.BuildExtended(ctx)
+}
+
+func (c Config) BuildExtended(_ context.Context) (backend.ExtendedProvider, error) {
+	if c.Path == "" {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the bbolt database path must not be empty"))
+	}
+	if c.LockTimeout <= 0 {
+		// bbolt waits forever with a timeout of 0, which would hang the startup if another process holds the file.
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the bbolt lock timeout must be positive"))
+	}
+	p := &provider{
+		lock:   &sync.RWMutex{},
+		config: c,
+	}
+	db, err := p.open(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	p.db = db
+	return p, nil
+}
+
+// options returns the bbolt options for the database file and its compacted copy.
+func (c Config) options() *bolt.Options {
+	return &bolt.Options{Timeout: c.LockTimeout}
+}
+
+// open opens the database file at path and creates the buckets if they do not exist yet.
+func (p *provider) open(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, p.config.options())
+	if err != nil {
+		return nil, ErrOpenFailed.Wrap(err).WithAttr(slog.String("path", path))
+	}
+	if err := db.Update(initialize); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// initialize creates the buckets of a new database and checks the schema version of an existing one.
+func initialize(tx *bolt.Tx) error {
+	meta, err := tx.CreateBucketIfNotExists(metaBucket)
+	if err != nil {
+		return ErrOpenFailed.Wrap(err)
+	}
+	if value := meta.Get(versionKey); value != nil {
+		version, err := strconv.Atoi(string(value))
+		if err != nil {
+			return ErrOpenFailed.Wrap(fmt.Errorf("invalid schema version: %w", err))
+		}
+		if version > schemaVersion {
+			return ErrSchemaTooNew.WithAttr(slog.Int("version", version)).WithAttr(slog.Int("supported_version", schemaVersion))
+		}
+	} else if err := meta.Put(versionKey, []byte(strconv.Itoa(schemaVersion))); err != nil {
+		return ErrOpenFailed.Wrap(err)
+	}
+	for _, name := range [][]byte{zonesBucket, keysBucket} {
+		if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+			return ErrOpenFailed.Wrap(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package bbolt
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+type descriptor struct {
+}
+
+func (d descriptor) Name() string {
+	return "bbolt"
+}
+
+func (d descriptor) Description() string {
+	return "Backend that stores zones and update keys in an embedded bbolt database file."
+}
+
+func (d descriptor) Config() backend.Config {
+	return &Config{}
+}
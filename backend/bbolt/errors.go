@@ -0,0 +1,8 @@
+package bbolt
+
+import "github.com/dns4acme/dns4acme/lang/E"
+
+var ErrOpenFailed = E.New("BBOLT_OPEN_FAILED", "failed to open the bbolt database")
+var ErrSchemaTooNew = E.New("BBOLT_SCHEMA_TOO_NEW", "the bbolt database was created by a newer version of DNS4ACME")
+var ErrCompactFailed = E.New("BBOLT_COMPACT_FAILED", "failed to compact the bbolt database")
+var ErrClosed = E.New("BBOLT_CLOSED", "the bbolt database is closed")
@@ -0,0 +1,3 @@
+package bbolt
+
+const ID = "bbolt"
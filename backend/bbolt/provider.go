@@ -0,0 +1,435 @@
+package bbolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+	bolt "go.etcd.io/bbolt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Zones and update keys are stored as JSON values in the zones and keys buckets, keyed by their name. The zones an
+// update key is bound to are part of the key value.
+type zoneValue struct {
+	Serial  uint32   `json:"serial"`
+	Answers []string `json:"answers,omitempty"`
+	Debug   bool     `json:"debug,omitempty"`
+}
+
+type keyValue struct {
+	Secret string `json:"secret"`
+	// Zones is kept sorted.
+	Zones     []string `json:"zones,omitempty"`
+	AllowFrom []string `json:"allowFrom,omitempty"`
+}
+
+type provider struct {
+	// lock protects db, which Compact replaces and Close sets to nil. Transactions hold the read lock.
+	lock   *sync.RWMutex
+	config Config
+	db     *bolt.DB
+}
+
+func (p *provider) GetKey(_ context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	var key keyValue
+	err := p.view(func(tx *bolt.Tx) error {
+		return getKey(tx, keyName, &key, backend.ErrKeyNotFoundInBackend)
+	})
+	if err != nil {
+		return backend.ProviderKeyResponse{}, err
+	}
+	return backend.ProviderKeyResponse{Secret: key.Secret, Zones: key.Zones, AllowFrom: key.AllowFrom}, nil
+}
+
+func (p *provider) GetZone(_ context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	var zone zoneValue
+	err := p.view(func(tx *bolt.Tx) error {
+		return getZone(tx, zoneName, &zone)
+	})
+	if err != nil {
+		return backend.ProviderZoneResponse{}, err
+	}
+	return backend.ProviderZoneResponse{Serial: zone.Serial, ACMEChallengeAnswers: zone.Answers, Debug: zone.Debug}, nil
+}
+
+func (p *provider) SetZone(_ context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	return p.updateZone(zoneName, func(zone *zoneValue) {
+		// The serial wraps around like an RFC 1982 serial number.
+		zone.Serial++
+		zone.Answers = backend.NilIfEmpty(acmeChallengeAnswers)
+	})
+}
+
+func (p *provider) SetZoneDebug(_ context.Context, zoneName string, debug bool) error {
+	return p.updateZone(zoneName, func(zone *zoneValue) {
+		zone.Debug = debug
+	})
+}
+
+func (p *provider) CreateKey(_ context.Context, keyName string, secret string) error {
+	return p.update(func(tx *bolt.Tx) error {
+		if tx.Bucket(keysBucket).Get([]byte(keyName)) != nil {
+			return backend.ErrObjectBackendConflict
+		}
+		return put(tx, keysBucket, keyName, keyValue{Secret: secret})
+	})
+}
+
+func (p *provider) DeleteKey(_ context.Context, keyName string) error {
+	return p.update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(keysBucket)
+		if bucket.Get([]byte(keyName)) == nil {
+			return backend.ErrObjectNotInBackend
+		}
+		return bucket.Delete([]byte(keyName))
+	})
+}
+
+func (p *provider) SetKeySecret(_ context.Context, keyName string, secret string) error {
+	return p.updateKey(keyName, func(key *keyValue) error {
+		key.Secret = secret
+		return nil
+	})
+}
+
+func (p *provider) SetKeyAllowFrom(_ context.Context, keyName string, allowFrom []string) error {
+	return p.updateKey(keyName, func(key *keyValue) error {
+		key.AllowFrom = backend.NilIfEmpty(allowFrom)
+		return nil
+	})
+}
+
+func (p *provider) BindKey(_ context.Context, keyName string, zoneName string) error {
+	return p.update(func(tx *bolt.Tx) error {
+		var key keyValue
+		if err := getKey(tx, keyName, &key, backend.ErrObjectNotInBackend); err != nil {
+			return err
+		}
+		if tx.Bucket(zonesBucket).Get([]byte(zoneName)) == nil {
+			return backend.ErrZoneNotInBackend
+		}
+		i, found := slices.BinarySearch(key.Zones, zoneName)
+		if found {
+			return nil
+		}
+		key.Zones = slices.Insert(key.Zones, i, zoneName)
+		return put(tx, keysBucket, keyName, key)
+	})
+}
+
+func (p *provider) UnbindKey(_ context.Context, keyName string, zoneName string) error {
+	return p.updateKey(keyName, func(key *keyValue) error {
+		i, found := slices.BinarySearch(key.Zones, zoneName)
+		if !found {
+			return backend.ErrObjectNotInBackend
+		}
+		key.Zones = backend.NilIfEmpty(slices.Delete(key.Zones, i, i+1))
+		return nil
+	})
+}
+
+func (p *provider) CreateZone(_ context.Context, zoneName string) error {
+	return p.update(func(tx *bolt.Tx) error {
+		if tx.Bucket(zonesBucket).Get([]byte(zoneName)) != nil {
+			return backend.ErrObjectBackendConflict
+		}
+		return put(tx, zonesBucket, zoneName, zoneValue{})
+	})
+}
+
+func (p *provider) DeleteZone(_ context.Context, zoneName string) error {
+	return p.update(func(tx *bolt.Tx) error {
+		zones := tx.Bucket(zonesBucket)
+		if zones.Get([]byte(zoneName)) == nil {
+			return backend.ErrZoneNotInBackend
+		}
+		if err := zones.Delete([]byte(zoneName)); err != nil {
+			return err
+		}
+		// The key bindings are removed along with the zone. Buckets must not be modified while iterating over them,
+		// so the changed keys are written afterwards.
+		changed := map[string]keyValue{}
+		if err := tx.Bucket(keysBucket).ForEach(func(name []byte, value []byte) error {
+			var key keyValue
+			if err := json.Unmarshal(value, &key); err != nil {
+				return err
+			}
+			if i, found := slices.BinarySearch(key.Zones, zoneName); found {
+				key.Zones = backend.NilIfEmpty(slices.Delete(key.Zones, i, i+1))
+				changed[string(name)] = key
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for name, key := range changed {
+			if err := put(tx, keysBucket, name, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *provider) ListZones(_ context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	var zones []backend.ProviderZoneListItem
+	if err := p.view(func(tx *bolt.Tx) error {
+		// bbolt keeps the keys sorted bytewise, which is the order Paginate expects.
+		return tx.Bucket(zonesBucket).ForEach(func(name []byte, value []byte) error {
+			var zone zoneValue
+			if err := json.Unmarshal(value, &zone); err != nil {
+				return err
+			}
+			zones = append(zones, backend.ProviderZoneListItem{
+				Name: string(name),
+				ProviderZoneResponse: backend.ProviderZoneResponse{
+					Serial:               zone.Serial,
+					ACMEChallengeAnswers: zone.Answers,
+					Debug:                zone.Debug,
+				},
+			})
+			return nil
+		})
+	}); err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	page, continueToken, err := backend.Paginate(zones, func(zone backend.ProviderZoneListItem) string {
+		return zone.Name
+	}, options)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	return backend.ProviderZoneListResponse{Zones: backend.NilIfEmpty(page), Continue: continueToken}, nil
+}
+
+func (p *provider) ListKeys(_ context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	var keys []backend.ProviderKeyListItem
+	if err := p.view(func(tx *bolt.Tx) error {
+		return tx.Bucket(keysBucket).ForEach(func(name []byte, value []byte) error {
+			var key keyValue
+			if err := json.Unmarshal(value, &key); err != nil {
+				return err
+			}
+			keys = append(keys, backend.ProviderKeyListItem{Name: string(name), Zones: key.Zones, AllowFrom: key.AllowFrom})
+			return nil
+		})
+	}); err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	page, continueToken, err := backend.Paginate(keys, func(key backend.ProviderKeyListItem) string {
+		return key.Name
+	}, options)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	return backend.ProviderKeyListResponse{Keys: backend.NilIfEmpty(page), Continue: continueToken}, nil
+}
+
+func (p *provider) Backup(_ context.Context, w io.Writer) (int64, error) {
+	var written int64
+	// A read transaction sees a consistent snapshot, so updates can continue while the backup is written.
+	err := p.view(func(tx *bolt.Tx) error {
+		var err error
+		written, err = tx.WriteTo(w)
+		return err
+	})
+	return written, err
+}
+
+func (p *provider) Compact(ctx context.Context) (backend.CompactResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return backend.CompactResponse{}, ErrCompactFailed.Wrap(err)
+	}
+	// The write lock waits for running transactions and holds back new ones until the compacted file is open.
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.db == nil {
+		return backend.CompactResponse{}, ErrClosed
+	}
+	path := p.db.Path()
+	sizeBefore, err := fileSize(path)
+	if err != nil {
+		return backend.CompactResponse{}, ErrCompactFailed.Wrap(err)
+	}
+	compactedPath := path + ".compact"
+	if err := p.compactTo(compactedPath); err != nil {
+		_ = os.Remove(compactedPath)
+		return backend.CompactResponse{}, ErrCompactFailed.Wrap(err)
+	}
+	// Make sure the compacted file can be used before the live database is closed.
+	compacted, err := p.open(compactedPath)
+	if err == nil {
+		err = compacted.Close()
+	}
+	if err != nil {
+		_ = os.Remove(compactedPath)
+		return backend.CompactResponse{}, ErrCompactFailed.Wrap(err)
+	}
+	if err := p.replace(path, compactedPath); err != nil {
+		// Keep serving the original file rather than failing every request until a restart.
+		db, openErr := p.open(path)
+		p.db = db
+		return backend.CompactResponse{}, ErrCompactFailed.Wrap(errors.Join(err, openErr))
+	}
+	sizeAfter, err := fileSize(path)
+	if err != nil {
+		return backend.CompactResponse{}, ErrCompactFailed.Wrap(err)
+	}
+	return backend.CompactResponse{SizeBefore: sizeBefore, SizeAfter: sizeAfter}, nil
+}
+
+// replace closes the live database, moves the compacted file in its place and opens it. If anything fails, the original
+// file is moved back, so the caller can open it again.
+func (p *provider) replace(path string, compactedPath string) error {
+	// The compacted file is only left at compactedPath if it hasn't been moved in place.
+	defer func() {
+		_ = os.Remove(compactedPath)
+	}()
+	if err := p.db.Close(); err != nil {
+		return err
+	}
+	originalPath := path + ".original"
+	if err := os.Rename(path, originalPath); err != nil {
+		return err
+	}
+	if err := os.Rename(compactedPath, path); err != nil {
+		return errors.Join(err, os.Rename(originalPath, path))
+	}
+	db, err := p.open(path)
+	if err != nil {
+		return errors.Join(err, os.Rename(originalPath, path))
+	}
+	p.db = db
+	_ = os.Remove(originalPath)
+	return nil
+}
+
+// compactTo copies the database into a new file at path, which only contains the pages in use.
+func (p *provider) compactTo(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	compacted, err := bolt.Open(path, 0o600, p.config.options())
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(compacted, p.db, 0); err != nil {
+		_ = compacted.Close()
+		return err
+	}
+	return compacted.Close()
+}
+
+func (p *provider) Close(_ context.Context) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.db == nil {
+		return nil
+	}
+	err := p.db.Close()
+	p.db = nil
+	if err != nil {
+		return backend.RequestFailed(err, "", "")
+	}
+	return nil
+}
+
+// view runs f in a read-only transaction.
+func (p *provider) view(f func(tx *bolt.Tx) error) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.db == nil {
+		return ErrClosed
+	}
+	return wrapError(p.db.View(f))
+}
+
+// update runs f in a read-write transaction, which is committed if f succeeds. bbolt runs one read-write
+// transaction at a time, so the changes of concurrent updates never overlap.
+func (p *provider) update(f func(tx *bolt.Tx) error) error {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	if p.db == nil {
+		return ErrClosed
+	}
+	return wrapError(p.db.Update(f))
+}
+
+func (p *provider) updateZone(zoneName string, f func(zone *zoneValue)) error {
+	return p.update(func(tx *bolt.Tx) error {
+		var zone zoneValue
+		if err := getZone(tx, zoneName, &zone); err != nil {
+			return err
+		}
+		f(&zone)
+		return put(tx, zonesBucket, zoneName, zone)
+	})
+}
+
+func (p *provider) updateKey(keyName string, f func(key *keyValue) error) error {
+	return p.update(func(tx *bolt.Tx) error {
+		var key keyValue
+		if err := getKey(tx, keyName, &key, backend.ErrObjectNotInBackend); err != nil {
+			return err
+		}
+		if err := f(&key); err != nil {
+			return err
+		}
+		return put(tx, keysBucket, keyName, key)
+	})
+}
+
+func getZone(tx *bolt.Tx, zoneName string, zone *zoneValue) error {
+	value := tx.Bucket(zonesBucket).Get([]byte(zoneName))
+	if value == nil {
+		return backend.ErrZoneNotInBackend
+	}
+	if err := json.Unmarshal(value, zone); err != nil {
+		return backend.RequestFailed(err, "zone", zoneName)
+	}
+	return nil
+}
+
+func getKey(tx *bolt.Tx, keyName string, key *keyValue, notFound error) error {
+	value := tx.Bucket(keysBucket).Get([]byte(keyName))
+	if value == nil {
+		return notFound
+	}
+	if err := json.Unmarshal(value, key); err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	return nil
+}
+
+func put(tx *bolt.Tx, bucket []byte, name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucket).Put([]byte(name), data)
+}
+
+// wrapError passes the errors returned by the backend unchanged and reports all others as failed requests.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var typedErr E.Error
+	if errors.As(err, &typedErr) {
+		return err
+	}
+	return backend.RequestFailed(err, "", "")
+}
+
+func fileSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
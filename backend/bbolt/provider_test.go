@@ -0,0 +1,140 @@
+package bbolt_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/bbolt"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+func open(t *testing.T, path string) backend.ExtendedProvider {
+	t.Helper()
+	provider, err := bbolt.Config{Path: path, LockTimeout: time.Second}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Close(context.Background())
+	})
+	return provider
+}
+
+func TestProviderLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns4acme.db")
+	open(t, path)
+	_, err := bbolt.Config{Path: path, LockTimeout: 100 * time.Millisecond}.BuildExtended(t.Context())
+	if !E.Is(err, bbolt.ErrOpenFailed) {
+		t.Fatalf("Expected an open error while another provider holds the file, got %v", err)
+	}
+}
+
+func TestProviderBackup(t *testing.T) {
+	dir := t.TempDir()
+	provider := open(t, filepath.Join(dir, "dns4acme.db"))
+	ctx := t.Context()
+	if err := provider.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	if err := provider.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+
+	backup := &bytes.Buffer{}
+	written, err := provider.(backend.MaintenanceProvider).Backup(ctx, backup)
+	if err != nil {
+		t.Fatalf("Failed to back up database: %v", err)
+	}
+	if written != int64(backup.Len()) {
+		t.Fatalf("Incorrect number of bytes written: %d instead of %d", written, backup.Len())
+	}
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := os.WriteFile(backupPath, backup.Bytes(), 0o600); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+	restored := open(t, backupPath)
+	zone, err := restored.GetZone(ctx, "example.com")
+	if err != nil || zone.Serial != 1 || !slices.Equal(zone.ACMEChallengeAnswers, []string{"answer"}) {
+		t.Fatalf("Incorrect zone in the backup: %v (%v)", zone, err)
+	}
+}
+
+func TestProviderCompact(t *testing.T) {
+	provider := open(t, filepath.Join(t.TempDir(), "dns4acme.db"))
+	ctx := t.Context()
+	for i := range 1000 {
+		if err := provider.CreateZone(ctx, fmt.Sprintf("zone%d.example", i)); err != nil {
+			t.Fatalf("Failed to create zone: %v", err)
+		}
+	}
+	for i := range 999 {
+		if err := provider.DeleteZone(ctx, fmt.Sprintf("zone%d.example", i)); err != nil {
+			t.Fatalf("Failed to delete zone: %v", err)
+		}
+	}
+
+	result, err := provider.(backend.MaintenanceProvider).Compact(ctx)
+	if err != nil {
+		t.Fatalf("Failed to compact database: %v", err)
+	}
+	if result.SizeAfter >= result.SizeBefore {
+		t.Fatalf("The database did not shrink: %d bytes before, %d bytes after", result.SizeBefore, result.SizeAfter)
+	}
+
+	// The provider keeps working on the compacted file.
+	if _, err := provider.GetZone(ctx, "zone999.example"); err != nil {
+		t.Fatalf("Failed to get zone after compaction: %v", err)
+	}
+	if err := provider.SetZone(ctx, "zone999.example", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone after compaction: %v", err)
+	}
+}
+
+func TestProviderCompactFailed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns4acme.db")
+	provider := open(t, path)
+	ctx := t.Context()
+	if err := provider.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	// A directory in the way of the original file makes the compaction fail after the live database is closed.
+	if err := os.MkdirAll(filepath.Join(path+".original", "blocker"), 0o700); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	if _, err := provider.(backend.MaintenanceProvider).Compact(ctx); !E.Is(err, bbolt.ErrCompactFailed) {
+		t.Fatalf("Expected a compaction error, got %v", err)
+	}
+	// The provider keeps working on the original file.
+	if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to get zone after the failed compaction: %v", err)
+	}
+	if err := provider.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone after the failed compaction: %v", err)
+	}
+	if _, err := os.Stat(path + ".compact"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("The compacted file has not been removed: %v", err)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		return open(t, filepath.Join(t.TempDir(), "dns4acme.db"))
+	})
+	// The file lock allows only one provider at a time, so there are no replicas to test.
+	backendtest.RunPersistent(t, func(t *testing.T) backendtest.ExtendedProviderFactory {
+		path := filepath.Join(t.TempDir(), "dns4acme.db")
+		return func(t *testing.T) backend.ExtendedProvider {
+			return open(t, path)
+		}
+	})
+}
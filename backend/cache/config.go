@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"errors"
+	"github.com/dns4acme/dns4acme/backend"
+	"time"
+)
+
+// Config configures the read-through cache the DNS server and the HTTP API use for backend lookups.
+type Config struct {
+	TTL           time.Duration `config:"ttl" default:"0s" description:"Time to cache the zones and update keys read from the backend. Changes made through this server are visible immediately, changes made by other replicas once the entry expires, or immediately with backends reporting changes (inmemory, kubernetes). The cache is disabled if 0."`
+	NegativeTTL   time.Duration `config:"negative-ttl" default:"5s" description:"Time to remember that a zone does not exist in the backend. Negative caching is disabled if 0."`
+	MaxEntries    int           `config:"max-entries" default:"10000" description:"Maximum number of zones and update keys to cache each. Lookups exceeding it go to the backend."`
+	StatsInterval time.Duration `config:"stats-interval" default:"5m" description:"Interval of logging the cache hit rate at debug level. Logging is disabled if 0."`
+}
+
+// Enabled returns true if backend lookups should be cached.
+func (c Config) Enabled() bool {
+	return c.TTL > 0
+}
+
+func (c Config) Validate() error {
+	if c.TTL < 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the cache TTL must not be negative"))
+	}
+	if c.NegativeTTL < 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the negative cache TTL must not be negative"))
+	}
+	if c.Enabled() && c.MaxEntries <= 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the maximum number of cache entries must be positive"))
+	}
+	if c.StatsInterval < 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the cache statistics interval must not be negative"))
+	}
+	return nil
+}
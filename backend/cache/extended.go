@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"io"
+	"log/slog"
+)
+
+// ExtendedProvider is a backend.ExtendedProvider caching the results of GetZone and GetKey. It implements
+// backend.MaintenanceProvider if the wrapped provider does, passing backups and compactions on.
+type ExtendedProvider interface {
+	backend.ExtendedProvider
+	// Stats returns the cache statistics since the provider was created.
+	Stats() Stats
+}
+
+// NewExtended wraps a provider supporting the management functions in a cache, see New.
+func NewExtended(provider backend.ExtendedProvider, config Config, logger *slog.Logger) ExtendedProvider {
+	p := &cachingExtendedProvider{
+		cachingProvider: newProvider(provider, config, logger),
+		provider:        provider,
+	}
+	if maintenanceProvider, ok := provider.(backend.MaintenanceProvider); ok {
+		return &maintainingExtendedProvider{cachingExtendedProvider: p, maintenanceProvider: maintenanceProvider}
+	}
+	return p
+}
+
+type cachingExtendedProvider struct {
+	*cachingProvider
+	provider backend.ExtendedProvider
+}
+
+func (p *cachingExtendedProvider) CreateKey(ctx context.Context, keyName string, secret string) error {
+	defer p.invalidateKey(keyName)
+	return p.provider.CreateKey(ctx, keyName, secret)
+}
+
+func (p *cachingExtendedProvider) DeleteKey(ctx context.Context, keyName string) error {
+	defer p.invalidateKey(keyName)
+	return p.provider.DeleteKey(ctx, keyName)
+}
+
+func (p *cachingExtendedProvider) SetKeySecret(ctx context.Context, keyName string, secret string) error {
+	defer p.invalidateKey(keyName)
+	return p.provider.SetKeySecret(ctx, keyName, secret)
+}
+
+func (p *cachingExtendedProvider) BindKey(ctx context.Context, keyName string, zoneName string) error {
+	defer p.invalidateKey(keyName)
+	return p.provider.BindKey(ctx, keyName, zoneName)
+}
+
+func (p *cachingExtendedProvider) UnbindKey(ctx context.Context, keyName string, zoneName string) error {
+	defer p.invalidateKey(keyName)
+	return p.provider.UnbindKey(ctx, keyName, zoneName)
+}
+
+func (p *cachingExtendedProvider) SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error {
+	defer p.invalidateKey(keyName)
+	return p.provider.SetKeyAllowFrom(ctx, keyName, allowFrom)
+}
+
+func (p *cachingExtendedProvider) CreateZone(ctx context.Context, zoneName string) error {
+	defer p.invalidateZone(zoneName)
+	return p.provider.CreateZone(ctx, zoneName)
+}
+
+func (p *cachingExtendedProvider) DeleteZone(ctx context.Context, zoneName string) error {
+	// Deleting a zone removes it from the zones of all update keys bound to it.
+	defer p.invalidateAll()
+	return p.provider.DeleteZone(ctx, zoneName)
+}
+
+func (p *cachingExtendedProvider) ListZones(ctx context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	return p.provider.ListZones(ctx, options)
+}
+
+func (p *cachingExtendedProvider) ListKeys(ctx context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	return p.provider.ListKeys(ctx, options)
+}
+
+type maintainingExtendedProvider struct {
+	*cachingExtendedProvider
+	maintenanceProvider backend.MaintenanceProvider
+}
+
+func (p *maintainingExtendedProvider) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	return p.maintenanceProvider.Backup(ctx, w)
+}
+
+func (p *maintainingExtendedProvider) Compact(ctx context.Context) (backend.CompactResponse, error) {
+	return p.maintenanceProvider.Compact(ctx)
+}
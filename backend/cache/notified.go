@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"sync"
+)
+
+// Notified caches the zones and update keys a backend reads from storage shared with other replicas, for backends that
+// are notified of every change, such as the PostgreSQL and Redis backends. Entries don't expire, the backend
+// invalidates them when it is notified of a change. The cache is only used while the backend receives the
+// notifications, see SetValid, otherwise changes made by other replicas could be missed.
+type Notified struct {
+	lock  sync.Mutex
+	valid bool
+	// generation is incremented on every invalidation. Entries read from the storage are only stored if no
+	// invalidation happened since the read started, so a slow read cannot overwrite a newer change.
+	generation uint64
+	zones      map[string]backend.ProviderZoneResponse
+	keys       map[string]backend.ProviderKeyResponse
+}
+
+// NewNotified creates an empty Notified cache. It is not used until SetValid turns it on.
+func NewNotified() *Notified {
+	return &Notified{
+		zones: map[string]backend.ProviderZoneResponse{},
+		keys:  map[string]backend.ProviderKeyResponse{},
+	}
+}
+
+// GetZone returns the cached zone, or reads it with read and caches it. Lookups with a context passed to
+// backend.BypassCache always read the zone.
+func (c *Notified) GetZone(
+	ctx context.Context,
+	zoneName string,
+	read func(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error),
+) (backend.ProviderZoneResponse, error) {
+	return getNotified(ctx, c, c.zones, zoneName, read, cloneZone)
+}
+
+// GetKey returns the cached update key, or reads it with read and caches it. Lookups with a context passed to
+// backend.BypassCache always read the key.
+func (c *Notified) GetKey(
+	ctx context.Context,
+	keyName string,
+	read func(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error),
+) (backend.ProviderKeyResponse, error) {
+	return getNotified(ctx, c, c.keys, keyName, read, cloneKey)
+}
+
+// InvalidateZone removes a zone from the cache.
+func (c *Notified) InvalidateZone(zoneName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	delete(c.zones, zoneName)
+}
+
+// InvalidateKey removes an update key from the cache.
+func (c *Notified) InvalidateKey(keyName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	delete(c.keys, keyName)
+}
+
+// InvalidateKeys removes all update keys from the cache. Deleting a zone changes the zones of every key bound to it.
+func (c *Notified) InvalidateKeys() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	clear(c.keys)
+}
+
+// InvalidateAll empties the cache.
+func (c *Notified) InvalidateAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	clear(c.zones)
+	clear(c.keys)
+}
+
+// SetValid empties the cache and turns it on or off. Backends turn the cache on once they receive the notifications
+// and off when they lose the connection delivering them.
+func (c *Notified) SetValid(valid bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	c.valid = valid
+	clear(c.zones)
+	clear(c.keys)
+}
+
+func getNotified[T any](
+	ctx context.Context,
+	c *Notified,
+	entries map[string]T,
+	name string,
+	read func(ctx context.Context, name string) (T, error),
+	clone func(T) T,
+) (T, error) {
+	if backend.IsCacheBypassed(ctx) {
+		return read(ctx, name)
+	}
+	c.lock.Lock()
+	value, ok := entries[name]
+	valid := c.valid
+	generation := c.generation
+	c.lock.Unlock()
+	if ok && valid {
+		return clone(value), nil
+	}
+
+	value, err := read(ctx, name)
+	if err != nil {
+		return value, err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.valid && c.generation == generation {
+		entries[name] = clone(value)
+	}
+	return value, nil
+}
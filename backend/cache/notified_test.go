@@ -0,0 +1,158 @@
+package cache_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// storage stands in for the storage of a backend using the Notified cache and counts the reads.
+type storage struct {
+	zones map[string]backend.ProviderZoneResponse
+	keys  map[string]backend.ProviderKeyResponse
+	reads int
+	// beforeRead, if set, is called on every read before the value is read.
+	beforeRead func()
+}
+
+func (s *storage) readZone(_ context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	s.reads++
+	if s.beforeRead != nil {
+		s.beforeRead()
+	}
+	zone, ok := s.zones[zoneName]
+	if !ok {
+		return backend.ProviderZoneResponse{}, backend.ErrZoneNotInBackend
+	}
+	return zone, nil
+}
+
+func (s *storage) readKey(_ context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	s.reads++
+	if s.beforeRead != nil {
+		s.beforeRead()
+	}
+	key, ok := s.keys[keyName]
+	if !ok {
+		return backend.ProviderKeyResponse{}, backend.ErrKeyNotFoundInBackend
+	}
+	return key, nil
+}
+
+func (s *storage) getZone(t *testing.T, c *cache.Notified, ctx context.Context, zoneName string) backend.ProviderZoneResponse {
+	t.Helper()
+	zone, err := c.GetZone(ctx, zoneName, s.readZone)
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	return zone
+}
+
+func (s *storage) getKey(t *testing.T, c *cache.Notified, keyName string) backend.ProviderKeyResponse {
+	t.Helper()
+	key, err := c.GetKey(t.Context(), keyName, s.readKey)
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	return key
+}
+
+func newStorage() *storage {
+	return &storage{
+		zones: map[string]backend.ProviderZoneResponse{
+			"example.com": {Serial: 1, ACMEChallengeAnswers: []string{"answer"}},
+		},
+		keys: map[string]backend.ProviderKeyResponse{
+			"certbot": {Secret: "secret", Zones: []string{"example.com"}},
+			"lego":    {Secret: "secret"},
+		},
+	}
+}
+
+func TestNotified(t *testing.T) {
+	s := newStorage()
+	c := cache.NewNotified()
+	ctx := t.Context()
+
+	s.getZone(t, c, ctx, "example.com")
+	s.getZone(t, c, ctx, "example.com")
+	if s.reads != 2 {
+		t.Fatalf("The cache must not be used before it is turned on (%d reads).", s.reads)
+	}
+
+	c.SetValid(true)
+	s.reads = 0
+	zone := s.getZone(t, c, ctx, "example.com")
+	zone.ACMEChallengeAnswers[0] = "changed"
+	zone = s.getZone(t, c, ctx, "example.com")
+	if s.reads != 1 || !slices.Equal(zone.ACMEChallengeAnswers, []string{"answer"}) {
+		t.Fatalf("Incorrect cached zone after %d reads: %v", s.reads, zone)
+	}
+	s.getZone(t, c, backend.BypassCache(ctx), "example.com")
+	if s.reads != 2 {
+		t.Fatalf("Bypassing the cache did not read the zone.")
+	}
+	if _, err := c.GetZone(ctx, "example.org", s.readZone); !E.Is(err, backend.ErrZoneNotInBackend) {
+		t.Fatalf("Incorrect error for a missing zone: %v", err)
+	}
+
+	s.zones["example.com"] = backend.ProviderZoneResponse{Serial: 2}
+	c.InvalidateZone("example.com")
+	if zone := s.getZone(t, c, ctx, "example.com"); zone.Serial != 2 {
+		t.Fatalf("Invalidating the zone did not remove it from the cache: %v", zone)
+	}
+
+	s.getKey(t, c, "certbot")
+	s.getKey(t, c, "lego")
+	s.reads = 0
+	c.InvalidateKey("certbot")
+	s.getKey(t, c, "certbot")
+	s.getKey(t, c, "lego")
+	if s.reads != 1 {
+		t.Fatalf("Invalidating a key must only remove that key from the cache (%d reads).", s.reads)
+	}
+	s.reads = 0
+	c.InvalidateKeys()
+	s.getKey(t, c, "certbot")
+	s.getKey(t, c, "lego")
+	s.getZone(t, c, ctx, "example.com")
+	if s.reads != 2 {
+		t.Fatalf("Invalidating the keys must remove all keys and only the keys from the cache (%d reads).", s.reads)
+	}
+	s.reads = 0
+	c.InvalidateAll()
+	s.getKey(t, c, "certbot")
+	s.getZone(t, c, ctx, "example.com")
+	if s.reads != 2 {
+		t.Fatalf("Invalidating everything did not empty the cache (%d reads).", s.reads)
+	}
+
+	c.SetValid(false)
+	s.reads = 0
+	s.getZone(t, c, ctx, "example.com")
+	if s.reads != 1 {
+		t.Fatalf("The cache must be emptied when it is turned off.")
+	}
+}
+
+func TestNotified_StaleRead(t *testing.T) {
+	s := newStorage()
+	c := cache.NewNotified()
+	c.SetValid(true)
+
+	// The key changes while it is read, so the value read must not be cached.
+	s.beforeRead = func() {
+		s.beforeRead = nil
+		c.InvalidateKey("certbot")
+	}
+	s.getKey(t, c, "certbot")
+	s.reads = 0
+	s.getKey(t, c, "certbot")
+	if s.reads != 1 {
+		t.Fatalf("A key read before an invalidation was stored in the cache.")
+	}
+}
@@ -0,0 +1,277 @@
+// Package cache contains a read-through cache for backend lookups. Every signed DNS UPDATE looks up its update key and
+// zone several times, which the cache answers from memory instead of querying the backend each time. Backends notified
+// of the changes other replicas make to their storage cache their lookups with Notified instead.
+package cache
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// watchRetryInterval is the time to wait before watching the backend again after the watch failed or ended.
+const watchRetryInterval = 5 * time.Second
+
+// Provider is a backend.Provider caching the results of GetZone and GetKey.
+type Provider interface {
+	backend.Provider
+	// Stats returns the cache statistics since the provider was created.
+	Stats() Stats
+}
+
+// Stats contains the cache statistics.
+type Stats struct {
+	// Hits is the number of lookups answered from the cache, including NegativeHits.
+	Hits uint64
+	// NegativeHits is the number of lookups for missing zones answered from the cache.
+	NegativeHits uint64
+	// Misses is the number of lookups passed to the backend.
+	Misses uint64
+	// Invalidations is the number of entries removed from the cache because of changes.
+	Invalidations uint64
+}
+
+// HitRate returns the share of lookups answered from the cache between 0 and 1.
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// New wraps a provider in a cache. Zones and update keys are cached for the configured TTL, changes made through the
+// returned provider invalidate the affected entries immediately. Lookups with a context from backend.BypassCache are
+// always passed to the wrapped provider. If the wrapped provider implements
+// backend.WatchProvider, changes made elsewhere invalidate the affected entries as well. Closing the returned provider
+// closes the wrapped provider.
+func New(provider backend.Provider, config Config, logger *slog.Logger) Provider {
+	return newProvider(provider, config, logger)
+}
+
+func newProvider(provider backend.Provider, config Config, logger *slog.Logger) *cachingProvider {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &cachingProvider{
+		provider: provider,
+		config:   config,
+		logger:   logger,
+		zones:    newTable(config.MaxEntries, cloneZone),
+		keys:     newTable(config.MaxEntries, cloneKey),
+		cancel:   cancel,
+		done:     &sync.WaitGroup{},
+	}
+	if watchProvider, ok := provider.(backend.WatchProvider); ok {
+		p.done.Add(1)
+		go p.watch(ctx, watchProvider)
+	}
+	p.done.Add(1)
+	go p.maintain(ctx)
+	return p
+}
+
+type cachingProvider struct {
+	provider backend.Provider
+	config   Config
+	logger   *slog.Logger
+	zones    *table[backend.ProviderZoneResponse]
+	keys     *table[backend.ProviderKeyResponse]
+	// group merges concurrent lookups of the same object that are not cached into one backend call.
+	group singleflight.Group
+	// cancel stops the goroutines started by New, done waits for them to exit.
+	cancel context.CancelFunc
+	done   *sync.WaitGroup
+
+	hits          atomic.Uint64
+	negativeHits  atomic.Uint64
+	misses        atomic.Uint64
+	invalidations atomic.Uint64
+}
+
+func (p *cachingProvider) GetZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	return lookup(ctx, p, p.zones, "zone/"+zoneName, zoneName, p.provider.GetZone, func(err error) time.Duration {
+		if E.Is(err, backend.ErrZoneNotInBackend) {
+			return p.config.NegativeTTL
+		}
+		return 0
+	})
+}
+
+func (p *cachingProvider) GetKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	return lookup(ctx, p, p.keys, "key/"+keyName, keyName, p.provider.GetKey, func(error) time.Duration {
+		return 0
+	})
+}
+
+// lookup answers a lookup from the table if possible, otherwise it calls get and caches the result. errorTTL returns
+// the time to cache an error for, errors are not cached if it returns 0.
+func lookup[T any](
+	ctx context.Context,
+	p *cachingProvider,
+	t *table[T],
+	flightKey string,
+	name string,
+	get func(ctx context.Context, name string) (T, error),
+	errorTTL func(err error) time.Duration,
+) (T, error) {
+	if backend.IsCacheBypassed(ctx) {
+		// The result is about to be changed and written back, the entry is invalidated by the write.
+		return get(ctx, name)
+	}
+	cached, ok, generation := t.get(name, time.Now())
+	if ok {
+		p.hits.Add(1)
+		if cached.err != nil {
+			p.negativeHits.Add(1)
+		}
+		return cached.value, cached.err
+	}
+	p.misses.Add(1)
+	// Lookups starting after an invalidation must not share a call started before it, which may return the old data.
+	result := p.group.DoChan(flightKey+"@"+strconv.FormatUint(generation, 10), func() (any, error) {
+		// The call is shared with other callers, so it must not be canceled when the first caller gives up.
+		value, err := get(context.WithoutCancel(ctx), name)
+		ttl := p.config.TTL
+		if err != nil {
+			ttl = errorTTL(err)
+		}
+		if ttl > 0 {
+			t.store(name, value, err, time.Now().Add(ttl), generation)
+		}
+		return value, err
+	})
+	select {
+	case r := <-result:
+		value, _ := r.Val.(T)
+		return t.clone(value), r.Err
+	case <-ctx.Done():
+		var empty T
+		return empty, backend.ErrBackendRequestFailed.Wrap(ctx.Err())
+	}
+}
+
+func (p *cachingProvider) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	defer p.invalidateZone(zoneName)
+	return p.provider.SetZone(ctx, zoneName, acmeChallengeAnswers)
+}
+
+func (p *cachingProvider) SetZoneDebug(ctx context.Context, zoneName string, debug bool) error {
+	defer p.invalidateZone(zoneName)
+	return p.provider.SetZoneDebug(ctx, zoneName, debug)
+}
+
+func (p *cachingProvider) Close(ctx context.Context) error {
+	p.cancel()
+	p.done.Wait()
+	return p.provider.Close(ctx)
+}
+
+func (p *cachingProvider) Stats() Stats {
+	return Stats{
+		Hits:          p.hits.Load(),
+		NegativeHits:  p.negativeHits.Load(),
+		Misses:        p.misses.Load(),
+		Invalidations: p.invalidations.Load(),
+	}
+}
+
+func (p *cachingProvider) invalidateZone(zoneName string) {
+	p.invalidations.Add(1)
+	p.zones.invalidate(zoneName)
+}
+
+func (p *cachingProvider) invalidateKey(keyName string) {
+	p.invalidations.Add(1)
+	p.keys.invalidate(keyName)
+}
+
+func (p *cachingProvider) invalidateAll() {
+	p.invalidations.Add(1)
+	p.zones.invalidateAll()
+	p.keys.invalidateAll()
+}
+
+// watch invalidates the entries changed in the backend until ctx is canceled. While the watch is not running, changes
+// made elsewhere only become visible when the entries expire.
+func (p *cachingProvider) watch(ctx context.Context, watchProvider backend.WatchProvider) {
+	defer p.done.Done()
+	for {
+		events, err := watchProvider.Watch(ctx)
+		if err != nil {
+			p.logger.WarnContext(ctx, "Failed to watch the backend for changes, cached entries are only refreshed when they expire.", E.ToSLogAttr(err)...)
+		} else {
+			// Changes made before the watch started may have been missed.
+			p.invalidateAll()
+			for event := range events {
+				switch event.Kind {
+				case backend.ChangeKindZone:
+					p.invalidateZone(event.Name)
+				case backend.ChangeKindKey:
+					p.invalidateKey(event.Name)
+				default:
+					p.invalidateAll()
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			p.logger.WarnContext(ctx, "The backend stopped reporting changes, cached entries are only refreshed when they expire.")
+		}
+		select {
+		case <-time.After(watchRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// maintain removes expired entries and logs the statistics until ctx is canceled.
+func (p *cachingProvider) maintain(ctx context.Context) {
+	defer p.done.Done()
+	expireTicker := time.NewTicker(max(p.config.TTL, p.config.NegativeTTL, time.Second))
+	defer expireTicker.Stop()
+	var statsTicks <-chan time.Time
+	if p.config.StatsInterval > 0 {
+		statsTicker := time.NewTicker(p.config.StatsInterval)
+		defer statsTicker.Stop()
+		statsTicks = statsTicker.C
+	}
+	for {
+		select {
+		case now := <-expireTicker.C:
+			p.zones.expire(now)
+			p.keys.expire(now)
+		case <-statsTicks:
+			stats := p.Stats()
+			p.logger.DebugContext(
+				ctx,
+				"Backend cache statistics.",
+				slog.Uint64("hits", stats.Hits),
+				slog.Uint64("negative_hits", stats.NegativeHits),
+				slog.Uint64("misses", stats.Misses),
+				slog.Uint64("invalidations", stats.Invalidations),
+				slog.Float64("hit_rate", stats.HitRate()),
+				slog.Int("zones", p.zones.expire(time.Now())),
+				slog.Int("keys", p.keys.expire(time.Now())),
+			)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func cloneZone(zone backend.ProviderZoneResponse) backend.ProviderZoneResponse {
+	zone.ACMEChallengeAnswers = slices.Clone(zone.ACMEChallengeAnswers)
+	return zone
+}
+
+func cloneKey(key backend.ProviderKeyResponse) backend.ProviderKeyResponse {
+	key.Zones = slices.Clone(key.Zones)
+	key.AllowFrom = slices.Clone(key.AllowFrom)
+	return key
+}
@@ -0,0 +1,332 @@
+package cache_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+var testConfig = cache.Config{
+	TTL:         time.Hour,
+	NegativeTTL: time.Hour,
+	MaxEntries:  100,
+}
+
+func newCache(t *testing.T, provider backend.ExtendedProvider, config cache.Config) cache.ExtendedProvider {
+	t.Helper()
+	cached := cache.NewExtended(provider, config, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.Cleanup(func() {
+		if err := cached.Close(context.Background()); err != nil {
+			t.Errorf("Failed to close provider: %v", err)
+		}
+	})
+	return cached
+}
+
+// countingProvider counts the lookups reaching the backend. It does not implement backend.WatchProvider, so the cache
+// only sees the changes made through it.
+type countingProvider struct {
+	backend.ExtendedProvider
+	zoneLookups atomic.Int64
+	keyLookups  atomic.Int64
+	// block, if set, delays zone lookups until it is closed.
+	block chan struct{}
+}
+
+func (p *countingProvider) GetZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	p.zoneLookups.Add(1)
+	if p.block != nil {
+		<-p.block
+	}
+	return p.ExtendedProvider.GetZone(ctx, zoneName)
+}
+
+func (p *countingProvider) GetKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	p.keyLookups.Add(1)
+	return p.ExtendedProvider.GetKey(ctx, keyName)
+}
+
+func newCountingProvider(t *testing.T, zones ...string) *countingProvider {
+	t.Helper()
+	inner := inmemory.New(map[string]*backend.ProviderZoneResponse{}, map[string]*backend.ProviderKeyResponse{})
+	for _, zone := range zones {
+		if err := inner.CreateZone(t.Context(), zone); err != nil {
+			t.Fatalf("Failed to create zone: %v", err)
+		}
+	}
+	return &countingProvider{ExtendedProvider: inner}
+}
+
+func TestConformance(t *testing.T) {
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		return newCache(t, newCountingProvider(t), testConfig)
+	})
+}
+
+func TestCacheHits(t *testing.T) {
+	inner := newCountingProvider(t, "example.com")
+	ctx := t.Context()
+	if err := inner.CreateKey(ctx, "certbot", "secret"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	provider := newCache(t, inner, testConfig)
+
+	for range 3 {
+		if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		if _, err := provider.GetKey(ctx, "certbot"); err != nil {
+			t.Fatalf("Failed to get key: %v", err)
+		}
+	}
+	if zoneLookups, keyLookups := inner.zoneLookups.Load(), inner.keyLookups.Load(); zoneLookups != 1 || keyLookups != 1 {
+		t.Fatalf("Expected one zone and one key lookup in the backend, got %d and %d", zoneLookups, keyLookups)
+	}
+
+	// Changes made through the cache invalidate the cached entries.
+	if err := provider.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	if err := provider.SetKeySecret(ctx, "certbot", "new-secret"); err != nil {
+		t.Fatalf("Failed to set key secret: %v", err)
+	}
+	zone, err := provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != 1 {
+		t.Fatalf("The cache returned a stale zone: %v", zone)
+	}
+	key, err := provider.GetKey(ctx, "certbot")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if key.Secret != "new-secret" {
+		t.Fatalf("The cache returned a stale key secret: %s", key.Secret)
+	}
+
+	stats := provider.Stats()
+	if stats.Hits != 4 || stats.Misses != 4 || stats.NegativeHits != 0 || stats.Invalidations != 2 {
+		t.Fatalf("Incorrect statistics: %+v", stats)
+	}
+	if stats.HitRate() != 0.5 {
+		t.Fatalf("Expected a hit rate of 0.5, got %f", stats.HitRate())
+	}
+}
+
+func TestTTL(t *testing.T) {
+	inner := newCountingProvider(t, "example.com")
+	ctx := t.Context()
+	config := testConfig
+	config.TTL = 50 * time.Millisecond
+	provider := newCache(t, inner, config)
+
+	if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	// Changes made directly in the backend are not visible until the entry expires.
+	if err := inner.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	zone, err := provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != 0 {
+		t.Fatalf("Expected the cached zone, got %v", zone)
+	}
+	time.Sleep(config.TTL)
+	zone, err = provider.GetZone(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != 1 {
+		t.Fatalf("The zone has not expired from the cache: %v", zone)
+	}
+}
+
+func TestNegativeCache(t *testing.T) {
+	inner := newCountingProvider(t)
+	ctx := t.Context()
+	config := testConfig
+	config.NegativeTTL = 50 * time.Millisecond
+	provider := newCache(t, inner, config)
+
+	for range 3 {
+		if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrZoneNotInBackend) {
+			t.Fatalf("Expected %v, got %v", backend.ErrZoneNotInBackend, err)
+		}
+	}
+	if lookups := inner.zoneLookups.Load(); lookups != 1 {
+		t.Fatalf("Expected one zone lookup in the backend, got %d", lookups)
+	}
+	if stats := provider.Stats(); stats.NegativeHits != 2 {
+		t.Fatalf("Expected two negative hits, got %+v", stats)
+	}
+
+	if err := inner.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	time.Sleep(config.NegativeTTL)
+	if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("The missing zone has not expired from the cache: %v", err)
+	}
+
+	// Missing update keys are not cached.
+	for range 2 {
+		if _, err := provider.GetKey(ctx, "certbot"); !E.Is(err, backend.ErrKeyNotFoundInBackend) {
+			t.Fatalf("Expected %v, got %v", backend.ErrKeyNotFoundInBackend, err)
+		}
+	}
+	if lookups := inner.keyLookups.Load(); lookups != 2 {
+		t.Fatalf("Expected two key lookups in the backend, got %d", lookups)
+	}
+}
+
+func TestConcurrentMisses(t *testing.T) {
+	inner := newCountingProvider(t, "example.com")
+	inner.block = make(chan struct{})
+	provider := newCache(t, inner, testConfig)
+
+	const concurrency = 20
+	wg := &sync.WaitGroup{}
+	errs := make(chan error, concurrency)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := provider.GetZone(t.Context(), "example.com")
+			errs <- err
+		}()
+	}
+	// Wait for all lookups to miss the cache before letting the backend answer.
+	for provider.Stats().Misses != concurrency {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(inner.block)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+	}
+	if lookups := inner.zoneLookups.Load(); lookups != 1 {
+		t.Fatalf("Expected concurrent lookups to share one backend lookup, got %d", lookups)
+	}
+}
+
+func TestCanceledLookup(t *testing.T) {
+	inner := newCountingProvider(t, "example.com")
+	inner.block = make(chan struct{})
+	provider := newCache(t, inner, testConfig)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendRequestFailed) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+	}
+
+	// The backend lookup continues for the other callers and its result is cached.
+	close(inner.block)
+	if _, err := provider.GetZone(t.Context(), "example.com"); err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if lookups := inner.zoneLookups.Load(); lookups != 1 {
+		t.Fatalf("Expected one zone lookup in the backend, got %d", lookups)
+	}
+}
+
+func TestWatchInvalidation(t *testing.T) {
+	ctx := t.Context()
+	inner := inmemory.New(map[string]*backend.ProviderZoneResponse{}, map[string]*backend.ProviderKeyResponse{})
+	if err := inner.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	provider := newCache(t, inner, testConfig)
+
+	// Changes made directly in a backend reporting changes invalidate the cached entries.
+	if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if err := inner.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		zone, err := provider.GetZone(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		if zone.Serial == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("The change has not invalidated the cached zone: %v", zone)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// maintenanceProvider adds backups and compaction to a provider.
+type maintenanceProvider struct {
+	backend.ExtendedProvider
+}
+
+func (p maintenanceProvider) Backup(_ context.Context, w io.Writer) (int64, error) {
+	n, err := w.Write([]byte("snapshot"))
+	return int64(n), err
+}
+
+func (p maintenanceProvider) Compact(_ context.Context) (backend.CompactResponse, error) {
+	return backend.CompactResponse{SizeBefore: 2, SizeAfter: 1}, nil
+}
+
+func TestMaintenance(t *testing.T) {
+	provider := newCache(t, newCountingProvider(t), testConfig)
+	if _, ok := provider.(backend.MaintenanceProvider); ok {
+		t.Fatalf("The cache supports maintenance although the wrapped provider does not")
+	}
+	maintaining, ok := newCache(t, maintenanceProvider{newCountingProvider(t)}, testConfig).(backend.MaintenanceProvider)
+	if !ok {
+		t.Fatalf("The cache does not support maintenance although the wrapped provider does")
+	}
+	if result, err := maintaining.Compact(t.Context()); err != nil || result.SizeAfter != 1 {
+		t.Fatalf("Failed to compact: %v (%v)", err, result)
+	}
+}
+
+func TestBypassCache(t *testing.T) {
+	inner := newCountingProvider(t, "example.com")
+	provider := newCache(t, inner, testConfig)
+	ctx := t.Context()
+	if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+
+	// Another replica changes the zone without going through the cache.
+	if err := inner.ExtendedProvider.SetZone(ctx, "example.com", []string{"other replica"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	zone, err := provider.GetZone(backend.BypassCache(ctx), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if len(zone.ACMEChallengeAnswers) != 1 || zone.ACMEChallengeAnswers[0] != "other replica" {
+		t.Fatalf("The lookup bypassing the cache returned a stale zone: %v", zone)
+	}
+	if zoneLookups := inner.zoneLookups.Load(); zoneLookups != 2 {
+		t.Fatalf("Expected two zone lookups in the backend, got %d", zoneLookups)
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// table holds the cached results of one kind of lookup. Each entry is either a value or the error the backend
+// returned, with the time it expires at.
+type table[T any] struct {
+	lock sync.Mutex
+	// generation is incremented on every invalidation. Results read from the backend are only stored if no
+	// invalidation happened since the read started, so a slow read cannot overwrite a newer change.
+	generation uint64
+	entries    map[string]entry[T]
+	maxEntries int
+	clone      func(T) T
+}
+
+type entry[T any] struct {
+	value   T
+	err     error
+	expires time.Time
+}
+
+func newTable[T any](maxEntries int, clone func(T) T) *table[T] {
+	return &table[T]{
+		entries:    map[string]entry[T]{},
+		maxEntries: maxEntries,
+		clone:      clone,
+	}
+}
+
+// get returns the cached result and true if name is cached and has not expired yet. Otherwise, it returns the
+// generation to pass to store.
+func (t *table[T]) get(name string, now time.Time) (entry[T], bool, uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	e, ok := t.entries[name]
+	if !ok || !now.Before(e.expires) {
+		return entry[T]{}, false, t.generation
+	}
+	e.value = t.clone(e.value)
+	return e, true, t.generation
+}
+
+// store caches a result until expires, unless the table has been invalidated since generation or is full.
+func (t *table[T]) store(name string, value T, err error, expires time.Time, generation uint64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.generation != generation {
+		return
+	}
+	if _, ok := t.entries[name]; !ok && len(t.entries) >= t.maxEntries {
+		return
+	}
+	t.entries[name] = entry[T]{value: t.clone(value), err: err, expires: expires}
+}
+
+// invalidate removes name from the table.
+func (t *table[T]) invalidate(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.generation++
+	delete(t.entries, name)
+}
+
+// invalidateAll removes all entries from the table.
+func (t *table[T]) invalidateAll() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.generation++
+	clear(t.entries)
+}
+
+// expire removes the entries that have expired by now and returns the number of entries left.
+func (t *table[T]) expire(now time.Time) int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for name, e := range t.entries {
+		if !now.Before(e.expires) {
+			delete(t.entries, name)
+		}
+	}
+	return len(t.entries)
+}
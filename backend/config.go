@@ -1,6 +1,9 @@
 package backend
 
-import "context"
+import (
+	"context"
+	"log/slog"
+)
 
 type ExtendedConfig interface {
 	Config
@@ -10,3 +13,11 @@ type ExtendedConfig interface {
 type Config interface {
 	Build(ctx context.Context) (Provider, error)
 }
+
+// LoggingConfig is a Config for backends that log, for example when they lose the connection to their storage.
+type LoggingConfig interface {
+	Config
+	// WithLogger returns a copy of the configuration with the logger the backend logs to. The configuration itself is
+	// left untouched.
+	WithLogger(logger *slog.Logger) Config
+}
@@ -0,0 +1,20 @@
+package backend
+
+import (
+	"context"
+)
+
+type bypassCacheKey struct{}
+
+// BypassCache returns a context that makes caching layers pass lookups to the backend. Use it to read a zone or update
+// key that is changed and written back, so that the change is not based on an outdated copy and doesn't overwrite
+// changes made by other replicas.
+func BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+// IsCacheBypassed returns true if lookups made with ctx must not be answered from a cache.
+func IsCacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
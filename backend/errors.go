@@ -1,7 +1,9 @@
 package backend
 
 import (
+	"errors"
 	"github.com/dns4acme/dns4acme/lang/E"
+	"log/slog"
 )
 
 var ErrKeyNotFoundInBackend = E.New("KEY_NOT_IN_BACKEND", "key not found in backend")
@@ -13,3 +15,35 @@ var ErrZoneAlreadyExistsInBackend = E.New("ZONE_ALREADY_EXISTS", "zone already e
 
 var ErrObjectNotInBackend = E.New("OBJECT_NOT_IN_BACKEND", "object not found in backend")
 var ErrObjectBackendConflict = E.New("OBJECT_CONFLICT", "object conflict exists in backend")
+
+var ErrInvalidListOptions = E.New("INVALID_LIST_OPTIONS", "invalid list options")
+
+var ErrInvalidAllowFrom = E.New("INVALID_ALLOW_FROM", "invalid allowed network, must be a CIDR prefix such as 192.0.2.0/24")
+
+var ErrMaintenanceNotSupported = E.New("MAINTENANCE_NOT_SUPPORTED", "the backend does not support backups and compaction")
+
+var ErrBackendUnavailable = E.New("BACKEND_UNAVAILABLE", "the backend is unavailable, requests are rejected until it recovers")
+
+// RequestFailed wraps an error returned by the storage of a backend in ErrBackendRequestFailed. If kind is not empty,
+// the error names the zone or update key the request was for, for example RequestFailed(err, "zone", zoneName).
+func RequestFailed(err error, kind string, name string) error {
+	wrapped := ErrBackendRequestFailed.Wrap(err)
+	if kind != "" {
+		wrapped = wrapped.WithAttr(slog.String(kind, name))
+	}
+	return wrapped
+}
+
+// RequireRow checks the result of a database query selecting the zone or update key name, for SQL backends making
+// sure an object exists. Pass the Scan function of the row and the error the database driver returns if there is no
+// row. It returns notFound if there was no row.
+func RequireRow(scan func(dest ...any) error, noRows error, notFound error, kind string, name string) error {
+	var found int
+	if err := scan(&found); err != nil {
+		if errors.Is(err, noRows) {
+			return notFound
+		}
+		return RequestFailed(err, kind, name)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Endpoints []string `config:"endpoints" default:"localhost:2379" description:"Comma-separated list of etcd endpoints."`
+	Prefix    string   `config:"prefix" default:"/dns4acme/" description:"Prefix of the etcd keys zones and update keys are stored under."`
+
+	Username string `config:"username" file:"true" description:"Username for authenticating to etcd."`
+	Password string `config:"password" sensitive:"true" file:"true" description:"Password for authenticating to etcd."`
+
+	CertFile string `config:"cert-file" description:"File containing the PEM-encoded client certificate to use for authenticating to etcd."`
+	KeyFile  string `config:"key-file" description:"File containing the PEM-encoded client private key to use for authenticating to etcd."`
+	CAFile   string `config:"cacert-file" description:"File containing the PEM-encoded CA certificate to verify the connection to etcd. Setting any of the TLS files turns on TLS."`
+
+	DialTimeout time.Duration `config:"dial-timeout" default:"5s" description:"Maximum time to wait for the connection to etcd and the initial load of the zones and update keys."`
+
+	Logger *slog.Logger `json:"-"`
+}
+
+// WithLogger returns a copy of the configuration logging to logger.
+func (c Config) WithLogger(logger *slog.Logger) backend.Config {
+	c.Logger = logger
+	return &c
+}
+
+func (c Config) Build(ctx context.Context) (backend.Provider, error) {
+	return c.BuildExtended(ctx)
+}
+
+func (c Config) BuildExtended(ctx context.Context) (backend.ExtendedProvider, error) {
+	if len(c.Endpoints) == 0 {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("at least one etcd endpoint is required"))
+	}
+	if c.Prefix == "" {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the etcd key prefix must not be empty"))
+	}
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, backend.ErrConfiguration.Wrap(err)
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   c.Endpoints,
+		DialTimeout: c.DialTimeout,
+		Username:    c.Username,
+		Password:    c.Password,
+		TLS:         tlsConfig,
+		// The client logs connection problems on its own, which would bypass the DNS4ACME log settings.
+		Logger: zap.NewNop(),
+	})
+	if err != nil {
+		return nil, backend.ErrConfiguration.Wrap(err)
+	}
+
+	logger := c.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	logger = logger.WithGroup("etcd")
+
+	prefix := c.Prefix
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	// The initial load makes sure the DNS server is actually ready once the startup completes.
+	loadCtx := ctx
+	if c.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		loadCtx, cancel = context.WithTimeout(ctx, c.DialTimeout)
+		defer cancel()
+	}
+	m, err := startMirror(loadCtx, client, prefix, logger)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &provider{
+		client: client,
+		prefix: prefix,
+		mirror: m,
+	}, nil
+}
+
+func (c Config) tlsConfig() (*tls.Config, error) {
+	if c.CertFile == "" && c.KeyFile == "" && c.CAFile == "" {
+		return nil, nil //nolint:nilnil // No TLS configuration means a plain text connection.
+	}
+	info := transport.TLSInfo{
+		CertFile:      c.CertFile,
+		KeyFile:       c.KeyFile,
+		TrustedCAFile: c.CAFile,
+	}
+	return info.ClientConfig()
+}
@@ -0,0 +1,20 @@
+package etcd
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+type descriptor struct {
+}
+
+func (d descriptor) Name() string {
+	return "etcd"
+}
+
+func (d descriptor) Description() string {
+	return "Backend that stores zones and update keys in an etcd v3 cluster."
+}
+
+func (d descriptor) Config() backend.Config {
+	return &Config{}
+}
@@ -0,0 +1,3 @@
+package etcd
+
+const ID = "etcd"
@@ -0,0 +1,217 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mirrorRetryInterval is the time to wait before loading everything again after the watch failed.
+const mirrorRetryInterval = 5 * time.Second
+
+// mirror keeps a local copy of all zones and update keys under the prefix, like the Kubernetes backend does with its
+// informers. It loads everything once and then follows the changes with a watch, so reads never go to etcd.
+type mirror struct {
+	client *clientv3.Client
+	prefix string
+	logger *slog.Logger
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	lock sync.RWMutex
+	// revision is the etcd revision the mirror is up to date with.
+	revision int64
+	zones    map[string]zoneValue
+	keys     map[string]keyValue
+	// changed is closed and replaced every time the revision changes.
+	changed chan struct{}
+}
+
+func startMirror(ctx context.Context, client *clientv3.Client, prefix string, logger *slog.Logger) (*mirror, error) {
+	m := &mirror{
+		client:  client,
+		prefix:  prefix,
+		logger:  logger,
+		done:    make(chan struct{}),
+		changed: make(chan struct{}),
+	}
+	if err := m.load(ctx); err != nil {
+		return nil, err
+	}
+	// The watch runs until the provider is closed, so it must not use the context passed to Build.
+	watchCtx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	go m.run(watchCtx)
+	return m, nil
+}
+
+// load replaces the mirrored objects with everything currently stored under the prefix.
+func (m *mirror) load(ctx context.Context) error {
+	response, err := m.client.Get(ctx, m.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return backend.RequestFailed(err, "", "")
+	}
+	zones := map[string]zoneValue{}
+	keys := map[string]keyValue{}
+	for _, kv := range response.Kvs {
+		m.decode(kv, zones, keys)
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.zones = zones
+	m.keys = keys
+	m.setRevision(response.Header.Revision)
+	return nil
+}
+
+func (m *mirror) run(ctx context.Context) {
+	defer close(m.done)
+	for {
+		err := m.watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		m.logger.WarnContext(ctx, "etcd watch failed, reloading all zones and update keys", E.ToSLogAttr(err)...)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(mirrorRetryInterval):
+			}
+			err := m.load(ctx)
+			if err == nil {
+				break
+			}
+			m.logger.WarnContext(ctx, "Failed to reload zones and update keys from etcd", E.ToSLogAttr(err)...)
+		}
+	}
+}
+
+// watch applies the changes after the current revision until the watch fails. Events missed while the watch was down
+// are delivered when it resumes, unless they have been compacted, in which case everything is loaded again.
+func (m *mirror) watch(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	m.lock.RLock()
+	revision := m.revision
+	m.lock.RUnlock()
+	watchChan := m.client.Watch(
+		clientv3.WithRequireLeader(ctx),
+		m.prefix,
+		clientv3.WithPrefix(),
+		clientv3.WithRev(revision+1),
+	)
+	for response := range watchChan {
+		if err := response.Err(); err != nil {
+			return err
+		}
+		m.apply(response.Events)
+	}
+	return ctx.Err()
+}
+
+func (m *mirror) apply(events []*clientv3.Event) {
+	if len(events) == 0 {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, event := range events {
+		if event.Type == mvccpb.DELETE {
+			kind, name := m.parseKey(event.Kv.Key)
+			switch kind {
+			case zonesDir:
+				delete(m.zones, name)
+			case keysDir:
+				delete(m.keys, name)
+			}
+			continue
+		}
+		m.decode(event.Kv, m.zones, m.keys)
+	}
+	// The header revision may be ahead of the events while the watch catches up, so only the revision of the last
+	// event is known to be applied.
+	m.setRevision(events[len(events)-1].Kv.ModRevision)
+}
+
+// decode stores an etcd key-value pair in the matching map. Values that cannot be decoded are logged and skipped.
+func (m *mirror) decode(kv *mvccpb.KeyValue, zones map[string]zoneValue, keys map[string]keyValue) {
+	kind, name := m.parseKey(kv.Key)
+	var err error
+	switch kind {
+	case zonesDir:
+		var zone zoneValue
+		if err = json.Unmarshal(kv.Value, &zone); err == nil {
+			zones[name] = zone
+		}
+	case keysDir:
+		var key keyValue
+		if err = json.Unmarshal(kv.Value, &key); err == nil {
+			keys[name] = key
+		}
+	}
+	if err != nil {
+		m.logger.Warn("Ignoring invalid etcd value", E.ToSLogAttr(err, slog.String("key", string(kv.Key)))...)
+	}
+}
+
+// parseKey splits an etcd key into the kind of object and its name.
+func (m *mirror) parseKey(key []byte) (string, string) {
+	kind, name, _ := strings.Cut(strings.TrimPrefix(string(key), m.prefix), "/")
+	return kind, name
+}
+
+// setRevision must be called with the lock held.
+func (m *mirror) setRevision(revision int64) {
+	if revision <= m.revision {
+		return
+	}
+	m.revision = revision
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// wait blocks until the mirror has caught up with the specified revision, so reads reflect a write that has just
+// been made.
+func (m *mirror) wait(ctx context.Context, revision int64) error {
+	for {
+		m.lock.RLock()
+		current := m.revision
+		changed := m.changed
+		m.lock.RUnlock()
+		if current >= revision {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return backend.ErrBackendRequestFailed.Wrap(ctx.Err()).WithAttr(slog.Int64("revision", revision))
+		case <-changed:
+		}
+	}
+}
+
+func (m *mirror) zone(zoneName string) (zoneValue, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	zone, ok := m.zones[zoneName]
+	return zone, ok
+}
+
+func (m *mirror) key(keyName string) (keyValue, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	key, ok := m.keys[keyName]
+	return key, ok
+}
+
+func (m *mirror) stop() {
+	m.cancel()
+	<-m.done
+}
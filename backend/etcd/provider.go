@@ -0,0 +1,389 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Zones and update keys are stored as JSON values under <prefix>zones/<name> and <prefix>keys/<name>. The zones an
+// update key is bound to are part of the key value.
+const (
+	zonesDir = "zones"
+	keysDir  = "keys"
+)
+
+// maxAttempts limits the number of times a write is retried because another writer changed the same object first.
+// Every conflict means that another write succeeded, so this is only reached under heavy contention.
+const maxAttempts = 32
+
+type zoneValue struct {
+	Serial  uint32   `json:"serial"`
+	Answers []string `json:"answers,omitempty"`
+	Debug   bool     `json:"debug,omitempty"`
+}
+
+type keyValue struct {
+	Secret string `json:"secret"`
+	// Zones is kept sorted.
+	Zones     []string `json:"zones,omitempty"`
+	AllowFrom []string `json:"allowFrom,omitempty"`
+}
+
+type provider struct {
+	client *clientv3.Client
+	prefix string
+	mirror *mirror
+}
+
+func (p *provider) GetKey(_ context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	key, ok := p.mirror.key(keyName)
+	if !ok {
+		return backend.ProviderKeyResponse{}, backend.ErrKeyNotFoundInBackend
+	}
+	return backend.ProviderKeyResponse{
+		Secret:    key.Secret,
+		Zones:     slices.Clone(key.Zones),
+		AllowFrom: slices.Clone(key.AllowFrom),
+	}, nil
+}
+
+func (p *provider) GetZone(_ context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	zone, ok := p.mirror.zone(zoneName)
+	if !ok {
+		return backend.ProviderZoneResponse{}, backend.ErrZoneNotInBackend
+	}
+	return backend.ProviderZoneResponse{
+		Serial:               zone.Serial,
+		ACMEChallengeAnswers: slices.Clone(zone.Answers),
+		Debug:                zone.Debug,
+	}, nil
+}
+
+func (p *provider) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	return p.updateZone(ctx, zoneName, func(zone *zoneValue) {
+		// The serial wraps around like an RFC 1982 serial number.
+		zone.Serial++
+		zone.Answers = backend.NilIfEmpty(slices.Clone(acmeChallengeAnswers))
+	})
+}
+
+func (p *provider) SetZoneDebug(ctx context.Context, zoneName string, debug bool) error {
+	return p.updateZone(ctx, zoneName, func(zone *zoneValue) {
+		zone.Debug = debug
+	})
+}
+
+func (p *provider) CreateKey(ctx context.Context, keyName string, secret string) error {
+	return p.create(ctx, keysDir, "key", keyName, keyValue{Secret: secret})
+}
+
+func (p *provider) DeleteKey(ctx context.Context, keyName string) error {
+	path := p.path(keysDir, keyName)
+	response, err := p.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(path), "!=", 0)).
+		Then(clientv3.OpDelete(path)).
+		Commit()
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	if !response.Succeeded {
+		return backend.ErrObjectNotInBackend
+	}
+	return p.mirror.wait(ctx, response.Header.Revision)
+}
+
+func (p *provider) SetKeySecret(ctx context.Context, keyName string, secret string) error {
+	return p.updateKey(ctx, keyName, "", func(key *keyValue) bool {
+		key.Secret = secret
+		return true
+	})
+}
+
+func (p *provider) SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error {
+	return p.updateKey(ctx, keyName, "", func(key *keyValue) bool {
+		key.AllowFrom = backend.NilIfEmpty(slices.Clone(allowFrom))
+		return true
+	})
+}
+
+func (p *provider) BindKey(ctx context.Context, keyName string, zoneName string) error {
+	return p.updateKey(ctx, keyName, zoneName, func(key *keyValue) bool {
+		index, found := slices.BinarySearch(key.Zones, zoneName)
+		if found {
+			return false
+		}
+		key.Zones = slices.Insert(key.Zones, index, zoneName)
+		return true
+	})
+}
+
+func (p *provider) UnbindKey(ctx context.Context, keyName string, zoneName string) error {
+	var bound bool
+	err := p.updateKey(ctx, keyName, "", func(key *keyValue) bool {
+		index, found := slices.BinarySearch(key.Zones, zoneName)
+		bound = found
+		if found {
+			key.Zones = backend.NilIfEmpty(slices.Delete(key.Zones, index, index+1))
+		}
+		return found
+	})
+	if err == nil && !bound {
+		return backend.ErrObjectNotInBackend
+	}
+	return err
+}
+
+func (p *provider) CreateZone(ctx context.Context, zoneName string) error {
+	return p.create(ctx, zonesDir, "zone", zoneName, zoneValue{})
+}
+
+func (p *provider) DeleteZone(ctx context.Context, zoneName string) error {
+	zonePath := p.path(zonesDir, zoneName)
+	keysPrefix := p.path(keysDir, "")
+	return p.retry(ctx, "zone", zoneName, func() (*clientv3.TxnResponse, error) {
+		kvs, revision, err := p.get(ctx, clientv3.OpGet(zonePath), clientv3.OpGet(keysPrefix, clientv3.WithPrefix()))
+		if err != nil {
+			return nil, backend.RequestFailed(err, "zone", zoneName)
+		}
+		if len(kvs[0]) == 0 {
+			return nil, backend.ErrZoneNotInBackend
+		}
+		// The key bindings of the zone are removed in the same transaction. It only succeeds if no key has been
+		// changed since they were read, so a key bound to the zone concurrently cannot be missed.
+		ops := []clientv3.Op{clientv3.OpDelete(zonePath)}
+		for _, kv := range kvs[1] {
+			var key keyValue
+			if err := json.Unmarshal(kv.Value, &key); err != nil {
+				return nil, backend.RequestFailed(err, "key", string(kv.Key))
+			}
+			index, found := slices.BinarySearch(key.Zones, zoneName)
+			if !found {
+				continue
+			}
+			key.Zones = backend.NilIfEmpty(slices.Delete(key.Zones, index, index+1))
+			op, err := putOp(string(kv.Key), key)
+			if err != nil {
+				return nil, backend.RequestFailed(err, "key", string(kv.Key))
+			}
+			ops = append(ops, op)
+		}
+		return p.commit(
+			ctx,
+			"zone",
+			zoneName,
+			[]clientv3.Cmp{
+				unchanged(zonePath, kvs[0][0]),
+				clientv3.Compare(clientv3.ModRevision(keysPrefix), "<", revision+1).WithPrefix(),
+			},
+			ops...,
+		)
+	})
+}
+
+func (p *provider) ListZones(_ context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	p.mirror.lock.RLock()
+	zones := make([]backend.ProviderZoneListItem, 0, len(p.mirror.zones))
+	for name, zone := range p.mirror.zones {
+		zones = append(zones, backend.ProviderZoneListItem{
+			Name: name,
+			ProviderZoneResponse: backend.ProviderZoneResponse{
+				Serial:               zone.Serial,
+				ACMEChallengeAnswers: slices.Clone(zone.Answers),
+				Debug:                zone.Debug,
+			},
+		})
+	}
+	p.mirror.lock.RUnlock()
+	slices.SortFunc(zones, func(a, b backend.ProviderZoneListItem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	page, continueToken, err := backend.Paginate(zones, func(zone backend.ProviderZoneListItem) string {
+		return zone.Name
+	}, options)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	return backend.ProviderZoneListResponse{Zones: backend.NilIfEmpty(page), Continue: continueToken}, nil
+}
+
+func (p *provider) ListKeys(_ context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	p.mirror.lock.RLock()
+	keys := make([]backend.ProviderKeyListItem, 0, len(p.mirror.keys))
+	for name, key := range p.mirror.keys {
+		keys = append(keys, backend.ProviderKeyListItem{
+			Name:      name,
+			Zones:     slices.Clone(key.Zones),
+			AllowFrom: slices.Clone(key.AllowFrom),
+		})
+	}
+	p.mirror.lock.RUnlock()
+	slices.SortFunc(keys, func(a, b backend.ProviderKeyListItem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	page, continueToken, err := backend.Paginate(keys, func(key backend.ProviderKeyListItem) string {
+		return key.Name
+	}, options)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	return backend.ProviderKeyListResponse{Keys: backend.NilIfEmpty(page), Continue: continueToken}, nil
+}
+
+func (p *provider) Close(_ context.Context) error {
+	p.mirror.stop()
+	return p.client.Close()
+}
+
+func (p *provider) path(dir string, name string) string {
+	return p.prefix + dir + "/" + name
+}
+
+// create stores a new object if no object with the same name exists.
+func (p *provider) create(ctx context.Context, dir string, kind string, name string, value any) error {
+	path := p.path(dir, name)
+	op, err := putOp(path, value)
+	if err != nil {
+		return backend.RequestFailed(err, kind, name)
+	}
+	response, err := p.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(path), "=", 0)).
+		Then(op).
+		Commit()
+	if err != nil {
+		return backend.RequestFailed(err, kind, name)
+	}
+	if !response.Succeeded {
+		return backend.ErrObjectBackendConflict
+	}
+	return p.mirror.wait(ctx, response.Header.Revision)
+}
+
+// updateZone applies mutate to the current value of a zone and writes it back if the zone has not changed since it
+// was read.
+func (p *provider) updateZone(ctx context.Context, zoneName string, mutate func(zone *zoneValue)) error {
+	path := p.path(zonesDir, zoneName)
+	return p.retry(ctx, "zone", zoneName, func() (*clientv3.TxnResponse, error) {
+		kvs, _, err := p.get(ctx, clientv3.OpGet(path))
+		if err != nil {
+			return nil, backend.RequestFailed(err, "zone", zoneName)
+		}
+		if len(kvs[0]) == 0 {
+			return nil, backend.ErrZoneNotInBackend
+		}
+		var zone zoneValue
+		if err := json.Unmarshal(kvs[0][0].Value, &zone); err != nil {
+			return nil, backend.RequestFailed(err, "zone", zoneName)
+		}
+		mutate(&zone)
+		op, err := putOp(path, zone)
+		if err != nil {
+			return nil, backend.RequestFailed(err, "zone", zoneName)
+		}
+		return p.commit(ctx, "zone", zoneName, []clientv3.Cmp{unchanged(path, kvs[0][0])}, op)
+	})
+}
+
+// updateKey applies mutate to the current value of an update key and writes it back if the key has not changed since
+// it was read. If zoneName is set, the zone must exist and must not be deleted before the key is written. If mutate
+// returns false, nothing is written.
+func (p *provider) updateKey(ctx context.Context, keyName string, zoneName string, mutate func(key *keyValue) bool) error {
+	keyPath := p.path(keysDir, keyName)
+	zonePath := p.path(zonesDir, zoneName)
+	return p.retry(ctx, "key", keyName, func() (*clientv3.TxnResponse, error) {
+		ops := []clientv3.Op{clientv3.OpGet(keyPath)}
+		if zoneName != "" {
+			ops = append(ops, clientv3.OpGet(zonePath))
+		}
+		kvs, _, err := p.get(ctx, ops...)
+		if err != nil {
+			return nil, backend.RequestFailed(err, "key", keyName)
+		}
+		if len(kvs[0]) == 0 {
+			return nil, backend.ErrObjectNotInBackend
+		}
+		compares := []clientv3.Cmp{unchanged(keyPath, kvs[0][0])}
+		if zoneName != "" {
+			if len(kvs[1]) == 0 {
+				return nil, backend.ErrZoneNotInBackend
+			}
+			// Comparing the create revision lets the zone be updated in the meantime, but not deleted.
+			compares = append(compares, clientv3.Compare(clientv3.CreateRevision(zonePath), "=", kvs[1][0].CreateRevision))
+		}
+		var key keyValue
+		if err := json.Unmarshal(kvs[0][0].Value, &key); err != nil {
+			return nil, backend.RequestFailed(err, "key", keyName)
+		}
+		if !mutate(&key) {
+			return &clientv3.TxnResponse{Succeeded: true}, nil
+		}
+		op, err := putOp(keyPath, key)
+		if err != nil {
+			return nil, backend.RequestFailed(err, "key", keyName)
+		}
+		return p.commit(ctx, "key", keyName, compares, op)
+	})
+}
+
+// retry runs attempt until its transaction succeeds, then waits until the mirror reflects the change, so that it is
+// visible to reads right after the write returns.
+func (p *provider) retry(ctx context.Context, kind string, name string, attempt func() (*clientv3.TxnResponse, error)) error {
+	for range maxAttempts {
+		response, err := attempt()
+		if err != nil {
+			return err
+		}
+		if !response.Succeeded {
+			continue
+		}
+		if response.Header == nil {
+			// Nothing was written.
+			return nil
+		}
+		return p.mirror.wait(ctx, response.Header.Revision)
+	}
+	return backend.ErrBackendRequestFailed.
+		Wrap(fmt.Errorf("exhausted retries while trying to update %s", kind)).
+		WithAttr(slog.String(kind, name))
+}
+
+// get reads the results of the specified get operations from a single snapshot and returns them with the revision of
+// the snapshot.
+func (p *provider) get(ctx context.Context, ops ...clientv3.Op) ([][]*mvccpb.KeyValue, int64, error) {
+	response, err := p.client.Txn(ctx).Then(ops...).Commit()
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([][]*mvccpb.KeyValue, len(response.Responses))
+	for i, op := range response.Responses {
+		result[i] = op.GetResponseRange().Kvs
+	}
+	return result, response.Header.Revision, nil
+}
+
+func (p *provider) commit(ctx context.Context, kind string, name string, compares []clientv3.Cmp, ops ...clientv3.Op) (*clientv3.TxnResponse, error) {
+	response, err := p.client.Txn(ctx).If(compares...).Then(ops...).Commit()
+	if err != nil {
+		return nil, backend.RequestFailed(err, kind, name)
+	}
+	return response, nil
+}
+
+// unchanged compares the mod revision of an etcd key with the revision it was read at.
+func unchanged(path string, kv *mvccpb.KeyValue) clientv3.Cmp {
+	return clientv3.Compare(clientv3.ModRevision(path), "=", kv.ModRevision)
+}
+
+func putOp(path string, value any) (clientv3.Op, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return clientv3.Op{}, err
+	}
+	return clientv3.OpPut(path, string(encoded)), nil
+}
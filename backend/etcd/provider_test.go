@@ -0,0 +1,73 @@
+package etcd_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/server/v3/embed"
+	"go.uber.org/zap"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/etcd"
+)
+
+// startEtcd starts an embedded single node etcd server and returns its client endpoint.
+func startEtcd(t *testing.T) string {
+	t.Helper()
+	config := embed.NewConfig()
+	config.Dir = t.TempDir()
+	config.ZapLoggerBuilder = embed.NewZapLoggerBuilder(zap.NewNop())
+	localhost := url.URL{Scheme: "http", Host: "127.0.0.1:0"}
+	config.ListenClientUrls = []url.URL{localhost}
+	config.AdvertiseClientUrls = []url.URL{localhost}
+	config.ListenPeerUrls = []url.URL{localhost}
+	config.AdvertisePeerUrls = []url.URL{localhost}
+	config.InitialCluster = config.InitialClusterFromName(config.Name)
+	server, err := embed.StartEtcd(config)
+	if err != nil {
+		t.Fatalf("Failed to start etcd: %v", err)
+	}
+	t.Cleanup(server.Close)
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		t.Fatalf("etcd did not start in time")
+	}
+	return server.Clients[0].Addr().String()
+}
+
+// open connects a provider to the etcd server. Providers using different prefixes don't see each other's data, so tests
+// can share the server.
+func open(t *testing.T, endpoint string, prefix string) backend.ExtendedProvider {
+	t.Helper()
+	provider, err := etcd.Config{
+		Endpoints:   []string{endpoint},
+		Prefix:      prefix,
+		DialTimeout: 5 * time.Second,
+	}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to connect to etcd: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Close(context.Background())
+	})
+	return provider
+}
+
+func TestConformance(t *testing.T) {
+	endpoint := startEtcd(t)
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		return open(t, endpoint, "/"+t.Name()+"/")
+	})
+	sharedPrefix := func(t *testing.T) backendtest.ExtendedProviderFactory {
+		prefix := "/" + t.Name() + "/"
+		return func(t *testing.T) backend.ExtendedProvider {
+			return open(t, endpoint, prefix)
+		}
+	}
+	backendtest.RunPersistent(t, sharedPrefix)
+	backendtest.RunReplicas(t, sharedPrefix)
+}
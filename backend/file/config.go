@@ -0,0 +1,59 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"log/slog"
+)
+
+type Config struct {
+	Path      string `config:"path" default:"zones.yaml" description:"Path to the YAML or JSON file declaring the zones and update keys."`
+	StatePath string `config:"state-path" default:"dns4acme-state.json" description:"Path to the file the serials and ACME challenge answers are persisted to. The file is created if it does not exist."`
+	Watch     bool   `config:"watch" default:"true" description:"Reload the definitions file when it changes. Invalid changes are logged and ignored."`
+
+	Logger *slog.Logger `json:"-"`
+}
+
+// WithLogger returns a copy of the configuration logging to logger.
+func (c Config) WithLogger(logger *slog.Logger) backend.Config {
+	c.Logger = logger
+	return &c
+}
+
+func (c Config) Build(_ context.Context) (backend.Provider, error) {
+	if c.Path == "" {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the definitions file path must not be empty"))
+	}
+	if c.StatePath == "" {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the state file path must not be empty"))
+	}
+	logger := c.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	logger = logger.WithGroup("file")
+
+	defs, content, err := readDefinitions(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	state, err := readState(c.StatePath)
+	if err != nil {
+		return nil, err
+	}
+	p := &provider{
+		path:        c.Path,
+		statePath:   c.StatePath,
+		definitions: defs,
+		content:     content,
+		state:       state,
+		logger:      logger,
+	}
+	if c.Watch {
+		if p.watcher, err = startWatcher(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
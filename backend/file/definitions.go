@@ -0,0 +1,117 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/internal/secret"
+	"gopkg.in/yaml.v3"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+)
+
+// definitions are the zones and update keys declared in the definitions file, normalized the way the DNS server looks
+// them up: names are lower case and have no trailing dot.
+type definitions struct {
+	zones map[string]backend.ProviderZoneResponse
+	keys  map[string]backend.ProviderKeyResponse
+}
+
+// readDefinitions reads and validates the definitions file. It also returns the raw content so that reloads can be
+// skipped if the file has not changed.
+func readDefinitions(path string) (definitions, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return definitions{}, nil, ErrInvalidDefinitions.Wrap(err).WithAttr(slog.String("path", path))
+	}
+	defs, err := parseDefinitions(data)
+	if err != nil {
+		return definitions{}, nil, ErrInvalidDefinitions.Wrap(err).WithAttr(slog.String("path", path))
+	}
+	return defs, data, nil
+}
+
+// parseDefinitions decodes a definitions file in the shape of the in-memory backend configuration. YAML is a superset
+// of JSON, so both formats are decoded as YAML first and then converted to JSON, which gives both the same field names
+// and lets unknown fields be rejected.
+func parseDefinitions(data []byte) (definitions, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return definitions{}, err
+	}
+	// An empty file is most likely being written right now, so it is never taken as "no zones".
+	if raw == nil {
+		return definitions{}, fmt.Errorf("the file is empty")
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return definitions{}, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var config inmemory.Config
+	if err := decoder.Decode(&config); err != nil {
+		return definitions{}, err
+	}
+
+	defs := definitions{
+		zones: make(map[string]backend.ProviderZoneResponse, len(config.Zones)),
+		keys:  make(map[string]backend.ProviderKeyResponse, len(config.Keys)),
+	}
+	for name, zone := range config.Zones {
+		zoneName := normalizeName(name)
+		if zoneName == "" {
+			return definitions{}, fmt.Errorf("zone names must not be empty")
+		}
+		if _, ok := defs.zones[zoneName]; ok {
+			return definitions{}, fmt.Errorf("zone %s is declared twice", zoneName)
+		}
+		if zone == nil {
+			zone = &backend.ProviderZoneResponse{}
+		}
+		defs.zones[zoneName] = *zone
+	}
+	for name, key := range config.Keys {
+		keyName := normalizeName(name)
+		if keyName == "" {
+			return definitions{}, fmt.Errorf("key names must not be empty")
+		}
+		if _, ok := defs.keys[keyName]; ok {
+			return definitions{}, fmt.Errorf("key %s is declared twice", keyName)
+		}
+		if key == nil || key.Secret == "" {
+			return definitions{}, fmt.Errorf("key %s has no secret", keyName)
+		}
+		if err := secret.Validate(key.Secret); err != nil {
+			return definitions{}, fmt.Errorf("key %s: %w", keyName, err)
+		}
+		if err := backend.ValidateAllowFrom(key.AllowFrom); err != nil {
+			return definitions{}, fmt.Errorf("key %s: %w", keyName, err)
+		}
+		zones := make([]string, 0, len(key.Zones))
+		for _, zone := range key.Zones {
+			zoneName := normalizeName(zone)
+			if _, ok := defs.zones[zoneName]; !ok {
+				return definitions{}, fmt.Errorf("key %s is bound to zone %s, which is not declared", keyName, zone)
+			}
+			if !slices.Contains(zones, zoneName) {
+				zones = append(zones, zoneName)
+			}
+		}
+		slices.Sort(zones)
+		defs.keys[keyName] = backend.ProviderKeyResponse{
+			Secret:    key.Secret,
+			Zones:     backend.NilIfEmpty(zones),
+			AllowFrom: backend.NilIfEmpty(key.AllowFrom),
+		}
+	}
+	return defs, nil
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
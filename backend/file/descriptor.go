@@ -0,0 +1,20 @@
+package file
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+type descriptor struct {
+}
+
+func (d descriptor) Name() string {
+	return "File"
+}
+
+func (d descriptor) Description() string {
+	return "Backend that reads zones and update keys from a YAML or JSON file and persists challenge answers to a state file."
+}
+
+func (d descriptor) Config() backend.Config {
+	return &Config{}
+}
@@ -0,0 +1,7 @@
+package file
+
+import "github.com/dns4acme/dns4acme/lang/E"
+
+var ErrInvalidDefinitions = E.New("FILE_INVALID_DEFINITIONS", "the zone and key definitions file is invalid")
+var ErrStateFailed = E.New("FILE_STATE_FAILED", "failed to read or write the state file")
+var ErrWatchFailed = E.New("FILE_WATCH_FAILED", "failed to watch the zone and key definitions file")
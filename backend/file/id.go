@@ -0,0 +1,3 @@
+package file
+
+const ID = "file"
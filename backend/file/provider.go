@@ -0,0 +1,135 @@
+package file
+
+import (
+	"bytes"
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+type provider struct {
+	path      string
+	statePath string
+	logger    *slog.Logger
+	// watcher is nil if watching is turned off.
+	watcher *watcher
+
+	lock        sync.RWMutex
+	definitions definitions
+	// content is the raw definitions file the definitions were parsed from.
+	content []byte
+	state   map[string]zoneState
+}
+
+func (p *provider) GetKey(_ context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	key, ok := p.definitions.keys[keyName]
+	if !ok {
+		return backend.ProviderKeyResponse{}, backend.ErrKeyNotFoundInBackend
+	}
+	key.Zones = slices.Clone(key.Zones)
+	key.AllowFrom = slices.Clone(key.AllowFrom)
+	return key, nil
+}
+
+func (p *provider) GetZone(_ context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	state, ok := p.zoneState(zoneName)
+	if !ok {
+		return backend.ProviderZoneResponse{}, backend.ErrZoneNotInBackend
+	}
+	return backend.ProviderZoneResponse{
+		Serial:               state.Serial,
+		ACMEChallengeAnswers: slices.Clone(state.Answers),
+		Debug:                state.Debug,
+	}, nil
+}
+
+func (p *provider) SetZone(_ context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	state, ok := p.zoneState(zoneName)
+	if !ok {
+		return backend.ErrZoneNotInBackend
+	}
+	// The serial wraps around like an RFC 1982 serial number.
+	state.Serial++
+	state.Answers = backend.NilIfEmpty(slices.Clone(acmeChallengeAnswers))
+	return p.setZoneState(zoneName, state)
+}
+
+func (p *provider) SetZoneDebug(_ context.Context, zoneName string, debug bool) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	state, ok := p.zoneState(zoneName)
+	if !ok {
+		return backend.ErrZoneNotInBackend
+	}
+	state.Debug = debug
+	return p.setZoneState(zoneName, state)
+}
+
+func (p *provider) Close(_ context.Context) error {
+	if p.watcher != nil {
+		p.watcher.stop()
+	}
+	return nil
+}
+
+// zoneState returns the current state of a declared zone. The values in the definitions file are the initial state,
+// the state file takes precedence once DNS4ACME has changed the zone. The caller must hold the lock.
+func (p *provider) zoneState(zoneName string) (zoneState, bool) {
+	zone, ok := p.definitions.zones[zoneName]
+	if !ok {
+		return zoneState{}, false
+	}
+	if state, ok := p.state[zoneName]; ok {
+		return state, true
+	}
+	return zoneState{Serial: zone.Serial, Answers: zone.ACMEChallengeAnswers, Debug: zone.Debug}, true
+}
+
+// setZoneState persists the state of a zone. If the state file cannot be written, the change is rolled back, so the
+// answers served never differ from the ones that survive a restart. The caller must hold the write lock.
+func (p *provider) setZoneState(zoneName string, state zoneState) error {
+	previous, existed := p.state[zoneName]
+	p.state[zoneName] = state
+	if err := writeState(p.statePath, p.state); err != nil {
+		if existed {
+			p.state[zoneName] = previous
+		} else {
+			delete(p.state, zoneName)
+		}
+		return backend.ErrBackendRequestFailed.Wrap(err).WithAttr(slog.String("zone", zoneName))
+	}
+	return nil
+}
+
+// reload reads the definitions file again and replaces the current definitions if the file is valid. An invalid file
+// is logged and the previous definitions stay in effect.
+func (p *provider) reload(ctx context.Context) {
+	defs, content, err := readDefinitions(p.path)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "Failed to reload the definitions file, keeping the previous definitions", E.ToSLogAttr(err)...)
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if bytes.Equal(content, p.content) {
+		return
+	}
+	p.definitions = defs
+	p.content = content
+	p.logger.InfoContext(
+		ctx,
+		"Reloaded the definitions file",
+		slog.String("path", p.path),
+		slog.Int("zones", len(defs.zones)),
+		slog.Int("keys", len(defs.keys)),
+	)
+}
@@ -0,0 +1,202 @@
+package file_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/file"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+const testDefinitions = `zones:
+  Example.com.: {}
+  example.org:
+    serial: 41
+keys:
+  Certbot.:
+    secret: c2VjcmV0
+    zones: [example.org, example.com]
+    allowFrom: [192.0.2.0/24]
+`
+
+// replace writes a file the way editors do, by renaming a new file over the old one.
+func replace(t *testing.T, path string, content string) {
+	t.Helper()
+	tmp := path + ".new"
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("Failed to replace file: %v", err)
+	}
+}
+
+func open(t *testing.T, config file.Config) backend.Provider {
+	t.Helper()
+	provider, err := config.Build(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to build provider: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Close(context.Background())
+	})
+	return provider
+}
+
+func TestProvider(t *testing.T) {
+	dir := t.TempDir()
+	config := file.Config{Path: filepath.Join(dir, "zones.yaml"), StatePath: filepath.Join(dir, "state.json")}
+	replace(t, config.Path, testDefinitions)
+	provider := open(t, config)
+	ctx := t.Context()
+
+	key, err := provider.GetKey(ctx, "certbot")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if key.Secret != "c2VjcmV0" || !slices.Equal(key.Zones, []string{"example.com", "example.org"}) || !slices.Equal(key.AllowFrom, []string{"192.0.2.0/24"}) {
+		t.Fatalf("Incorrect key: %v", key)
+	}
+	if _, err := provider.GetKey(ctx, "lego"); !E.Is(err, backend.ErrKeyNotFoundInBackend) {
+		t.Fatalf("Expected a missing key error, got %v", err)
+	}
+
+	if err := provider.SetZone(ctx, "example.org", []string{"first", "second"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	if err := provider.SetZoneDebug(ctx, "example.com", true); err != nil {
+		t.Fatalf("Failed to set debug: %v", err)
+	}
+	if err := provider.SetZone(ctx, "example.net", nil); !E.Is(err, backend.ErrZoneNotInBackend) {
+		t.Fatalf("Expected a missing zone error, got %v", err)
+	}
+
+	// The state must survive a restart.
+	if err := provider.Close(ctx); err != nil {
+		t.Fatalf("Failed to close provider: %v", err)
+	}
+	provider = open(t, config)
+	zone, err := provider.GetZone(ctx, "example.org")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if zone.Serial != 42 || zone.Debug || !slices.Equal(zone.ACMEChallengeAnswers, []string{"first", "second"}) {
+		t.Fatalf("Incorrect zone: %v", zone)
+	}
+	if zone, err = provider.GetZone(ctx, "example.com"); err != nil || zone.Serial != 0 || !zone.Debug {
+		t.Fatalf("Incorrect zone: %v (%v)", zone, err)
+	}
+
+	data, err := os.ReadFile(config.StatePath)
+	if err != nil {
+		t.Fatalf("Failed to read state file: %v", err)
+	}
+	var state struct {
+		Zones map[string]struct {
+			Serial  uint32   `json:"serial"`
+			Answers []string `json:"answers"`
+		} `json:"zones"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Failed to decode state file: %v", err)
+	}
+	if state.Zones["example.org"].Serial != 42 || !slices.Equal(state.Zones["example.org"].Answers, []string{"first", "second"}) {
+		t.Fatalf("Incorrect state file: %s", data)
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "state.json.tmp*"))
+	if err != nil || len(matches) != 0 {
+		t.Fatalf("Temporary state files were left behind: %v (%v)", matches, err)
+	}
+}
+
+func TestProviderInvalidDefinitions(t *testing.T) {
+	for name, content := range map[string]string{
+		"empty":          "",
+		"syntax":         "zones: [",
+		"unknown field":  "zones:\n  example.com:\n    serail: 1\n",
+		"missing secret": "zones:\n  example.com: {}\nkeys:\n  certbot:\n    zones: [example.com]\n",
+		"unknown zone":   "zones:\n  example.com: {}\nkeys:\n  certbot:\n    secret: c2VjcmV0\n    zones: [example.org]\n",
+		"allow from":     "zones:\n  example.com: {}\nkeys:\n  certbot:\n    secret: c2VjcmV0\n    allowFrom: [192.0.2.1]\n",
+		"invalid secret": "zones:\n  example.com: {}\nkeys:\n  certbot:\n    secret: not base64!\n",
+		"duplicate zone": "zones:\n  example.com: {}\n  EXAMPLE.com.: {}\n",
+		"duplicate key":  "keys:\n  certbot:\n    secret: c2VjcmV0\n  CERTBOT.:\n    secret: c2VjcmV0\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			config := file.Config{Path: filepath.Join(dir, "zones.yaml"), StatePath: filepath.Join(dir, "state.json")}
+			replace(t, config.Path, content)
+			if _, err := config.Build(t.Context()); !E.Is(err, file.ErrInvalidDefinitions) {
+				t.Fatalf("Expected an invalid definitions error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestProviderReload(t *testing.T) {
+	dir := t.TempDir()
+	config := file.Config{Path: filepath.Join(dir, "zones.json"), StatePath: filepath.Join(dir, "state.json"), Watch: true}
+	replace(t, config.Path, `{"zones": {"example.com": {}}}`)
+	provider := open(t, config)
+	ctx := t.Context()
+	if err := provider.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+
+	replace(t, config.Path, `{"zones": {"example.com": {}, "example.org": {}}, "keys": {"certbot": {"secret": "c2VjcmV0", "zones": ["example.org"]}}}`)
+	eventually(t, func() bool {
+		_, err := provider.GetKey(ctx, "certbot")
+		return err == nil
+	})
+	// Reloading the definitions keeps the state of existing zones.
+	zone, err := provider.GetZone(ctx, "example.com")
+	if err != nil || zone.Serial != 1 || !slices.Equal(zone.ACMEChallengeAnswers, []string{"answer"}) {
+		t.Fatalf("Incorrect zone after reload: %v (%v)", zone, err)
+	}
+
+	// An invalid file must not replace the previous definitions, and a valid one afterward must still be picked up.
+	replace(t, config.Path, `{"zones": {"example.com": {}}, "keys": {"certbot": {"secret": "c2VjcmV0", "zones": ["example.org"]}}}`)
+	time.Sleep(500 * time.Millisecond)
+	if _, err := provider.GetZone(ctx, "example.org"); err != nil {
+		t.Fatalf("An invalid definitions file replaced the previous definitions: %v", err)
+	}
+	replace(t, config.Path, `{"zones": {"example.com": {}}}`)
+	eventually(t, func() bool {
+		_, err := provider.GetZone(ctx, "example.org")
+		return E.Is(err, backend.ErrZoneNotInBackend)
+	})
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("The definitions file was not reloaded in time.")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	backendtest.RunProvider(t, func(t *testing.T, zones []string) backend.Provider {
+		dir := t.TempDir()
+		config := file.Config{Path: filepath.Join(dir, "zones.json"), StatePath: filepath.Join(dir, "state.json")}
+		definitions := map[string]map[string]any{"zones": {}}
+		for _, zone := range zones {
+			definitions["zones"][zone] = map[string]any{}
+		}
+		content, err := json.Marshal(definitions)
+		if err != nil {
+			t.Fatalf("Failed to encode definitions: %v", err)
+		}
+		replace(t, config.Path, string(content))
+		return open(t, config)
+	})
+}
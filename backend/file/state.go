@@ -0,0 +1,77 @@
+package file
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// zoneState is the part of a zone that DNS4ACME changes at runtime and persists to the state file.
+type zoneState struct {
+	Serial  uint32   `json:"serial"`
+	Answers []string `json:"answers,omitempty"`
+	Debug   bool     `json:"debug,omitempty"`
+}
+
+type stateFile struct {
+	Zones map[string]zoneState `json:"zones"`
+}
+
+// readState reads the state file. A missing state file is not an error, it is created on the first update.
+func readState(path string) (map[string]zoneState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return map[string]zoneState{}, nil
+		}
+		return nil, ErrStateFailed.Wrap(err).WithAttr(slog.String("path", path))
+	}
+	var state stateFile
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, ErrStateFailed.Wrap(err).WithAttr(slog.String("path", path))
+	}
+	if state.Zones == nil {
+		state.Zones = map[string]zoneState{}
+	}
+	return state.Zones, nil
+}
+
+// writeState replaces the state file. The state is written to a temporary file in the same directory first and then
+// renamed over the state file, so a crash never leaves a partially written state file behind.
+func writeState(path string, zones map[string]zoneState) error {
+	data, err := json.MarshalIndent(stateFile{Zones: zones}, "", "  ")
+	if err != nil {
+		return ErrStateFailed.Wrap(err).WithAttr(slog.String("path", path))
+	}
+	if err := writeAtomic(path, data); err != nil {
+		return ErrStateFailed.Wrap(err).WithAttr(slog.String("path", path))
+	}
+	return nil
+}
+
+func writeAtomic(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	// The data must be on disk before the rename, otherwise a crash could leave an empty state file.
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
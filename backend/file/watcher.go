@@ -0,0 +1,82 @@
+package file
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"github.com/fsnotify/fsnotify"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// reloadDelay is the time to wait for more changes before reloading. Editors often write a file in several steps, and
+// reloading after the last one avoids logging errors for the intermediate states.
+const reloadDelay = 100 * time.Millisecond
+
+// watcher reloads the definitions of a provider when the definitions file changes.
+type watcher struct {
+	fsWatcher *fsnotify.Watcher
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+func startWatcher(p *provider) (*watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, ErrWatchFailed.Wrap(err)
+	}
+	// Watching the directory instead of the file keeps working when the file is replaced, which is what most editors
+	// and Kubernetes ConfigMap mounts do.
+	if err := fsWatcher.Add(filepath.Dir(p.path)); err != nil {
+		_ = fsWatcher.Close()
+		return nil, ErrWatchFailed.Wrap(err).WithAttr(slog.String("path", p.path))
+	}
+	// The watcher runs until the provider is closed, so it must not use the context passed to Build.
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watcher{
+		fsWatcher: fsWatcher,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go w.run(ctx, p)
+	return w, nil
+}
+
+func (w *watcher) run(ctx context.Context, p *provider) {
+	defer close(w.done)
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+	statePath := filepath.Clean(p.statePath)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+			// Writing the state file and its temporary files must not trigger a reload.
+			if strings.HasPrefix(filepath.Clean(event.Name), statePath) {
+				continue
+			}
+			// Any other change in the directory may replace the definitions file, for example through a symlink.
+			// Reloading an unchanged file is a no-op.
+			timer.Reset(reloadDelay)
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			p.logger.WarnContext(ctx, "Error while watching the definitions file", E.ToSLogAttr(err, slog.String("path", p.path))...)
+		case <-timer.C:
+			p.reload(ctx)
+		}
+	}
+}
+
+func (w *watcher) stop() {
+	w.cancel()
+	<-w.done
+	_ = w.fsWatcher.Close()
+}
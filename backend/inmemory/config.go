@@ -6,15 +6,30 @@ import (
 	"sync"
 )
 
+// Config holds the initial zones and keys of the inmemory backend. It has no config tags, so it cannot be set from
+// the command line, environment variables or _FILE variables. The file backend decodes it from its definitions file.
 type Config struct {
 	Keys  map[string]*backend.ProviderKeyResponse  `json:"keys"`
 	Zones map[string]*backend.ProviderZoneResponse `json:"zones"`
 }
 
-func (c Config) Build(_ context.Context) (backend.Provider, error) {
+func (c Config) Build(ctx context.Context) (backend.Provider, error) {
+	return c.BuildExtended(ctx)
+}
+
+func (c Config) BuildExtended(_ context.Context) (backend.ExtendedProvider, error) {
+	keys := c.Keys
+	if keys == nil {
+		keys = map[string]*backend.ProviderKeyResponse{}
+	}
+	zones := c.Zones
+	if zones == nil {
+		zones = map[string]*backend.ProviderZoneResponse{}
+	}
 	return &provider{
-		lock:  &sync.RWMutex{},
-		keys:  c.Keys,
-		zones: c.Zones,
+		lock:    &sync.RWMutex{},
+		keys:    keys,
+		zones:   zones,
+		changes: &backend.Broadcaster{},
 	}, nil
 }
@@ -3,16 +3,19 @@ package inmemory
 import (
 	"context"
 	"github.com/dns4acme/dns4acme/backend"
+	"slices"
+	"strings"
 	"sync"
 )
 
 // New creates a new in-memory backend for the specified domains. This backend is not suitable for production use as
-// it doesn't persist the domain serials over restarts.
+// it doesn't persist the domain serials over restarts. Use the bbolt backend for a single server instead.
 func New(zones map[string]*backend.ProviderZoneResponse, keys map[string]*backend.ProviderKeyResponse) backend.ExtendedProvider {
 	return &provider{
-		lock:  &sync.RWMutex{},
-		keys:  keys,
-		zones: zones,
+		lock:    &sync.RWMutex{},
+		keys:    keys,
+		zones:   zones,
+		changes: &backend.Broadcaster{},
 	}
 }
 
@@ -20,6 +23,9 @@ type provider struct {
 	lock  *sync.RWMutex
 	keys  map[string]*backend.ProviderKeyResponse
 	zones map[string]*backend.ProviderZoneResponse
+	// changes receives an event for every change. Events are published while holding the write lock, which keeps them
+	// in the order the changes were made.
+	changes *backend.Broadcaster
 }
 
 func (p *provider) CreateKey(_ context.Context, keyName string, secret string) error {
@@ -32,6 +38,7 @@ func (p *provider) CreateKey(_ context.Context, keyName string, secret string) e
 		Secret: secret,
 		Zones:  nil,
 	}
+	p.publishKey(keyName, false)
 	return nil
 }
 
@@ -42,6 +49,7 @@ func (p *provider) DeleteKey(_ context.Context, keyName string) error {
 		return backend.ErrObjectNotInBackend
 	}
 	delete(p.keys, keyName)
+	p.publishKey(keyName, true)
 	return nil
 }
 
@@ -53,6 +61,19 @@ func (p *provider) SetKeySecret(_ context.Context, keyName string, secret string
 		return backend.ErrObjectNotInBackend
 	}
 	keyData.Secret = secret
+	p.publishKey(keyName, false)
+	return nil
+}
+
+func (p *provider) SetKeyAllowFrom(_ context.Context, keyName string, allowFrom []string) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	keyData, ok := p.keys[keyName]
+	if !ok {
+		return backend.ErrObjectNotInBackend
+	}
+	keyData.AllowFrom = slices.Clone(allowFrom)
+	p.publishKey(keyName, false)
 	return nil
 }
 
@@ -63,12 +84,15 @@ func (p *provider) BindKey(_ context.Context, keyName string, zoneName string) e
 	if !ok {
 		return backend.ErrObjectNotInBackend
 	}
-	for _, zone := range keyData.Zones {
-		if zone == zoneName {
-			return nil
-		}
+	if _, ok := p.zones[zoneName]; !ok {
+		return backend.ErrZoneNotInBackend
+	}
+	if slices.Contains(keyData.Zones, zoneName) {
+		return nil
 	}
 	keyData.Zones = append(keyData.Zones, zoneName)
+	slices.Sort(keyData.Zones)
+	p.publishKey(keyName, false)
 	return nil
 }
 
@@ -96,6 +120,7 @@ func (p *provider) unbindKeyLocked(keyName string, zoneName string) error {
 	if !found {
 		return backend.ErrObjectNotInBackend
 	}
+	p.publishKey(keyName, false)
 	return nil
 }
 
@@ -106,6 +131,7 @@ func (p *provider) CreateZone(_ context.Context, zoneName string) error {
 		return backend.ErrObjectBackendConflict
 	}
 	p.zones[zoneName] = &backend.ProviderZoneResponse{}
+	p.publishZone(zoneName, false)
 	return nil
 }
 
@@ -119,6 +145,7 @@ func (p *provider) DeleteZone(_ context.Context, zoneName string) error {
 		_ = p.unbindKeyLocked(keyName, zoneName)
 	}
 	delete(p.zones, zoneName)
+	p.publishZone(zoneName, true)
 	return nil
 }
 
@@ -129,7 +156,11 @@ func (p *provider) GetKey(_ context.Context, keyName string) (backend.ProviderKe
 	if !ok {
 		return backend.ProviderKeyResponse{}, backend.ErrKeyNotFoundInBackend
 	}
-	return *key, nil
+	return backend.ProviderKeyResponse{
+		Secret:    key.Secret,
+		Zones:     slices.Clone(key.Zones),
+		AllowFrom: slices.Clone(key.AllowFrom),
+	}, nil
 }
 
 func (p *provider) GetZone(_ context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
@@ -139,7 +170,11 @@ func (p *provider) GetZone(_ context.Context, zoneName string) (backend.Provider
 	if !ok {
 		return backend.ProviderZoneResponse{}, backend.ErrZoneNotInBackend
 	}
-	return *zone, nil
+	return backend.ProviderZoneResponse{
+		Serial:               zone.Serial,
+		ACMEChallengeAnswers: slices.Clone(zone.ACMEChallengeAnswers),
+		Debug:                zone.Debug,
+	}, nil
 }
 
 func (p *provider) SetZone(_ context.Context, zoneName string, acmeChallengeAnswers []string) error {
@@ -150,7 +185,8 @@ func (p *provider) SetZone(_ context.Context, zoneName string, acmeChallengeAnsw
 		return backend.ErrZoneNotInBackend
 	}
 	zone.Serial++
-	zone.ACMEChallengeAnswers = acmeChallengeAnswers
+	zone.ACMEChallengeAnswers = slices.Clone(acmeChallengeAnswers)
+	p.publishZone(zoneName, false)
 	return nil
 }
 
@@ -162,9 +198,72 @@ func (p *provider) SetZoneDebug(_ context.Context, zoneName string, debug bool)
 		return backend.ErrZoneNotInBackend
 	}
 	zone.Debug = debug
+	p.publishZone(zoneName, false)
 	return nil
 }
 
 func (p *provider) Close(_ context.Context) error {
+	p.changes.Close()
 	return nil
 }
+
+func (p *provider) Watch(ctx context.Context) (<-chan backend.ChangeEvent, error) {
+	return p.changes.Watch(ctx), nil
+}
+
+func (p *provider) publishZone(zoneName string, deleted bool) {
+	p.changes.Publish(backend.ChangeEvent{Kind: backend.ChangeKindZone, Name: zoneName, Deleted: deleted})
+}
+
+func (p *provider) publishKey(keyName string, deleted bool) {
+	p.changes.Publish(backend.ChangeEvent{Kind: backend.ChangeKindKey, Name: keyName, Deleted: deleted})
+}
+
+func (p *provider) ListZones(_ context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	zones := make([]backend.ProviderZoneListItem, 0, len(p.zones))
+	for name, zone := range p.zones {
+		zones = append(zones, backend.ProviderZoneListItem{
+			Name: name,
+			ProviderZoneResponse: backend.ProviderZoneResponse{
+				Serial:               zone.Serial,
+				ACMEChallengeAnswers: slices.Clone(zone.ACMEChallengeAnswers),
+				Debug:                zone.Debug,
+			},
+		})
+	}
+	slices.SortFunc(zones, func(a, b backend.ProviderZoneListItem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	page, continueToken, err := backend.Paginate(zones, func(item backend.ProviderZoneListItem) string {
+		return item.Name
+	}, options)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	return backend.ProviderZoneListResponse{Zones: page, Continue: continueToken}, nil
+}
+
+func (p *provider) ListKeys(_ context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	keys := make([]backend.ProviderKeyListItem, 0, len(p.keys))
+	for name, key := range p.keys {
+		keys = append(keys, backend.ProviderKeyListItem{
+			Name:      name,
+			Zones:     slices.Clone(key.Zones),
+			AllowFrom: slices.Clone(key.AllowFrom),
+		})
+	}
+	slices.SortFunc(keys, func(a, b backend.ProviderKeyListItem) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	page, continueToken, err := backend.Paginate(keys, func(item backend.ProviderKeyListItem) string {
+		return item.Name
+	}, options)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	return backend.ProviderKeyListResponse{Keys: page, Continue: continueToken}, nil
+}
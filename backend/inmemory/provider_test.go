@@ -0,0 +1,19 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+)
+
+func TestConformance(t *testing.T) {
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		provider, err := inmemory.Config{}.BuildExtended(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to build provider: %v", err)
+		}
+		return provider
+	})
+}
@@ -22,19 +22,19 @@ type Config struct {
 	Namespace string `config:"namespace" default:"default" description:"Namespace to look for DNS4ACME resources in."`
 
 	Username string `config:"username" description:"Username for authenticating to the Kubernetes API."`
-	Password string `config:"password" description:"Password for authenticating to the Kubernetes API."`
+	Password string `config:"password" sensitive:"true" file:"true" description:"Password for authenticating to the Kubernetes API."`
 
 	ServerName string `config:"server-name" description:"SNI name to pass to the Kubernetes API server."`
 
 	CertData []byte `config:"cert" description:"PEM-encoded client certificate to use for authenticating to the Kubernetes API."`
-	KeyData  []byte `config:"key" description:"PEM-encoded client private key to use for authenticating to the Kubernetes API."`
+	KeyData  []byte `config:"key" sensitive:"true" description:"PEM-encoded client private key to use for authenticating to the Kubernetes API."`
 	CAData   []byte `config:"cacert" description:"PEM-encoded Certificate Authority to verify the connection to the Kubernetes API."`
 
 	CertFile string `config:"cert-file" description:"File containing the PEM-encoded client certificate to use for authenticating to the Kubernetes API. Set to /var/run/secrets/kubernetes.io/serviceaccount/ca.crt for in-cluster operation."`
 	KeyFile  string `config:"key-file" description:"File containing the PEM-encoded client private key to use for authenticating to the Kubernetes API."`
 	CAFile   string `config:"cacert-file" description:"File containing the PEM-encoded CA certificate to verify the connection to the Kubernetes API."`
 
-	BearerToken     string `config:"bearer-token" description:"Token used to authenticate to the Kubernetes API."`
+	BearerToken     string `config:"bearer-token" sensitive:"true" description:"Token used to authenticate to the Kubernetes API."`
 	BearerTokenFile string `config:"bearer-token-file" description:"File containing the bearer token used to authenticate to the Kubernetes API. Set to /var/run/secrets/kubernetes.io/serviceaccount/token for in-cluster authentication."`
 
 	QPS     float32       `config:"qps" default:"5" description:"Maximum QPS to use for Kubernetes API requests."`
@@ -44,6 +44,12 @@ type Config struct {
 	Logger *slog.Logger `json:"-"`
 }
 
+// WithLogger returns a copy of the configuration logging to logger.
+func (c Config) WithLogger(logger *slog.Logger) backend.Config {
+	c.Logger = logger
+	return &c
+}
+
 func (c Config) Build(ctx context.Context) (backend.Provider, error) {
 	return c.BuildFull(ctx)
 }
@@ -115,6 +121,7 @@ func (c Config) BuildFull(ctx context.Context) (Provider, error) {
 		dynamicClient:    nil,
 		keyBindingsLock:  &sync.RWMutex{},
 		keyBindingsByKey: map[string]map[string]keyBindingSpec{},
+		changes:          &backend.Broadcaster{},
 	}
 	cfg.WarningHandlerWithContext = p
 	p.logger.DebugContext(ctx, "Starting Kubernetes monitoring...")
@@ -122,7 +129,7 @@ func (c Config) BuildFull(ctx context.Context) (Provider, error) {
 	if err != nil {
 		return nil, backend.ErrConfiguration.Wrap(err)
 	}
-	p.zones, err = newObjectCRUD[*zone](ctx, p.dynamicClient, c.Namespace, zoneKind, zoneGroupVersionResource, logger, nil)
+	p.zones, err = newObjectCRUD[*zone](ctx, p.dynamicClient, c.Namespace, zoneKind, zoneGroupVersionResource, logger, p.onZoneChange)
 	if err != nil {
 		if E.Is(err, backend.ErrObjectNotInBackend) {
 			err = ErrCRDMissing.Wrap(err)
@@ -130,7 +137,7 @@ func (c Config) BuildFull(ctx context.Context) (Provider, error) {
 		p.logger.ErrorContext(ctx, err.Error(), E.ToSLogAttr(err)...)
 		return nil, err
 	}
-	p.keys, err = newObjectCRUD[*key](ctx, p.dynamicClient, c.Namespace, keyKind, keyGroupVersionResource, logger, nil)
+	p.keys, err = newObjectCRUD[*key](ctx, p.dynamicClient, c.Namespace, keyKind, keyGroupVersionResource, logger, p.onKeyChange)
 	if err != nil {
 		if E.Is(err, backend.ErrObjectNotInBackend) {
 			err = ErrCRDMissing.Wrap(err)
@@ -138,7 +145,7 @@ func (c Config) BuildFull(ctx context.Context) (Provider, error) {
 		p.logger.ErrorContext(ctx, err.Error(), E.ToSLogAttr(err)...)
 		return nil, err
 	}
-	p.keyBindings, err = newObjectCRUD[*keyBinding](ctx, p.dynamicClient, c.Namespace, keyBindingKind, keyBindingGroupVersionResource, logger, p.updateKeyBindingIndex)
+	p.keyBindings, err = newObjectCRUD[*keyBinding](ctx, p.dynamicClient, c.Namespace, keyBindingKind, keyBindingGroupVersionResource, logger, p.onKeyBindingChange)
 	if err != nil {
 		if E.Is(err, backend.ErrObjectNotInBackend) {
 			err = ErrCRDMissing.Wrap(err)
@@ -146,7 +153,10 @@ func (c Config) BuildFull(ctx context.Context) (Provider, error) {
 		p.logger.ErrorContext(ctx, err.Error(), E.ToSLogAttr(err)...)
 		return nil, err
 	}
-	p.secrets, err = newObjectCRUD[*secret](ctx, p.dynamicClient, c.Namespace, secretKind, secretGroupVersionResource, logger, nil)
+	// The secret handler looks up the keys, so it must read p.keys when called rather than copy the provider now.
+	p.secrets, err = newObjectCRUD[*secret](ctx, p.dynamicClient, c.Namespace, secretKind, secretGroupVersionResource, logger, func(change changeType, object *secret, oldObject *secret) {
+		p.onSecretChange(change, object, oldObject)
+	})
 	if err != nil {
 		if E.Is(err, backend.ErrObjectNotInBackend) {
 			err = ErrCRDMissing.Wrap(err)
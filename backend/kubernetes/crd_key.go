@@ -3,6 +3,7 @@ package kubernetes
 import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"slices"
 )
 
 type key struct {
@@ -22,6 +23,7 @@ func (k *key) mutate(mutate func(object *key) error) (*key, []patch, error) { //
 		Metadata: *k.Metadata.DeepCopy(),
 		Spec: keySpec{
 			SecretRef: k.Spec.SecretRef,
+			AllowFrom: slices.Clone(k.Spec.AllowFrom),
 		},
 	}
 	if err := mutate(newKey); err != nil {
@@ -31,10 +33,16 @@ func (k *key) mutate(mutate func(object *key) error) (*key, []patch, error) { //
 }
 
 func (k *key) checkUpdate(newVersion *key) bool { //nolint:unused // This is used from the interface
-	return k.Spec.SecretRef.Key == newVersion.Spec.SecretRef.Key && k.Spec.SecretRef.Name == newVersion.Spec.SecretRef.Name
+	return k.Spec.SecretRef.Key == newVersion.Spec.SecretRef.Key &&
+		k.Spec.SecretRef.Name == newVersion.Spec.SecretRef.Name &&
+		slices.Equal(k.Spec.AllowFrom, newVersion.Spec.AllowFrom)
 }
 
 func (k *key) createJSONPatch(previousKey *key) []patch { //nolint:unused // This is used from the interface
+	allowFrom := k.Spec.AllowFrom
+	if allowFrom == nil {
+		allowFrom = []string{}
+	}
 	return []patch{
 		{
 			Op:    "test",
@@ -57,21 +65,17 @@ func (k *key) createJSONPatch(previousKey *key) []patch { //nolint:unused // Thi
 			Value: k.Spec.SecretRef.Name,
 		},
 		{
-			Op:   "replace",
-			Path: "/metadata/ownerReferences",
-			Value: []metav1.OwnerReference{
-				{
-					APIVersion: "v1",
-					Kind:       secretKind,
-					Name:       k.Spec.SecretRef.Name,
-				},
-			},
+			// add replaces the value if it exists, keys created by older versions don't have this field.
+			Op:    "add",
+			Path:  "/spec/allowFrom",
+			Value: allowFrom,
 		},
 	}
 }
 
 type keySpec struct {
 	SecretRef secretRef `json:"secretRef"`
+	AllowFrom []string  `json:"allowFrom,omitempty"`
 }
 
 type secretRef struct {
@@ -18,6 +18,8 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
 )
 
@@ -50,6 +52,8 @@ type objectCRUD[T object[T]] interface {
 	delete(ctx context.Context, name string) error
 	// get returns the cached version of the object by name.
 	get(ctx context.Context, name string) (T, error)
+	// list returns the cached version of all objects, ordered by name.
+	list(ctx context.Context) []T
 	// set updates the specified object by name using the specified mutator function.
 	set(ctx context.Context, name string, mutate func(object T) error) error
 	// close cleans up the CRUD provider.
@@ -194,11 +198,7 @@ func (o *objectClient[T]) delete(ctx context.Context, name string) error { //nol
 	)
 	original, err := o.get(ctx, name)
 	if err != nil {
-		var statusErr *kubeerrors.StatusError
-		if !errors.As(err, &statusErr) || statusErr.Status().Code != http.StatusNotFound {
-			return o.processError(err, original.name())
-		}
-		return nil
+		return err
 	}
 
 	ctx = o.getLoggerContext(ctx)
@@ -236,6 +236,19 @@ func (o *objectClient[T]) get(_ context.Context, name string) (T, error) { //nol
 	return object, nil
 }
 
+func (o *objectClient[T]) list(_ context.Context) []T { //nolint:unused // This is used through objectCRUD
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+	result := make([]T, 0, len(o.objects))
+	for _, object := range o.objects {
+		result = append(result, object)
+	}
+	slices.SortFunc(result, func(a, b T) int {
+		return strings.Compare(a.name(), b.name())
+	})
+	return result
+}
+
 func (o *objectClient[T]) set(ctx context.Context, name string, mutate func(object T) error) error { //nolint:unused // This is used through objectCRUD
 	ctx = o.getLoggerContext(ctx)
 	o.logger.DebugContext(
@@ -8,12 +8,14 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	"log/slog"
+	"slices"
 	"sync"
 )
 
 // Provider implements backend.ExtendedProvider and adds Kubernetes-specific functions.
 type Provider interface {
 	backend.ExtendedProvider
+	backend.WatchProvider
 }
 
 type provider struct {
@@ -26,6 +28,7 @@ type provider struct {
 	dynamicClient    *dynamic.DynamicClient
 	keyBindingsLock  *sync.RWMutex
 	keyBindingsByKey map[string]map[string]keyBindingSpec
+	changes          *backend.Broadcaster
 }
 
 func (p provider) CreateKey(ctx context.Context, keyName string, secretData string) error {
@@ -164,34 +167,68 @@ func (p provider) GetKey(ctx context.Context, keyName string) (backend.ProviderK
 	}
 
 	result := backend.ProviderKeyResponse{
-		Secret: updateKey,
-		Zones:  nil,
+		Secret:    updateKey,
+		Zones:     nil,
+		AllowFrom: slices.Clone(keyData.Spec.AllowFrom),
 	}
 
+	result.Zones = p.zonesForKey(keyName)
+	return result, nil
+}
+
+// zonesForKey returns the zones the specified update key is bound to.
+func (p provider) zonesForKey(keyName string) []string {
 	p.keyBindingsLock.RLock()
 	defer p.keyBindingsLock.RUnlock()
 	keyBindings, ok := p.keyBindingsByKey[keyName]
 	if !ok {
-		return result, nil
+		return nil
 	}
+	var result []string
 	zones := map[string]struct{}{}
 	for _, keyBindingData := range keyBindings {
 		if _, ok := zones[keyBindingData.Zone]; ok {
 			continue
 		}
 		zones[keyBindingData.Zone] = struct{}{}
-		result.Zones = append(result.Zones, keyBindingData.Zone)
+		result = append(result, keyBindingData.Zone)
+	}
+	slices.Sort(result)
+	return result
+}
+
+func (p provider) ListKeys(ctx context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	page, continueToken, err := backend.Paginate(p.keys.list(ctx), (*key).name, options)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	result := backend.ProviderKeyListResponse{
+		Keys:     make([]backend.ProviderKeyListItem, len(page)),
+		Continue: continueToken,
+	}
+	for i, keyData := range page {
+		result.Keys[i] = backend.ProviderKeyListItem{
+			Name:      keyData.name(),
+			Zones:     p.zonesForKey(keyData.name()),
+			AllowFrom: slices.Clone(keyData.Spec.AllowFrom),
+		}
 	}
 	return result, nil
 }
 
 func (p provider) DeleteKey(ctx context.Context, keyName string) error {
 	p.logger.InfoContext(ctx, "Deleting update key", slog.String("updateKey", keyName))
-	// Secrets are auto-deleted due to the owner reference if present.
+	if _, err := p.keys.get(ctx, keyName); err != nil {
+		return err
+	}
+	// The bindings and secrets are also deleted by the garbage collector due to the owner references, but only
+	// eventually. Deleting the bindings first keeps a new key with the same name from inheriting them.
+	if err := p.deleteKeyBindings(ctx, func(binding keyBindingSpec) bool {
+		return binding.UpdateKey == keyName
+	}); err != nil {
+		return err
+	}
 	if err := p.keys.delete(ctx, keyName); err != nil {
-		if E.Is(err, backend.ErrObjectNotInBackend) {
-			return backend.ErrKeyNotFoundInBackend.Wrap(err)
-		}
 		p.logger.WarnContext(
 			ctx,
 			"Error deleting update key",
@@ -209,7 +246,7 @@ func (p provider) SetKeySecret(ctx context.Context, keyName string, secretData s
 	keyData, err := p.keys.get(ctx, keyName)
 	if err != nil {
 		if E.Is(err, backend.ErrObjectNotInBackend) {
-			return backend.ErrKeyNotFoundInBackend.Wrap(err)
+			return err
 		}
 		p.logger.WarnContext(
 			ctx,
@@ -225,7 +262,7 @@ func (p provider) SetKeySecret(ctx context.Context, keyName string, secretData s
 		return nil
 	}); err != nil {
 		if E.Is(err, backend.ErrObjectNotInBackend) {
-			return backend.ErrKeyNotFoundInBackend.Wrap(err)
+			return err
 		}
 		p.logger.WarnContext(
 			ctx,
@@ -240,6 +277,27 @@ func (p provider) SetKeySecret(ctx context.Context, keyName string, secretData s
 	return nil
 }
 
+func (p provider) SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error {
+	p.logger.InfoContext(ctx, "Setting allowed networks for update key", slog.String("updateKey", keyName))
+	if err := p.keys.set(ctx, keyName, func(object *key) error {
+		object.Spec.AllowFrom = slices.Clone(allowFrom)
+		return nil
+	}); err != nil {
+		if E.Is(err, backend.ErrObjectNotInBackend) {
+			return err
+		}
+		p.logger.WarnContext(
+			ctx,
+			"Error setting allowed networks for update key",
+			E.ToSLogAttr(err,
+				slog.String("updateKey", keyName),
+			)...,
+		)
+		return err
+	}
+	return nil
+}
+
 func (p provider) BindKey(ctx context.Context, keyName string, zoneName string) error {
 	p.logger.InfoContext(
 		ctx,
@@ -253,7 +311,10 @@ func (p provider) BindKey(ctx context.Context, keyName string, zoneName string)
 	}
 	theZone, err := p.zones.get(ctx, zoneName)
 	if err != nil {
-		return err
+		return backend.ErrZoneNotInBackend.Wrap(err)
+	}
+	if slices.Contains(p.zonesForKey(keyName), zoneName) {
+		return nil
 	}
 	_, err = p.keyBindings.create(ctx, &keyBinding{
 		TypeMeta: v1.TypeMeta{
@@ -287,27 +348,50 @@ func (p provider) BindKey(ctx context.Context, keyName string, zoneName string)
 }
 
 func (p provider) UnbindKey(ctx context.Context, keyName string, zoneName string) error {
-	p.keyBindingsLock.RLock()
-	defer p.keyBindingsLock.RUnlock()
-	bindings, ok := p.keyBindingsByKey[keyName]
-	if !ok {
-		return nil
+	p.logger.InfoContext(
+		ctx,
+		"Unbinding update key from zone",
+		slog.String("updateKey", keyName),
+		slog.String("zone", zoneName),
+	)
+	if _, err := p.keys.get(ctx, keyName); err != nil {
+		return err
 	}
-	for bindingName, binding := range bindings {
-		if binding.Zone != zoneName {
-			continue
+	if !slices.Contains(p.zonesForKey(keyName), zoneName) {
+		return backend.ErrObjectNotInBackend.
+			WithAttr(slog.String("updateKey", keyName)).
+			WithAttr(slog.String("zone", zoneName))
+	}
+	return p.deleteKeyBindings(ctx, func(binding keyBindingSpec) bool {
+		return binding.UpdateKey == keyName && binding.Zone == zoneName
+	})
+}
+
+// deleteKeyBindings deletes all key bindings matching the filter. Bindings deleted concurrently are ignored.
+func (p provider) deleteKeyBindings(ctx context.Context, match func(binding keyBindingSpec) bool) error {
+	// The lock must not be held while deleting, since the informer takes it to update the index before the deletion
+	// completes.
+	var names []string
+	p.keyBindingsLock.RLock()
+	for _, bindings := range p.keyBindingsByKey {
+		for bindingName, binding := range bindings {
+			if match(binding) {
+				names = append(names, bindingName)
+			}
 		}
+	}
+	p.keyBindingsLock.RUnlock()
+
+	for _, bindingName := range names {
 		if err := p.keyBindings.delete(ctx, bindingName); err != nil {
 			if E.Is(err, backend.ErrObjectNotInBackend) {
 				continue
 			}
 			p.logger.WarnContext(
 				ctx,
-				"Error unbinding update key from zone",
+				"Error deleting key binding",
 				E.ToSLogAttr(err,
 					slog.String("keyBinding", bindingName),
-					slog.String("updateKey", keyName),
-					slog.String("zone", zoneName),
 				)...,
 			)
 			return err
@@ -351,30 +435,69 @@ func (p provider) GetZone(ctx context.Context, zoneName string) (backend.Provide
 	}
 	return backend.ProviderZoneResponse{
 		Serial:               zoneData.Spec.Serial,
-		ACMEChallengeAnswers: zoneData.Spec.ACMEChallengeAnswers,
+		ACMEChallengeAnswers: slices.Clone(zoneData.Spec.ACMEChallengeAnswers),
 		Debug:                zoneData.Spec.Debug,
 	}, nil
 }
 
+func (p provider) ListZones(ctx context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	page, continueToken, err := backend.Paginate(p.zones.list(ctx), (*zone).name, options)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	result := backend.ProviderZoneListResponse{
+		Zones:    make([]backend.ProviderZoneListItem, len(page)),
+		Continue: continueToken,
+	}
+	for i, zoneData := range page {
+		result.Zones[i] = backend.ProviderZoneListItem{
+			Name: zoneData.name(),
+			ProviderZoneResponse: backend.ProviderZoneResponse{
+				Serial:               zoneData.Spec.Serial,
+				ACMEChallengeAnswers: slices.Clone(zoneData.Spec.ACMEChallengeAnswers),
+				Debug:                zoneData.Spec.Debug,
+			},
+		}
+	}
+	return result, nil
+}
+
 func (p provider) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
-	return p.zones.set(ctx, zoneName, func(object *zone) error {
-		object.Spec.ACMEChallengeAnswers = acmeChallengeAnswers
+	return zoneError(p.zones.set(ctx, zoneName, func(object *zone) error {
+		object.Spec.ACMEChallengeAnswers = slices.Clone(acmeChallengeAnswers)
 		object.Spec.Serial++
 		return nil
-	})
+	}))
 }
 
 func (p provider) SetZoneDebug(ctx context.Context, zoneName string, debug bool) error {
-	return p.zones.set(ctx, zoneName, func(object *zone) error {
+	return zoneError(p.zones.set(ctx, zoneName, func(object *zone) error {
 		object.Spec.Debug = debug
 		object.Spec.Serial++
 		return nil
-	})
+	}))
 }
 
 func (p provider) DeleteZone(ctx context.Context, zoneName string) error {
 	p.logger.InfoContext(ctx, "Deleting zone", slog.String("zone", zoneName))
-	return p.zones.delete(ctx, zoneName)
+	if _, err := p.zones.get(ctx, zoneName); err != nil {
+		return zoneError(err)
+	}
+	// See DeleteKey for why the bindings are deleted before the zone.
+	if err := p.deleteKeyBindings(ctx, func(binding keyBindingSpec) bool {
+		return binding.Zone == zoneName
+	}); err != nil {
+		return err
+	}
+	return zoneError(p.zones.delete(ctx, zoneName))
+}
+
+// zoneError reports a missing zone object as a missing zone.
+func zoneError(err error) error {
+	if E.Is(err, backend.ErrObjectNotInBackend) {
+		return backend.ErrZoneNotInBackend.Wrap(err)
+	}
+	return err
 }
 
 func (p provider) HandleWarningHeaderWithContext(ctx context.Context, code int, agent string, text string) {
@@ -388,6 +511,7 @@ func (p provider) HandleWarningHeaderWithContext(ctx context.Context, code int,
 }
 
 func (p provider) Close(ctx context.Context) error {
+	p.changes.Close()
 	grp := errgroup.Group{}
 	grp.Go(func() error {
 		return p.zones.close(ctx)
@@ -414,7 +538,12 @@ func (p provider) updateKeyBindingIndex(change changeType, binding *keyBinding,
 		p.keyBindingsByKey[binding.Spec.UpdateKey][binding.name()] = binding.Spec
 	}
 	remove := func(binding *keyBinding) {
-		if _, ok := p.keyBindingsByKey[binding.Spec.UpdateKey]; !ok {
+		bindings, ok := p.keyBindingsByKey[binding.Spec.UpdateKey]
+		if !ok {
+			return
+		}
+		delete(bindings, binding.name())
+		if len(bindings) == 0 {
 			delete(p.keyBindingsByKey, binding.Spec.UpdateKey)
 		}
 	}
@@ -2,8 +2,15 @@ package kubernetes_test
 
 import (
 	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
 	"github.com/dns4acme/dns4acme/backend/kubernetes"
 	"github.com/dns4acme/dns4acme/internal/testlogger"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"os"
 	"slices"
@@ -11,23 +18,57 @@ import (
 	"time"
 )
 
-func TestProvider(t *testing.T) {
-	logger := testlogger.New(t)
-	home, err := os.UserHomeDir()
+// kubeconfigEnv names the environment variable with the path of the kubeconfig for the test cluster. The tests create
+// a new namespace for each provider and delete it afterwards. The DNS4ACME CRDs must be installed in the cluster.
+const kubeconfigEnv = "DNS4ACME_TEST_KUBECONFIG"
+
+var namespaces = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// createNamespace creates a namespace with a random name that is deleted when the test ends.
+func createNamespace(t *testing.T, clientConfig *rest.Config) string {
+	t.Helper()
+	client, err := dynamic.NewForConfig(clientConfig)
 	if err != nil {
-		t.Skipf("Could not determine user home directory, skipping test (%v)", err)
+		t.Fatalf("Failed to create Kubernetes client: %v", err)
+	}
+	namespace := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]any{"generateName": "dns4acme-test-"},
+	}}
+	created, err := client.Resource(namespaces).Create(t.Context(), namespace, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Failed to create test namespace: %v", err)
+	}
+	name := created.GetName()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		if err := client.Resource(namespaces).Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+			t.Errorf("Failed to delete test namespace %s: %v", name, err)
+		}
+	})
+	return name
+}
+
+// open builds a provider for a new namespace in the test cluster and skips the test if no test cluster is configured.
+func open(t *testing.T) kubernetes.Provider {
+	t.Helper()
+	logger := testlogger.New(t)
+	kubeconfigPath := os.Getenv(kubeconfigEnv)
+	if kubeconfigPath == "" {
+		t.Skipf("%s is not set, skipping Kubernetes tests.", kubeconfigEnv)
 	}
-	kubeconfigPath := home + "/.kube/config"
 	clientConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath},
 		nil).ClientConfig()
 	if err != nil {
-		t.Skipf("Could not load user kubeconfig from %s, skipping test (%v)", kubeconfigPath, err)
+		t.Fatalf("Could not load kubeconfig from %s: %v", kubeconfigPath, err)
 	}
+	namespace := createNamespace(t, clientConfig)
 
 	config := kubernetes.Config{
-		// TODO this namespace must exist for the test to work.
-		Namespace:       "dns4acme",
+		Namespace:       namespace,
 		Host:            clientConfig.Host,
 		APIPath:         clientConfig.APIPath,
 		Username:        clientConfig.Username,
@@ -52,15 +93,20 @@ func TestProvider(t *testing.T) {
 
 	provider, err := config.BuildFull(t.Context())
 	if err != nil {
-		t.Skipf("Cannot build Kubernetes provider from config, skipping test (%v)", err)
+		t.Fatalf("Cannot build Kubernetes provider from config: %v", err)
 	}
-	defer func() {
+	t.Cleanup(func() {
 		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 		defer cancel()
 		if err := provider.Close(ctx); err != nil {
 			t.Errorf("Failed to close provider: %v", err)
 		}
-	}()
+	})
+	return provider
+}
+
+func TestProvider(t *testing.T) {
+	provider := open(t)
 
 	// TODO the CRD must be deployed for this to work.
 
@@ -100,4 +146,29 @@ func TestProvider(t *testing.T) {
 	if !slices.Equal(nextData.ACMEChallengeAnswers, []string{"Hello world!"}) {
 		t.Fatalf("Incorrect ACME challenge answers returned: %v", nextData.ACMEChallengeAnswers)
 	}
+
+	zones, err := provider.ListZones(t.Context(), backend.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list zones: %v", err)
+	}
+	if !slices.ContainsFunc(zones.Zones, func(item backend.ProviderZoneListItem) bool {
+		return item.Name == "test.example.com" && item.Serial == nextData.Serial
+	}) {
+		t.Fatalf("The test zone is missing from the zone list: %v", zones.Zones)
+	}
+	keys, err := provider.ListKeys(t.Context(), backend.ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !slices.ContainsFunc(keys.Keys, func(item backend.ProviderKeyListItem) bool {
+		return item.Name == "asdf" && slices.Equal(item.Zones, []string{"test.example.com"})
+	}) {
+		t.Fatalf("The test key is missing from the key list: %v", keys.Keys)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		return open(t)
+	})
 }
@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"reflect"
+)
+
+func (p provider) Watch(ctx context.Context) (<-chan backend.ChangeEvent, error) {
+	return p.changes.Watch(ctx), nil
+}
+
+// The change handlers below run in the informers, so they also report changes made by other replicas or by kubectl.
+// Updates that only change the metadata, such as the status or the managed fields, are not reported.
+
+func (p provider) onZoneChange(change changeType, object *zone, oldObject *zone) {
+	switch change {
+	case changeTypeAdd:
+		p.publish(backend.ChangeKindZone, object.name(), false)
+	case changeTypeUpdate:
+		if !reflect.DeepEqual(object.Spec, oldObject.Spec) {
+			p.publish(backend.ChangeKindZone, object.name(), false)
+		}
+	case changeTypeDelete:
+		p.publish(backend.ChangeKindZone, oldObject.name(), true)
+	}
+}
+
+func (p provider) onKeyChange(change changeType, object *key, oldObject *key) {
+	switch change {
+	case changeTypeAdd:
+		p.publish(backend.ChangeKindKey, object.name(), false)
+	case changeTypeUpdate:
+		if !reflect.DeepEqual(object.Spec, oldObject.Spec) {
+			p.publish(backend.ChangeKindKey, object.name(), false)
+		}
+	case changeTypeDelete:
+		p.publish(backend.ChangeKindKey, oldObject.name(), true)
+	}
+}
+
+// onKeyBindingChange updates the binding index and reports the change as a change of the bound update key.
+func (p provider) onKeyBindingChange(change changeType, object *keyBinding, oldObject *keyBinding) {
+	p.updateKeyBindingIndex(change, object, oldObject)
+	switch change {
+	case changeTypeAdd:
+		p.publish(backend.ChangeKindKey, object.Spec.UpdateKey, false)
+	case changeTypeUpdate:
+		if object.Spec != oldObject.Spec {
+			p.publish(backend.ChangeKindKey, oldObject.Spec.UpdateKey, false)
+			p.publish(backend.ChangeKindKey, object.Spec.UpdateKey, false)
+		}
+	case changeTypeDelete:
+		p.publish(backend.ChangeKindKey, oldObject.Spec.UpdateKey, false)
+	}
+}
+
+// onSecretChange reports a changed secret as a change of the update keys referencing it.
+func (p provider) onSecretChange(change changeType, object *secret, oldObject *secret) {
+	if change == changeTypeUpdate && reflect.DeepEqual(object.Data, oldObject.Data) {
+		return
+	}
+	secretData := object
+	if change == changeTypeDelete {
+		secretData = oldObject
+	}
+	for _, keyData := range p.keys.list(context.Background()) {
+		if keyData.Spec.SecretRef.Name == secretData.name() {
+			p.publish(backend.ChangeKindKey, keyData.name(), false)
+		}
+	}
+}
+
+func (p provider) publish(kind backend.ChangeKind, name string, deleted bool) {
+	p.changes.Publish(backend.ChangeEvent{Kind: kind, Name: name, Deleted: deleted})
+}
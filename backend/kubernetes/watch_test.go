@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+func TestChangeHandlers(t *testing.T) {
+	p := provider{
+		keyBindingsLock:  &sync.RWMutex{},
+		keyBindingsByKey: map[string]map[string]keyBindingSpec{},
+		changes:          &backend.Broadcaster{},
+	}
+	events := p.changes.Watch(t.Context())
+
+	zoneObject := &zone{Metadata: v1.ObjectMeta{Name: "example.com"}}
+	updatedZone := &zone{Metadata: v1.ObjectMeta{Name: "example.com"}, Spec: zoneSpec{Serial: 1}}
+	binding := &keyBinding{
+		Metadata: v1.ObjectMeta{Name: "certbot-binding-example.com-abcde"},
+		Spec:     keyBindingSpec{Zone: "example.com", UpdateKey: "certbot"},
+	}
+	p.onZoneChange(changeTypeAdd, zoneObject, nil)
+	// Metadata-only updates are not reported.
+	p.onZoneChange(changeTypeUpdate, zoneObject, zoneObject)
+	p.onZoneChange(changeTypeUpdate, updatedZone, zoneObject)
+	p.onKeyBindingChange(changeTypeAdd, binding, nil)
+	if zones := p.zonesForKey("certbot"); !slices.Equal(zones, []string{"example.com"}) {
+		t.Fatalf("Incorrect zones after adding a binding: %v", zones)
+	}
+	p.onKeyBindingChange(changeTypeDelete, nil, binding)
+	if zones := p.zonesForKey("certbot"); len(zones) != 0 {
+		t.Fatalf("Incorrect zones after deleting the binding: %v", zones)
+	}
+	p.onZoneChange(changeTypeDelete, nil, updatedZone)
+
+	for _, expected := range []backend.ChangeEvent{
+		{Kind: backend.ChangeKindZone, Name: "example.com"},
+		{Kind: backend.ChangeKindZone, Name: "example.com"},
+		{Kind: backend.ChangeKindKey, Name: "certbot"},
+		{Kind: backend.ChangeKindKey, Name: "certbot"},
+		{Kind: backend.ChangeKindZone, Name: "example.com", Deleted: true},
+	} {
+		select {
+		case event := <-events:
+			if event != expected {
+				t.Fatalf("Expected %v, got %v", expected, event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timeout while waiting for %v", expected)
+		}
+	}
+}
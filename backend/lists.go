@@ -0,0 +1,18 @@
+package backend
+
+// NilIfEmpty returns nil for empty lists. Providers return nil instead of empty lists, so that the results of all
+// backends compare equal.
+func NilIfEmpty[T any](items []T) []T {
+	if len(items) == 0 {
+		return nil
+	}
+	return items
+}
+
+// NonNil returns an empty list instead of nil, for storages telling an empty list and a missing value apart.
+func NonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
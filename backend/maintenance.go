@@ -0,0 +1,24 @@
+package backend
+
+import (
+	"context"
+	"io"
+)
+
+// MaintenanceProvider defines the functions a backend storing its data in a local database file can implement to
+// support backups and compaction while it is serving requests.
+type MaintenanceProvider interface {
+	// Backup writes a consistent snapshot of the database to w and returns the number of bytes written. The snapshot
+	// is a valid database file that the backend can open.
+	Backup(ctx context.Context, w io.Writer) (int64, error)
+	// Compact rewrites the database file to release the space left behind by deleted and updated data.
+	Compact(ctx context.Context) (CompactResponse, error)
+}
+
+// CompactResponse describes the result of a compaction.
+type CompactResponse struct {
+	// SizeBefore is the size of the database file in bytes before the compaction.
+	SizeBefore int64
+	// SizeAfter is the size of the database file in bytes after the compaction.
+	SizeAfter int64
+}
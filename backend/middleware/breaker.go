@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// CircuitBreaker rejects requests with backend.ErrBackendUnavailable without passing them on after threshold
+// consecutive requests have failed. After openTime, it lets a single request through to test whether the backend has
+// recovered: if it succeeds, all requests are passed on again, otherwise requests are rejected for another openTime.
+// Errors with which the backend answered a request, such as a missing zone, do not count as failures. Requests canceled
+// by the caller count as neither failures nor successes.
+func CircuitBreaker(threshold int, openTime time.Duration, logger *slog.Logger) Middleware {
+	b := &breaker{
+		threshold: threshold,
+		openTime:  openTime,
+		logger:    logger,
+	}
+	return b.handle
+}
+
+type breaker struct {
+	threshold int
+	openTime  time.Duration
+	logger    *slog.Logger
+
+	lock sync.Mutex
+	// failures is the number of consecutive failed requests. The breaker is open if it reaches the threshold.
+	failures int
+	// openUntil is the time until which requests are rejected while the breaker is open.
+	openUntil time.Time
+	// testing is true while the request testing whether the backend has recovered is in progress.
+	testing bool
+}
+
+func (b *breaker) handle(ctx context.Context, call Call, next Handler) error {
+	allowed, test := b.allow()
+	if !allowed {
+		return backend.ErrBackendUnavailable.WithAttr(slog.String("method", call.Method))
+	}
+	err := next(ctx)
+	b.record(ctx, err, test)
+	return err
+}
+
+// allow returns if a request may be passed on, and if it is the request testing whether the backend has recovered.
+func (b *breaker) allow() (bool, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.failures < b.threshold {
+		return true, false
+	}
+	if b.testing || time.Now().Before(b.openUntil) {
+		return false, false
+	}
+	b.testing = true
+	return true, true
+}
+
+func (b *breaker) record(ctx context.Context, err error, test bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if test {
+		b.testing = false
+	}
+	if err != nil && ctx.Err() != nil {
+		// A canceled request shows neither that the backend is failing nor that it has recovered. If it was the test
+		// request, the breaker stays open and the next request tests the backend instead.
+		return
+	}
+	if err == nil || isPermanent(err) {
+		if b.failures >= b.threshold {
+			b.logger.InfoContext(ctx, "The backend has recovered, passing on requests again.")
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures == b.threshold || test {
+		b.openUntil = time.Now().Add(b.openTime)
+		b.logger.WarnContext(
+			ctx,
+			"Backend requests are failing, rejecting requests until the backend recovers.",
+			E.ToSLogAttr(err, slog.Int("failures", b.failures), slog.Duration("retry_after", b.openTime))...,
+		)
+	}
+}
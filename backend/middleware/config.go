@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/dns4acme/dns4acme/backend"
+	"log/slog"
+	"time"
+)
+
+// Config configures the middleware chain the DNS server and the HTTP API send backend requests through.
+type Config struct {
+	Timeout          time.Duration `config:"timeout" default:"10s" description:"Maximum time for a single backend request. Each retry gets its own timeout. Requests have no timeout if 0."`
+	Retries          int           `config:"retries" default:"2" description:"Number of times to retry failed backend requests that are safe to repeat, such as lookups. Updating the ACME challenge answers and creating or deleting zones and update keys are never retried."`
+	RetryBackoff     time.Duration `config:"retry-backoff" default:"100ms" description:"Time to wait before the first retry. The time doubles with each further retry."`
+	RetryMaxBackoff  time.Duration `config:"retry-max-backoff" default:"2s" description:"Maximum time to wait between retries."`
+	BreakerThreshold int           `config:"breaker-threshold" default:"10" description:"Number of consecutive failed backend requests after which requests fail immediately without reaching the backend. The circuit breaker is disabled if 0."`
+	BreakerOpenTime  time.Duration `config:"breaker-open-time" default:"10s" description:"Time to fail requests immediately before letting a request through to test whether the backend has recovered."`
+}
+
+func (c Config) Validate() error {
+	if c.Timeout < 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the backend request timeout must not be negative"))
+	}
+	if c.Retries < 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the number of backend request retries must not be negative"))
+	}
+	if c.Retries > 0 && (c.RetryBackoff <= 0 || c.RetryMaxBackoff < c.RetryBackoff) {
+		return backend.ErrConfiguration.Wrap(errors.New("the retry backoff must be positive and not exceed the maximum retry backoff"))
+	}
+	if c.BreakerThreshold < 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the circuit breaker threshold must not be negative"))
+	}
+	if c.BreakerThreshold > 0 && c.BreakerOpenTime <= 0 {
+		return backend.ErrConfiguration.Wrap(errors.New("the circuit breaker open time must be positive"))
+	}
+	return nil
+}
+
+// Middlewares returns the middleware chain for the configuration. Requests are logged first, so the log shows the
+// total duration including retries, then pass the circuit breaker, which counts a request failing after all retries as
+// a single failure, and are retried with a timeout for each attempt.
+func (c Config) Middlewares(logger *slog.Logger) []Middleware {
+	middlewares := []Middleware{Log(logger)}
+	if c.BreakerThreshold > 0 {
+		middlewares = append(middlewares, CircuitBreaker(c.BreakerThreshold, c.BreakerOpenTime, logger))
+	}
+	if c.Retries > 0 {
+		middlewares = append(middlewares, Retry(c.Retries, c.RetryBackoff, c.RetryMaxBackoff, logger))
+	}
+	if c.Timeout > 0 {
+		middlewares = append(middlewares, Timeout(c.Timeout))
+	}
+	return middlewares
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"log/slog"
+	"time"
+)
+
+// Log logs every request with its duration at debug level, and failed requests at warning level unless the backend
+// answered with an error such as a missing zone.
+func Log(logger *slog.Logger) Middleware {
+	return func(ctx context.Context, call Call, next Handler) error {
+		start := time.Now()
+		err := next(ctx)
+		attrs := []any{
+			slog.String("method", call.Method),
+			slog.String("name", call.Name),
+			slog.Duration("duration", time.Since(start)),
+		}
+		switch {
+		case err == nil:
+			logger.DebugContext(ctx, "Backend request completed.", attrs...)
+		case isPermanent(err):
+			logger.DebugContext(ctx, "Backend request completed with an error.", E.ToSLogAttr(err, attrs...)...)
+		default:
+			logger.WarnContext(ctx, "Backend request failed.", E.ToSLogAttr(err, attrs...)...)
+		}
+		return err
+	}
+}
@@ -0,0 +1,243 @@
+// Package middleware wraps backend requests in a chain of middlewares, such as retries, a circuit breaker, timeouts and
+// logging. The chain works with any backend, since it only relies on the functions of backend.Provider and
+// backend.ExtendedProvider.
+package middleware
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"io"
+)
+
+// Call describes a backend request passing through the middleware chain.
+type Call struct {
+	// Method is the name of the provider function called, for example GetZone.
+	Method string
+	// Name is the zone or update key the request refers to. It is empty for lists.
+	Name string
+	// Idempotent is true if making the request again has the same effect as making it once, so it is safe to retry.
+	Idempotent bool
+}
+
+// Handler makes a backend request, or passes it on to the next middleware.
+type Handler func(ctx context.Context) error
+
+// Middleware handles a backend request. It passes the request on by calling next, which it may call several times or
+// not at all.
+type Middleware func(ctx context.Context, call Call, next Handler) error
+
+// New wraps a provider in a middleware chain. The first middleware sees each request first. Closing the returned
+// provider closes the wrapped provider. If the wrapped provider implements backend.WatchProvider, so does the
+// returned provider.
+func New(provider backend.Provider, middlewares ...Middleware) backend.Provider {
+	c := &chain{provider: provider, middlewares: middlewares}
+	if watchProvider, ok := provider.(backend.WatchProvider); ok {
+		return &watchingChain{chain: c, watchProvider: watchProvider}
+	}
+	return c
+}
+
+// NewExtended wraps a provider supporting the management functions in a middleware chain, see New. If the wrapped
+// provider implements backend.MaintenanceProvider, so does the returned provider. Backups and compactions bypass the
+// middlewares, since they can neither be retried nor limited to the usual request time.
+func NewExtended(provider backend.ExtendedProvider, middlewares ...Middleware) backend.ExtendedProvider {
+	c := &extendedChain{chain: &chain{provider: provider, middlewares: middlewares}, provider: provider}
+	watchProvider, watches := provider.(backend.WatchProvider)
+	maintenanceProvider, maintains := provider.(backend.MaintenanceProvider)
+	switch {
+	case watches && maintains:
+		return &watchingMaintenanceChain{
+			maintenanceChain: &maintenanceChain{extendedChain: c, maintenanceProvider: maintenanceProvider},
+			watchProvider:    watchProvider,
+		}
+	case watches:
+		return &watchingExtendedChain{extendedChain: c, watchProvider: watchProvider}
+	case maintains:
+		return &maintenanceChain{extendedChain: c, maintenanceProvider: maintenanceProvider}
+	default:
+		return c
+	}
+}
+
+type chain struct {
+	provider    backend.Provider
+	middlewares []Middleware
+}
+
+// invoke passes a request through the middlewares to handler.
+func (c *chain) invoke(ctx context.Context, call Call, handler Handler) error {
+	next := handler
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		middleware := c.middlewares[i]
+		inner := next
+		next = func(ctx context.Context) error {
+			return middleware(ctx, call, inner)
+		}
+	}
+	return next(ctx)
+}
+
+func (c *chain) GetKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	var key backend.ProviderKeyResponse
+	err := c.invoke(ctx, Call{Method: "GetKey", Name: keyName, Idempotent: true}, func(ctx context.Context) error {
+		var err error
+		key, err = c.provider.GetKey(ctx, keyName)
+		return err
+	})
+	if err != nil {
+		return backend.ProviderKeyResponse{}, err
+	}
+	return key, nil
+}
+
+func (c *chain) GetZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	var zone backend.ProviderZoneResponse
+	err := c.invoke(ctx, Call{Method: "GetZone", Name: zoneName, Idempotent: true}, func(ctx context.Context) error {
+		var err error
+		zone, err = c.provider.GetZone(ctx, zoneName)
+		return err
+	})
+	if err != nil {
+		return backend.ProviderZoneResponse{}, err
+	}
+	return zone, nil
+}
+
+func (c *chain) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	// Every call increments the serial, so a retry after a lost response would increment it twice.
+	return c.invoke(ctx, Call{Method: "SetZone", Name: zoneName}, func(ctx context.Context) error {
+		return c.provider.SetZone(ctx, zoneName, acmeChallengeAnswers)
+	})
+}
+
+func (c *chain) SetZoneDebug(ctx context.Context, zoneName string, debug bool) error {
+	return c.invoke(ctx, Call{Method: "SetZoneDebug", Name: zoneName, Idempotent: true}, func(ctx context.Context) error {
+		return c.provider.SetZoneDebug(ctx, zoneName, debug)
+	})
+}
+
+func (c *chain) Close(ctx context.Context) error {
+	return c.provider.Close(ctx)
+}
+
+type watchingChain struct {
+	*chain
+	watchProvider backend.WatchProvider
+}
+
+func (c *watchingChain) Watch(ctx context.Context) (<-chan backend.ChangeEvent, error) {
+	return c.watchProvider.Watch(ctx)
+}
+
+type extendedChain struct {
+	*chain
+	provider backend.ExtendedProvider
+}
+
+// CreateKey, DeleteKey, UnbindKey, CreateZone and DeleteZone are not idempotent: repeating them after a lost response
+// fails with a conflict or because the object no longer exists.
+
+func (c *extendedChain) CreateKey(ctx context.Context, keyName string, secret string) error {
+	return c.invoke(ctx, Call{Method: "CreateKey", Name: keyName}, func(ctx context.Context) error {
+		return c.provider.CreateKey(ctx, keyName, secret)
+	})
+}
+
+func (c *extendedChain) DeleteKey(ctx context.Context, keyName string) error {
+	return c.invoke(ctx, Call{Method: "DeleteKey", Name: keyName}, func(ctx context.Context) error {
+		return c.provider.DeleteKey(ctx, keyName)
+	})
+}
+
+func (c *extendedChain) SetKeySecret(ctx context.Context, keyName string, secret string) error {
+	return c.invoke(ctx, Call{Method: "SetKeySecret", Name: keyName, Idempotent: true}, func(ctx context.Context) error {
+		return c.provider.SetKeySecret(ctx, keyName, secret)
+	})
+}
+
+func (c *extendedChain) BindKey(ctx context.Context, keyName string, zoneName string) error {
+	return c.invoke(ctx, Call{Method: "BindKey", Name: keyName, Idempotent: true}, func(ctx context.Context) error {
+		return c.provider.BindKey(ctx, keyName, zoneName)
+	})
+}
+
+func (c *extendedChain) UnbindKey(ctx context.Context, keyName string, zoneName string) error {
+	return c.invoke(ctx, Call{Method: "UnbindKey", Name: keyName}, func(ctx context.Context) error {
+		return c.provider.UnbindKey(ctx, keyName, zoneName)
+	})
+}
+
+func (c *extendedChain) SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error {
+	return c.invoke(ctx, Call{Method: "SetKeyAllowFrom", Name: keyName, Idempotent: true}, func(ctx context.Context) error {
+		return c.provider.SetKeyAllowFrom(ctx, keyName, allowFrom)
+	})
+}
+
+func (c *extendedChain) CreateZone(ctx context.Context, zoneName string) error {
+	return c.invoke(ctx, Call{Method: "CreateZone", Name: zoneName}, func(ctx context.Context) error {
+		return c.provider.CreateZone(ctx, zoneName)
+	})
+}
+
+func (c *extendedChain) DeleteZone(ctx context.Context, zoneName string) error {
+	return c.invoke(ctx, Call{Method: "DeleteZone", Name: zoneName}, func(ctx context.Context) error {
+		return c.provider.DeleteZone(ctx, zoneName)
+	})
+}
+
+func (c *extendedChain) ListZones(ctx context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	var zones backend.ProviderZoneListResponse
+	err := c.invoke(ctx, Call{Method: "ListZones", Idempotent: true}, func(ctx context.Context) error {
+		var err error
+		zones, err = c.provider.ListZones(ctx, options)
+		return err
+	})
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	return zones, nil
+}
+
+func (c *extendedChain) ListKeys(ctx context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	var keys backend.ProviderKeyListResponse
+	err := c.invoke(ctx, Call{Method: "ListKeys", Idempotent: true}, func(ctx context.Context) error {
+		var err error
+		keys, err = c.provider.ListKeys(ctx, options)
+		return err
+	})
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	return keys, nil
+}
+
+type watchingExtendedChain struct {
+	*extendedChain
+	watchProvider backend.WatchProvider
+}
+
+func (c *watchingExtendedChain) Watch(ctx context.Context) (<-chan backend.ChangeEvent, error) {
+	return c.watchProvider.Watch(ctx)
+}
+
+type maintenanceChain struct {
+	*extendedChain
+	maintenanceProvider backend.MaintenanceProvider
+}
+
+func (c *maintenanceChain) Backup(ctx context.Context, w io.Writer) (int64, error) {
+	return c.maintenanceProvider.Backup(ctx, w)
+}
+
+func (c *maintenanceChain) Compact(ctx context.Context) (backend.CompactResponse, error) {
+	return c.maintenanceProvider.Compact(ctx)
+}
+
+type watchingMaintenanceChain struct {
+	*maintenanceChain
+	watchProvider backend.WatchProvider
+}
+
+func (c *watchingMaintenanceChain) Watch(ctx context.Context) (<-chan backend.ChangeEvent, error) {
+	return c.watchProvider.Watch(ctx)
+}
@@ -0,0 +1,267 @@
+package middleware_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/backend/middleware"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+var logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// failingProvider fails the specified number of GetZone and SetZone calls before passing them on, and counts the
+// calls.
+type failingProvider struct {
+	backend.ExtendedProvider
+	failures atomic.Int64
+	calls    atomic.Int64
+	// hang, if true, makes GetZone wait until the context is canceled.
+	hang bool
+}
+
+func (p *failingProvider) fail(ctx context.Context) error {
+	p.calls.Add(1)
+	if p.hang {
+		<-ctx.Done()
+		return backend.ErrBackendRequestFailed.Wrap(ctx.Err())
+	}
+	if p.failures.Add(-1) >= 0 {
+		return backend.ErrBackendRequestFailed
+	}
+	return nil
+}
+
+func (p *failingProvider) GetZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	if err := p.fail(ctx); err != nil {
+		return backend.ProviderZoneResponse{}, err
+	}
+	return p.ExtendedProvider.GetZone(ctx, zoneName)
+}
+
+func (p *failingProvider) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	if err := p.fail(ctx); err != nil {
+		return err
+	}
+	return p.ExtendedProvider.SetZone(ctx, zoneName, acmeChallengeAnswers)
+}
+
+func newFailingProvider(t *testing.T, failures int64) *failingProvider {
+	t.Helper()
+	inner := inmemory.New(map[string]*backend.ProviderZoneResponse{}, map[string]*backend.ProviderKeyResponse{})
+	if err := inner.CreateZone(t.Context(), "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	provider := &failingProvider{ExtendedProvider: inner}
+	provider.failures.Store(failures)
+	return provider
+}
+
+func TestConformance(t *testing.T) {
+	config := middleware.Config{
+		Timeout:          time.Second,
+		Retries:          2,
+		RetryBackoff:     time.Millisecond,
+		RetryMaxBackoff:  time.Millisecond,
+		BreakerThreshold: 10,
+		BreakerOpenTime:  time.Second,
+	}
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		provider, err := inmemory.Config{}.BuildExtended(t.Context())
+		if err != nil {
+			t.Fatalf("Failed to build provider: %v", err)
+		}
+		return middleware.NewExtended(provider, config.Middlewares(logger)...)
+	})
+}
+
+func TestRetry(t *testing.T) {
+	ctx := t.Context()
+	retry := middleware.Retry(2, time.Millisecond, 2*time.Millisecond, logger)
+
+	// Idempotent requests are retried.
+	inner := newFailingProvider(t, 2)
+	provider := middleware.NewExtended(inner, retry)
+	if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if calls := inner.calls.Load(); calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+
+	// The error of the last attempt is returned.
+	inner = newFailingProvider(t, 3)
+	provider = middleware.NewExtended(inner, retry)
+	if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendRequestFailed) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+	}
+	if calls := inner.calls.Load(); calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", calls)
+	}
+
+	// SetZone increments the serial, so it is not retried.
+	inner = newFailingProvider(t, 1)
+	provider = middleware.NewExtended(inner, retry)
+	if err := provider.SetZone(ctx, "example.com", nil); !E.Is(err, backend.ErrBackendRequestFailed) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+
+	// Answers from the backend are not retried.
+	inner = newFailingProvider(t, 0)
+	provider = middleware.NewExtended(inner, retry)
+	if _, err := provider.GetZone(ctx, "example.org"); !E.Is(err, backend.ErrZoneNotInBackend) {
+		t.Fatalf("Expected %v, got %v", backend.ErrZoneNotInBackend, err)
+	}
+	if calls := inner.calls.Load(); calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", calls)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := t.Context()
+	const openTime = 50 * time.Millisecond
+	inner := newFailingProvider(t, 0)
+	provider := middleware.NewExtended(inner, middleware.CircuitBreaker(3, openTime, logger))
+
+	// Missing zones do not count as failures.
+	if _, err := provider.GetZone(ctx, "example.org"); !E.Is(err, backend.ErrZoneNotInBackend) {
+		t.Fatalf("Expected %v, got %v", backend.ErrZoneNotInBackend, err)
+	}
+	inner.failures.Store(4)
+	for range 3 {
+		if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendRequestFailed) {
+			t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+		}
+	}
+	// The breaker is open, requests fail without reaching the backend.
+	if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendUnavailable) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendUnavailable, err)
+	}
+	if calls := inner.calls.Load(); calls != 4 {
+		t.Fatalf("Expected 4 calls, got %d", calls)
+	}
+
+	// The test request after the open time fails, so the breaker opens again.
+	time.Sleep(openTime)
+	if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendRequestFailed) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+	}
+	if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendUnavailable) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendUnavailable, err)
+	}
+
+	// The backend has recovered, so the test request succeeds and closes the breaker.
+	time.Sleep(openTime)
+	for range 2 {
+		if _, err := provider.GetZone(ctx, "example.com"); err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+	}
+	if calls := inner.calls.Load(); calls != 7 {
+		t.Fatalf("Expected 7 calls, got %d", calls)
+	}
+}
+
+func TestCircuitBreaker_CanceledTest(t *testing.T) {
+	ctx := t.Context()
+	const openTime = 50 * time.Millisecond
+	inner := newFailingProvider(t, 10)
+	provider := middleware.NewExtended(inner, middleware.CircuitBreaker(2, openTime, logger))
+	for range 2 {
+		if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendRequestFailed) {
+			t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+		}
+	}
+
+	// The caller cancels the test request, which proves nothing about the backend.
+	time.Sleep(openTime)
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := provider.GetZone(canceledCtx, "example.com"); err == nil {
+		t.Fatalf("The canceled request did not fail")
+	}
+
+	// The breaker is still open, so the next request is the test request and its failure opens the breaker again.
+	if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendRequestFailed) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+	}
+	if _, err := provider.GetZone(ctx, "example.com"); !E.Is(err, backend.ErrBackendUnavailable) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendUnavailable, err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	inner := newFailingProvider(t, 0)
+	inner.hang = true
+	provider := middleware.NewExtended(
+		inner,
+		middleware.Retry(1, time.Millisecond, time.Millisecond, logger),
+		middleware.Timeout(10*time.Millisecond),
+	)
+	if _, err := provider.GetZone(t.Context(), "example.com"); !E.Is(err, backend.ErrBackendRequestFailed) {
+		t.Fatalf("Expected %v, got %v", backend.ErrBackendRequestFailed, err)
+	}
+	// Every attempt gets its own timeout.
+	if calls := inner.calls.Load(); calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", calls)
+	}
+}
+
+// maintenanceProvider adds backups and compaction to a provider.
+type maintenanceProvider struct {
+	backend.ExtendedProvider
+}
+
+func (p maintenanceProvider) Backup(_ context.Context, w io.Writer) (int64, error) {
+	n, err := w.Write([]byte("snapshot"))
+	return int64(n), err
+}
+
+func (p maintenanceProvider) Compact(_ context.Context) (backend.CompactResponse, error) {
+	return backend.CompactResponse{SizeBefore: 2, SizeAfter: 1}, nil
+}
+
+type watchingMaintenanceProvider struct {
+	maintenanceProvider
+	backend.WatchProvider
+}
+
+func TestMaintenance(t *testing.T) {
+	inner := newFailingProvider(t, 0)
+	if _, ok := middleware.NewExtended(inner).(backend.MaintenanceProvider); ok {
+		t.Fatalf("The chain supports maintenance although the wrapped provider does not")
+	}
+	provider, ok := middleware.NewExtended(maintenanceProvider{inner}).(backend.MaintenanceProvider)
+	if !ok {
+		t.Fatalf("The chain does not support maintenance although the wrapped provider does")
+	}
+	if _, err := provider.Backup(t.Context(), io.Discard); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+
+	// Providers reporting changes keep doing so.
+	watching, err := inmemory.Config{}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to build provider: %v", err)
+	}
+	chain := middleware.NewExtended(watchingMaintenanceProvider{
+		maintenanceProvider: maintenanceProvider{watching},
+		WatchProvider:       watching.(backend.WatchProvider),
+	})
+	if _, ok := chain.(backend.MaintenanceProvider); !ok {
+		t.Fatalf("The chain does not support maintenance although the wrapped provider does")
+	}
+	if _, ok := chain.(backend.WatchProvider); !ok {
+		t.Fatalf("The chain does not report changes although the wrapped provider does")
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// permanentErrors are the errors with which the backend has answered a request. Making the request again returns the
+// same error, so these requests are neither retried nor counted as backend failures.
+var permanentErrors = []E.Error{
+	backend.ErrKeyNotFoundInBackend,
+	backend.ErrZoneNotInBackend,
+	backend.ErrZoneAlreadyExistsInBackend,
+	backend.ErrObjectNotInBackend,
+	backend.ErrObjectBackendConflict,
+	backend.ErrInvalidListOptions,
+	backend.ErrInvalidAllowFrom,
+	backend.ErrConfiguration,
+}
+
+func isPermanent(err error) bool {
+	for _, permanentErr := range permanentErrors {
+		if E.Is(err, permanentErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry retries idempotent requests that failed with an error other than an answer from the backend, such as a
+// missing zone, up to retries times. It waits backoff before the first retry and doubles the wait for each further
+// retry up to maxBackoff. Each wait is shortened randomly by up to half, so replicas do not retry in lockstep.
+func Retry(retries int, backoff time.Duration, maxBackoff time.Duration, logger *slog.Logger) Middleware {
+	return func(ctx context.Context, call Call, next Handler) error {
+		err := next(ctx)
+		if !call.Idempotent {
+			return err
+		}
+		wait := backoff
+		for attempt := 1; attempt <= retries && err != nil && !isPermanent(err) && ctx.Err() == nil; attempt++ {
+			logger.DebugContext(
+				ctx,
+				"Retrying failed backend request.",
+				E.ToSLogAttr(err, slog.String("method", call.Method), slog.String("name", call.Name), slog.Int("attempt", attempt))...,
+			)
+			jitter := time.Duration(rand.Int64N(int64(wait/2) + 1)) //nolint:gosec // The jitter does not need to be unpredictable.
+			select {
+			case <-time.After(wait - jitter):
+			case <-ctx.Done():
+				return err
+			}
+			err = next(ctx)
+			wait = min(2*wait, maxBackoff)
+		}
+		return err
+	}
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"context"
+	"time"
+)
+
+// Timeout cancels each request after timeout. Placed after Retry, every attempt gets its own timeout.
+func Timeout(timeout time.Duration) Middleware {
+	return func(ctx context.Context, _ Call, next Handler) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return next(ctx)
+	}
+}
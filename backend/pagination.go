@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// Paginate returns the page of items selected by options and the continue token for the next page. The items must be
+// sorted by name. The continue token is the name of the last item returned, which keeps pagination stable when items
+// are added or removed between requests.
+func Paginate[T any](items []T, name func(item T) string, options ListOptions) ([]T, string, error) {
+	if options.Limit < 0 {
+		return nil, "", ErrInvalidListOptions.WithAttr(slog.Int("limit", options.Limit))
+	}
+	if options.Continue != "" {
+		start, _ := slices.BinarySearchFunc(items, options.Continue, func(item T, target string) int {
+			return strings.Compare(name(item), target)
+		})
+		for start < len(items) && name(items[start]) <= options.Continue {
+			start++
+		}
+		items = items[start:]
+	}
+	items, continueToken := Page(items, name, options)
+	return items, continueToken, nil
+}
+
+// QueryLimit validates options for backends selecting the page in their query, which return the items with names after
+// options.Continue. It returns the number of items to fetch, one more than requested to find out whether there is a
+// next page, or -1 if the number of items is not limited. Pass the items fetched to Page.
+func QueryLimit(options ListOptions) (int, error) {
+	if options.Limit < 0 {
+		return 0, ErrInvalidListOptions.WithAttr(slog.Int("limit", options.Limit))
+	}
+	if options.Limit == 0 {
+		return -1, nil
+	}
+	return options.Limit + 1, nil
+}
+
+// Page cuts the items after the first options.Limit, such as the extra item fetched because of QueryLimit, and returns
+// the continue token for the next page, or an empty string if there is none.
+func Page[T any](items []T, name func(item T) string, options ListOptions) ([]T, string) {
+	if options.Limit == 0 || len(items) <= options.Limit {
+		return items, ""
+	}
+	items = items[:options.Limit]
+	return items, name(items[len(items)-1])
+}
@@ -0,0 +1,81 @@
+package backend_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+func TestPaginate(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	identity := func(item string) string { return item }
+
+	var result []string
+	options := backend.ListOptions{Limit: 2}
+	for range len(items) {
+		page, continueToken, err := backend.Paginate(items, identity, options)
+		if err != nil {
+			t.Fatalf("Paginate failed: %v", err)
+		}
+		if len(page) > options.Limit {
+			t.Fatalf("Page too large: %v", page)
+		}
+		result = append(result, page...)
+		if continueToken == "" {
+			break
+		}
+		options.Continue = continueToken
+	}
+	if !slices.Equal(result, items) {
+		t.Fatalf("Incorrect items returned: %v", result)
+	}
+
+	// Items removed between requests must not cause items to be skipped.
+	page, _, err := backend.Paginate([]string{"a", "c", "d"}, identity, backend.ListOptions{Continue: "b"})
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if !slices.Equal(page, []string{"c", "d"}) {
+		t.Fatalf("Incorrect items returned after removal: %v", page)
+	}
+
+	if _, _, err := backend.Paginate(items, identity, backend.ListOptions{Limit: -1}); !E.Is(err, backend.ErrInvalidListOptions) {
+		t.Fatalf("Negative limit did not return an error: %v", err)
+	}
+}
+
+func TestQueryLimit(t *testing.T) {
+	identity := func(item string) string { return item }
+	for _, test := range []struct {
+		options  backend.ListOptions
+		limit    int
+		expected []string
+		next     string
+	}{
+		{backend.ListOptions{}, -1, []string{"a", "b", "c"}, ""},
+		{backend.ListOptions{Limit: 2}, 3, []string{"a", "b"}, "b"},
+		{backend.ListOptions{Limit: 3}, 4, []string{"a", "b", "c"}, ""},
+	} {
+		limit, err := backend.QueryLimit(test.options)
+		if err != nil {
+			t.Fatalf("QueryLimit failed: %v", err)
+		}
+		if limit != test.limit {
+			t.Fatalf("Incorrect limit for %v: %d", test.options, limit)
+		}
+		items := []string{"a", "b", "c"}
+		if limit >= 0 {
+			items = items[:min(limit, len(items))]
+		}
+		page, continueToken := backend.Page(items, identity, test.options)
+		if !slices.Equal(page, test.expected) || continueToken != test.next {
+			t.Fatalf("Incorrect page for %v: %v (continue token %q)", test.options, page, continueToken)
+		}
+	}
+
+	if _, err := backend.QueryLimit(backend.ListOptions{Limit: -1}); !E.Is(err, backend.ErrInvalidListOptions) {
+		t.Fatalf("Negative limit did not return an error: %v", err)
+	}
+}
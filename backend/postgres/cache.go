@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"strings"
+)
+
+// notificationChannel is the channel replicas notify each other on when they change a zone or an update key.
+const notificationChannel = "dns4acme"
+
+// Notification payloads are the kind of the changed object and its name, separated by a slash. A key notification
+// without a name invalidates all keys, which is sent when a zone is deleted and its key bindings are removed with it.
+const (
+	notifyZone = "zone"
+	notifyKey  = "key"
+)
+
+func zoneNotification(zoneName string) string {
+	return notifyZone + "/" + zoneName
+}
+
+func keyNotification(keyName string) string {
+	return notifyKey + "/" + keyName
+}
+
+// invalidate removes the object described by a notification payload from the cache.
+func invalidate(c *cache.Notified, payload string) {
+	kind, name, _ := strings.Cut(payload, "/")
+	switch {
+	case kind == notifyZone:
+		c.InvalidateZone(name)
+	case kind == notifyKey && name != "":
+		c.InvalidateKey(name)
+	case kind == notifyKey:
+		c.InvalidateKeys()
+	default:
+		// Unknown payloads come from newer versions. Drop everything to be safe.
+		c.InvalidateAll()
+	}
+}
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/cache"
+)
+
+func TestInvalidate(t *testing.T) {
+	c := cache.NewNotified()
+	c.SetValid(true)
+	reads := map[string]int{}
+	readZone := func(_ context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+		reads[zoneName]++
+		return backend.ProviderZoneResponse{}, nil
+	}
+	readKey := func(_ context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+		reads[keyName]++
+		return backend.ProviderKeyResponse{}, nil
+	}
+	// fill caches the zone and both keys, so that reads counts the reads after the last invalidation.
+	fill := func() {
+		clear(reads)
+		for _, name := range []string{"certbot", "lego"} {
+			if _, err := c.GetKey(t.Context(), name, readKey); err != nil {
+				t.Fatalf("Failed to get key: %v", err)
+			}
+		}
+		if _, err := c.GetZone(t.Context(), "example.com", readZone); err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+	}
+	for _, test := range []struct {
+		payload string
+		removed []string
+	}{
+		{zoneNotification("example.com"), []string{"example.com"}},
+		{keyNotification("certbot"), []string{"certbot"}},
+		{keyNotification(""), []string{"certbot", "lego"}},
+		{"unknown/example.com", []string{"certbot", "example.com", "lego"}},
+	} {
+		fill()
+		invalidate(c, test.payload)
+		fill()
+		for _, name := range []string{"certbot", "example.com", "lego"} {
+			if slices.Contains(test.removed, name) != (reads[name] == 1) {
+				t.Fatalf("Incorrect invalidation of %s by the notification %q, %d reads", name, test.payload, reads[name])
+			}
+		}
+	}
+}
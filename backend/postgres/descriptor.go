@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+type descriptor struct {
+}
+
+func (d descriptor) Name() string {
+	return "PostgreSQL"
+}
+
+func (d descriptor) Description() string {
+	return "Backend that stores zones and update keys in a PostgreSQL database shared by multiple DNS4ACME replicas."
+}
+
+func (d descriptor) Config() backend.Config {
+	return &Config{}
+}
@@ -0,0 +1,6 @@
+package postgres
+
+import "github.com/dns4acme/dns4acme/lang/E"
+
+var ErrMigrationFailed = E.New("POSTGRES_MIGRATION_FAILED", "failed to migrate the PostgreSQL database schema")
+var ErrSchemaTooNew = E.New("POSTGRES_SCHEMA_TOO_NEW", "the PostgreSQL database was created by a newer version of DNS4ACME")
@@ -0,0 +1,3 @@
+package postgres
+
+const ID = "postgres"
@@ -0,0 +1,7 @@
+package postgres
+
+import "github.com/dns4acme/dns4acme/backend/registry"
+
+func init() {
+	registry.Backends[ID] = &descriptor{}
+}
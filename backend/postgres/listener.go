@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"time"
+)
+
+// listenerRetryInterval is the time to wait before reconnecting after the notification connection is lost.
+const listenerRetryInterval = 5 * time.Second
+
+// listener keeps a dedicated connection outside the pool that listens for the notifications of other replicas and
+// invalidates the cache accordingly.
+type listener struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startListener(connConfig *pgx.ConnConfig, c *cache.Notified, logger *slog.Logger) *listener {
+	// The listener runs until the provider is closed, so it must not use the context passed to Build.
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &listener{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(l.done)
+		for {
+			err := listen(ctx, connConfig, c)
+			c.SetValid(false)
+			if ctx.Err() != nil {
+				return
+			}
+			logger.WarnContext(
+				ctx,
+				"PostgreSQL notification connection lost, caching is disabled until it is restored",
+				E.ToSLogAttr(err)...,
+			)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(listenerRetryInterval):
+			}
+		}
+	}()
+	return l
+}
+
+// listen connects to the database and invalidates the cache on every notification until the connection fails.
+func listen(ctx context.Context, connConfig *pgx.ConnConfig, c *cache.Notified) error {
+	conn, err := pgx.ConnectConfig(ctx, connConfig.Copy())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx) //nolint:contextcheck // The listener context is already canceled on shutdown.
+	}()
+	if _, err := conn.Exec(ctx, `LISTEN `+notificationChannel); err != nil {
+		return err
+	}
+	// Changes made before LISTEN took effect were never cached, so the cache can start out empty.
+	c.SetValid(true)
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		invalidate(c, notification.Payload)
+	}
+}
+
+func (l *listener) stop() {
+	l.cancel()
+	<-l.done
+}
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log/slog"
+)
+
+// migrationLock is the key of the advisory lock held while migrating, so replicas starting at the same time do not
+// apply the same migration twice. The value is arbitrary, but must never change.
+const migrationLock = 0x646e7334616d65
+
+// migrations contains the schema changes in order. The number of migrations applied is stored in the schema_version
+// table. Never change a migration that has been released, add a new one instead.
+//
+// Names use the C collation so that they are ordered byte by byte, which the continue tokens of list requests rely on.
+var migrations = []string{
+	`CREATE TABLE zones (
+		name    TEXT COLLATE "C" NOT NULL PRIMARY KEY,
+		serial  BIGINT  NOT NULL DEFAULT 0,
+		answers TEXT[]  NOT NULL DEFAULT '{}',
+		debug   BOOLEAN NOT NULL DEFAULT FALSE
+	);
+	CREATE TABLE keys (
+		name       TEXT COLLATE "C" NOT NULL PRIMARY KEY,
+		secret     TEXT   NOT NULL,
+		allow_from TEXT[] NOT NULL DEFAULT '{}'
+	);
+	CREATE TABLE key_bindings (
+		key_name  TEXT COLLATE "C" NOT NULL REFERENCES keys (name) ON DELETE CASCADE,
+		zone_name TEXT COLLATE "C" NOT NULL REFERENCES zones (name) ON DELETE CASCADE,
+		PRIMARY KEY (key_name, zone_name)
+	);
+	CREATE INDEX key_bindings_zone_name ON key_bindings (zone_name);`,
+}
+
+// migrate applies the migrations missing from the database in a single transaction. PostgreSQL supports transactional
+// DDL, so a failed migration leaves the schema untouched.
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return ErrMigrationFailed.Wrap(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+		return ErrMigrationFailed.Wrap(err)
+	}
+	if _, err := tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return ErrMigrationFailed.Wrap(err)
+	}
+	var version int
+	if err := tx.QueryRow(ctx, `SELECT version FROM schema_version`).Scan(&version); err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return ErrMigrationFailed.Wrap(err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_version (version) VALUES (0)`); err != nil {
+			return ErrMigrationFailed.Wrap(err)
+		}
+	}
+	if version > len(migrations) {
+		return ErrSchemaTooNew.WithAttr(slog.Int("version", version)).WithAttr(slog.Int("supported_version", len(migrations)))
+	}
+	if version == len(migrations) {
+		return nil
+	}
+	for ; version < len(migrations); version++ {
+		if _, err := tx.Exec(ctx, migrations[version]); err != nil {
+			return ErrMigrationFailed.Wrap(err).WithAttr(slog.Int("version", version+1))
+		}
+	}
+	if _, err := tx.Exec(ctx, `UPDATE schema_version SET version = $1`, version); err != nil {
+		return ErrMigrationFailed.Wrap(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return ErrMigrationFailed.Wrap(err)
+	}
+	return nil
+}
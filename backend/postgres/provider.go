@@ -0,0 +1,322 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// keyColumns selects an update key with its zone bindings as an array ordered by zone name.
+const keyColumns = `keys.name, keys.secret, keys.allow_from, ARRAY(
+	SELECT zone_name FROM key_bindings WHERE key_name = keys.name ORDER BY zone_name
+)`
+
+type provider struct {
+	pool *pgxpool.Pool
+	// cache and listener are nil if caching is turned off.
+	cache    *cache.Notified
+	listener *listener
+}
+
+func (p *provider) GetKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	if p.cache == nil {
+		return p.getKey(ctx, keyName)
+	}
+	return p.cache.GetKey(ctx, keyName, p.getKey)
+}
+
+func (p *provider) getKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	row := p.pool.QueryRow(ctx, `SELECT `+keyColumns+` FROM keys WHERE name = $1`, keyName)
+	var name string
+	var key backend.ProviderKeyResponse
+	if err := scanKey(row, &name, &key); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return backend.ProviderKeyResponse{}, backend.ErrKeyNotFoundInBackend
+		}
+		return backend.ProviderKeyResponse{}, backend.RequestFailed(err, "key", keyName)
+	}
+	return key, nil
+}
+
+func (p *provider) GetZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	if p.cache == nil {
+		return p.getZone(ctx, zoneName)
+	}
+	return p.cache.GetZone(ctx, zoneName, p.getZone)
+}
+
+func (p *provider) getZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	row := p.pool.QueryRow(ctx, `SELECT name, serial, answers, debug FROM zones WHERE name = $1`, zoneName)
+	var name string
+	var zone backend.ProviderZoneResponse
+	if err := scanZone(row, &name, &zone); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return backend.ProviderZoneResponse{}, backend.ErrZoneNotInBackend
+		}
+		return backend.ProviderZoneResponse{}, backend.RequestFailed(err, "zone", zoneName)
+	}
+	return zone, nil
+}
+
+func (p *provider) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		// The row lock makes replicas updating the same zone wait for each other, so no two updates get the same
+		// serial.
+		var serial int64
+		if err := tx.QueryRow(ctx, `SELECT serial FROM zones WHERE name = $1 FOR UPDATE`, zoneName).Scan(&serial); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return backend.ErrZoneNotInBackend
+			}
+			return backend.RequestFailed(err, "zone", zoneName)
+		}
+		// The serial wraps around like an RFC 1982 serial number.
+		nextSerial := uint32(serial) + 1 //nolint:gosec // The serial is kept in the uint32 range by SetZone.
+		if _, err := tx.Exec(
+			ctx,
+			`UPDATE zones SET serial = $1, answers = $2 WHERE name = $3`,
+			int64(nextSerial),
+			backend.NonNil(acmeChallengeAnswers),
+			zoneName,
+		); err != nil {
+			return backend.RequestFailed(err, "zone", zoneName)
+		}
+		return nil
+	}, zoneNotification(zoneName))
+}
+
+func (p *provider) SetZoneDebug(ctx context.Context, zoneName string, debug bool) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE zones SET debug = $1 WHERE name = $2`, debug, zoneName)
+		if err != nil {
+			return backend.RequestFailed(err, "zone", zoneName)
+		}
+		return requireAffected(tag, backend.ErrZoneNotInBackend)
+	}, zoneNotification(zoneName))
+}
+
+func (p *provider) CreateKey(ctx context.Context, keyName string, secret string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `INSERT INTO keys (name, secret) VALUES ($1, $2) ON CONFLICT DO NOTHING`, keyName, secret)
+		if err != nil {
+			return backend.RequestFailed(err, "key", keyName)
+		}
+		return requireAffected(tag, backend.ErrObjectBackendConflict)
+	}, keyNotification(keyName))
+}
+
+func (p *provider) DeleteKey(ctx context.Context, keyName string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM keys WHERE name = $1`, keyName)
+		if err != nil {
+			return backend.RequestFailed(err, "key", keyName)
+		}
+		return requireAffected(tag, backend.ErrObjectNotInBackend)
+	}, keyNotification(keyName))
+}
+
+func (p *provider) SetKeySecret(ctx context.Context, keyName string, secret string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE keys SET secret = $1 WHERE name = $2`, secret, keyName)
+		if err != nil {
+			return backend.RequestFailed(err, "key", keyName)
+		}
+		return requireAffected(tag, backend.ErrObjectNotInBackend)
+	}, keyNotification(keyName))
+}
+
+func (p *provider) SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE keys SET allow_from = $1 WHERE name = $2`, backend.NonNil(allowFrom), keyName)
+		if err != nil {
+			return backend.RequestFailed(err, "key", keyName)
+		}
+		return requireAffected(tag, backend.ErrObjectNotInBackend)
+	}, keyNotification(keyName))
+}
+
+func (p *provider) BindKey(ctx context.Context, keyName string, zoneName string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		// The shared locks keep the key and the zone from being deleted before the binding is inserted.
+		row := tx.QueryRow(ctx, `SELECT 1 FROM keys WHERE name = $1 FOR KEY SHARE`, keyName)
+		if err := backend.RequireRow(row.Scan, pgx.ErrNoRows, backend.ErrObjectNotInBackend, "key", keyName); err != nil {
+			return err
+		}
+		row = tx.QueryRow(ctx, `SELECT 1 FROM zones WHERE name = $1 FOR KEY SHARE`, zoneName)
+		if err := backend.RequireRow(row.Scan, pgx.ErrNoRows, backend.ErrZoneNotInBackend, "zone", zoneName); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			ctx,
+			`INSERT INTO key_bindings (key_name, zone_name) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			keyName,
+			zoneName,
+		); err != nil {
+			return backend.RequestFailed(err, "key", keyName)
+		}
+		return nil
+	}, keyNotification(keyName))
+}
+
+func (p *provider) UnbindKey(ctx context.Context, keyName string, zoneName string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM key_bindings WHERE key_name = $1 AND zone_name = $2`, keyName, zoneName)
+		if err != nil {
+			return backend.RequestFailed(err, "key", keyName)
+		}
+		return requireAffected(tag, backend.ErrObjectNotInBackend)
+	}, keyNotification(keyName))
+}
+
+func (p *provider) CreateZone(ctx context.Context, zoneName string) error {
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `INSERT INTO zones (name) VALUES ($1) ON CONFLICT DO NOTHING`, zoneName)
+		if err != nil {
+			return backend.RequestFailed(err, "zone", zoneName)
+		}
+		return requireAffected(tag, backend.ErrObjectBackendConflict)
+	}, zoneNotification(zoneName))
+}
+
+func (p *provider) DeleteZone(ctx context.Context, zoneName string) error {
+	// The key bindings of the zone are removed by the foreign key, which changes the zones of every key bound to it.
+	return p.transaction(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM zones WHERE name = $1`, zoneName)
+		if err != nil {
+			return backend.RequestFailed(err, "zone", zoneName)
+		}
+		return requireAffected(tag, backend.ErrZoneNotInBackend)
+	}, zoneNotification(zoneName), keyNotification(""))
+}
+
+func (p *provider) ListZones(ctx context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	limit, err := backend.QueryLimit(options)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	// QueryLimit returns -1 if the number of zones is not limited, which is LIMIT NULL in PostgreSQL.
+	rows, err := p.pool.Query(
+		ctx,
+		`SELECT name, serial, answers, debug FROM zones WHERE name > $1 ORDER BY name LIMIT NULLIF($2::bigint, -1)`,
+		options.Continue,
+		limit,
+	)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	defer rows.Close()
+	var zones []backend.ProviderZoneListItem
+	for rows.Next() {
+		var zone backend.ProviderZoneListItem
+		if err := scanZone(rows, &zone.Name, &zone.ProviderZoneResponse); err != nil {
+			return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "", "")
+		}
+		zones = append(zones, zone)
+	}
+	if err := rows.Err(); err != nil {
+		return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	zones, continueToken := backend.Page(zones, func(zone backend.ProviderZoneListItem) string {
+		return zone.Name
+	}, options)
+	return backend.ProviderZoneListResponse{Zones: zones, Continue: continueToken}, nil
+}
+
+func (p *provider) ListKeys(ctx context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	limit, err := backend.QueryLimit(options)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	rows, err := p.pool.Query(
+		ctx,
+		`SELECT `+keyColumns+` FROM keys WHERE name > $1 ORDER BY name LIMIT NULLIF($2::bigint, -1)`,
+		options.Continue,
+		limit,
+	)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	defer rows.Close()
+	var keys []backend.ProviderKeyListItem
+	for rows.Next() {
+		var name string
+		var key backend.ProviderKeyResponse
+		if err := scanKey(rows, &name, &key); err != nil {
+			return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "", "")
+		}
+		keys = append(keys, backend.ProviderKeyListItem{Name: name, Zones: key.Zones, AllowFrom: key.AllowFrom})
+	}
+	if err := rows.Err(); err != nil {
+		return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	keys, continueToken := backend.Page(keys, func(key backend.ProviderKeyListItem) string {
+		return key.Name
+	}, options)
+	return backend.ProviderKeyListResponse{Keys: keys, Continue: continueToken}, nil
+}
+
+func (p *provider) Close(_ context.Context) error {
+	if p.listener != nil {
+		p.listener.stop()
+	}
+	p.pool.Close()
+	return nil
+}
+
+// transaction runs f in a transaction and commits it if f succeeds. The notifications are sent to the other replicas
+// when the transaction commits, and the local cache is invalidated right after.
+func (p *provider) transaction(ctx context.Context, f func(tx pgx.Tx) error, notifications ...string) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return backend.RequestFailed(err, "", "")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if err := f(tx); err != nil {
+		return err
+	}
+	for _, notification := range notifications {
+		if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, notificationChannel, notification); err != nil {
+			return backend.RequestFailed(err, "", "")
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return backend.RequestFailed(err, "", "")
+	}
+	if p.cache != nil {
+		for _, notification := range notifications {
+			invalidate(p.cache, notification)
+		}
+	}
+	return nil
+}
+
+func scanKey(row pgx.Row, name *string, key *backend.ProviderKeyResponse) error {
+	if err := row.Scan(name, &key.Secret, &key.AllowFrom, &key.Zones); err != nil {
+		return err
+	}
+	key.AllowFrom = backend.NilIfEmpty(key.AllowFrom)
+	key.Zones = backend.NilIfEmpty(key.Zones)
+	return nil
+}
+
+func scanZone(row pgx.Row, name *string, zone *backend.ProviderZoneResponse) error {
+	var serial int64
+	if err := row.Scan(name, &serial, &zone.ACMEChallengeAnswers, &zone.Debug); err != nil {
+		return err
+	}
+	zone.Serial = uint32(serial) //nolint:gosec // The serial is kept in the uint32 range by SetZone.
+	zone.ACMEChallengeAnswers = backend.NilIfEmpty(zone.ACMEChallengeAnswers)
+	return nil
+}
+
+func requireAffected(tag pgconn.CommandTag, notAffected error) error {
+	if tag.RowsAffected() == 0 {
+		return notAffected
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package postgres_test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/postgres"
+)
+
+// dsnEnv names the environment variable with the connection string of the test database. The tests delete all data
+// in that database.
+const dsnEnv = "DNS4ACME_TEST_POSTGRES_DSN"
+
+// resetDatabase skips the test if no test database is configured, otherwise it empties the database.
+func resetDatabase(t *testing.T) string {
+	t.Helper()
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set, skipping PostgreSQL tests.", dsnEnv)
+	}
+	// Opening a provider creates the schema.
+	provider := open(t, dsn)
+	_ = provider.Close(t.Context())
+
+	conn, err := pgx.Connect(t.Context(), dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to the test database: %v", err)
+	}
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+	if _, err := conn.Exec(t.Context(), `TRUNCATE key_bindings, keys, zones`); err != nil {
+		t.Fatalf("Failed to empty the test database: %v", err)
+	}
+	return dsn
+}
+
+func open(t *testing.T, dsn string) backend.ExtendedProvider {
+	t.Helper()
+	provider, err := postgres.Config{DSN: dsn, MaxConnections: 4, Cache: true}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Close(context.Background())
+	})
+	return provider
+}
+
+func TestProviderBypassCache(t *testing.T) {
+	dsn := resetDatabase(t)
+	// Two providers on the same database behave like two DNS4ACME replicas.
+	writer := open(t, dsn)
+	reader := open(t, dsn)
+	ctx := t.Context()
+	if err := writer.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		t.Fatalf("Failed to connect to the test database: %v", err)
+	}
+	defer func() {
+		_ = conn.Close(context.Background())
+	}()
+	// Changing the data without a notification shows whether the zone is served from the cache, which only happens
+	// once the listener is connected.
+	setDebug := func(debug bool) {
+		if _, err := conn.Exec(ctx, `UPDATE zones SET debug = $1 WHERE name = 'example.com'`, debug); err != nil {
+			t.Fatalf("Failed to update the zone: %v", err)
+		}
+	}
+	backendtest.Eventually(t, func() bool {
+		if _, err := reader.GetZone(ctx, "example.com"); err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		setDebug(true)
+		cached, err := reader.GetZone(ctx, "example.com")
+		setDebug(false)
+		return err == nil && !cached.Debug
+	})
+
+	// The writer's change is made without a notification, as if the notification was still on its way.
+	if _, err := conn.Exec(
+		ctx,
+		`UPDATE zones SET serial = serial + 1, answers = $1 WHERE name = 'example.com'`,
+		[]string{"writer"},
+	); err != nil {
+		t.Fatalf("Failed to update the zone: %v", err)
+	}
+	if zone, err := reader.GetZone(ctx, "example.com"); err != nil || len(zone.ACMEChallengeAnswers) != 0 {
+		t.Fatalf("Expected the cached zone, got %v (%v)", zone, err)
+	}
+	zone, err := reader.GetZone(backend.BypassCache(ctx), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zone.ACMEChallengeAnswers, []string{"writer"}) {
+		t.Fatalf("Incorrect answers when bypassing the cache: %v", zone.ACMEChallengeAnswers)
+	}
+	if err := reader.SetZone(ctx, "example.com", append(zone.ACMEChallengeAnswers, "reader")); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	zone, err = writer.GetZone(backend.BypassCache(ctx), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zone.ACMEChallengeAnswers, []string{"writer", "reader"}) {
+		t.Fatalf("Incorrect answers after both replicas updated the zone: %v", zone.ACMEChallengeAnswers)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		return open(t, resetDatabase(t))
+	})
+	sharedDatabase := func(t *testing.T) backendtest.ExtendedProviderFactory {
+		dsn := resetDatabase(t)
+		return func(t *testing.T) backend.ExtendedProvider {
+			return open(t, dsn)
+		}
+	}
+	backendtest.RunPersistent(t, sharedDatabase)
+	backendtest.RunReplicas(t, sharedDatabase)
+}
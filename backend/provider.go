@@ -17,11 +17,21 @@ type ExtendedProvider interface {
 	BindKey(ctx context.Context, keyName string, zoneName string) error
 	// UnbindKey removes all key bindings between a specific zone and an update key.
 	UnbindKey(ctx context.Context, keyName string, zoneName string) error
+	// SetKeyAllowFrom restricts the networks the update key may be used from to the specified CIDR prefixes. An empty
+	// list allows all networks.
+	SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error
 
 	// CreateZone registers a new zone with the specified name.
 	CreateZone(ctx context.Context, zoneName string) error
 	// DeleteZone deletes a zone with the specified name.
 	DeleteZone(ctx context.Context, zoneName string) error
+
+	// ListZones returns a page of zones ordered by name. Pass the Continue token of the response in the next call to
+	// fetch the next page.
+	ListZones(ctx context.Context, options ListOptions) (ProviderZoneListResponse, error)
+	// ListKeys returns a page of update keys ordered by name, including the zones they are bound to. Secrets are not
+	// included. Pass the Continue token of the response in the next call to fetch the next page.
+	ListKeys(ctx context.Context, options ListOptions) (ProviderKeyListResponse, error)
 }
 
 // Provider defines the baseline functionality a backend must implement.
@@ -45,6 +55,8 @@ type Provider interface {
 type ProviderKeyResponse struct {
 	Secret string
 	Zones  []string
+	// AllowFrom contains the CIDR prefixes the key may be used from. Empty means the key may be used from anywhere.
+	AllowFrom []string
 }
 
 // ProviderZoneResponse defines the fields a Provider needs to fill when returning a zone.
@@ -53,3 +65,38 @@ type ProviderZoneResponse struct {
 	ACMEChallengeAnswers []string
 	Debug                bool
 }
+
+// ListOptions controls the pagination of list requests.
+type ListOptions struct {
+	// Limit is the maximum number of items to return. 0 means no limit.
+	Limit int
+	// Continue is the token returned in the previous response. Leave empty to fetch the first page.
+	Continue string
+}
+
+// ProviderZoneListResponse is a single page of zones.
+type ProviderZoneListResponse struct {
+	Zones []ProviderZoneListItem
+	// Continue is the token to fetch the next page with. It is empty if this is the last page.
+	Continue string
+}
+
+// ProviderZoneListItem is a single zone in a ProviderZoneListResponse.
+type ProviderZoneListItem struct {
+	Name string
+	ProviderZoneResponse
+}
+
+// ProviderKeyListResponse is a single page of update keys.
+type ProviderKeyListResponse struct {
+	Keys []ProviderKeyListItem
+	// Continue is the token to fetch the next page with. It is empty if this is the last page.
+	Continue string
+}
+
+// ProviderKeyListItem is a single update key in a ProviderKeyListResponse.
+type ProviderKeyListItem struct {
+	Name      string
+	Zones     []string
+	AllowFrom []string
+}
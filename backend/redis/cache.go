@@ -0,0 +1,16 @@
+package redis
+
+import (
+	"github.com/dns4acme/dns4acme/backend/cache"
+)
+
+// invalidate removes the object stored under the specified Redis key, relative to the prefix, from the cache.
+func invalidate(c *cache.Notified, redisKey string) {
+	kind, name := parseKey(redisKey)
+	switch kind {
+	case zoneHash, answersString:
+		c.InvalidateZone(name)
+	case keyHash, keyZonesSet:
+		c.InvalidateKey(name)
+	}
+}
@@ -0,0 +1,196 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/dns4acme/dns4acme/lang/E"
+	goredis "github.com/redis/go-redis/v9"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Addresses []string `config:"addresses" default:"localhost:6379" description:"Comma-separated list of Redis addresses. With Sentinel, these are the addresses of the Sentinels, with Redis Cluster, the addresses of some of the cluster nodes."`
+	Username  string   `config:"username" file:"true" description:"Username for authenticating to Redis."`
+	Password  string   `config:"password" sensitive:"true" file:"true" description:"Password for authenticating to Redis."`
+	DB        int      `config:"db" default:"0" description:"Redis database number. Must be 0 with Redis Cluster."`
+
+	SentinelMaster   string `config:"sentinel-master" description:"Name of the master to connect to through the Sentinels listed in addresses. Turns on Sentinel mode."`
+	SentinelPassword string `config:"sentinel-password" sensitive:"true" file:"true" description:"Password for authenticating to the Sentinels."`
+	Cluster          bool   `config:"cluster" default:"false" description:"Connect to a Redis Cluster."`
+
+	TLS    bool   `config:"tls" default:"false" description:"Connect to Redis using TLS."`
+	CAFile string `config:"cacert-file" description:"File containing the PEM-encoded CA certificate to verify the connection to Redis. Uses the system CAs if empty."`
+
+	Prefix      string        `config:"prefix" default:"{dns4acme}:" description:"Prefix of the Redis keys zones and update keys are stored under. The braces make Redis Cluster store all keys in the same slot, which the atomic updates require."`
+	AnswerTTL   time.Duration `config:"answer-ttl" default:"0s" description:"Expire ACME challenge answers after this time if the ACME client does not remove them. 0 keeps them until they are removed."`
+	Cache       bool          `config:"cache" default:"false" description:"Cache zones and update keys in memory and invalidate them using keyspace notifications. Requires notify-keyspace-events to include K and A. Not available with Redis Cluster."`
+	DialTimeout time.Duration `config:"dial-timeout" default:"5s" description:"Maximum time to wait for a connection to Redis."`
+
+	Logger *slog.Logger `json:"-"`
+}
+
+// WithLogger returns a copy of the configuration logging to logger.
+func (c Config) WithLogger(logger *slog.Logger) backend.Config {
+	c.Logger = logger
+	return &c
+}
+
+func (c Config) Build(ctx context.Context) (backend.Provider, error) {
+	return c.BuildExtended(ctx)
+}
+
+func (c Config) BuildExtended(ctx context.Context) (backend.ExtendedProvider, error) {
+	if len(c.Addresses) == 0 {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("at least one Redis address is required"))
+	}
+	if c.SentinelMaster != "" && c.Cluster {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the sentinel master and cluster mode cannot be used together"))
+	}
+	if c.Cluster && c.DB != 0 {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("only database 0 is available with Redis Cluster"))
+	}
+	if c.Cluster && !hasHashTag(c.Prefix) {
+		return nil, backend.ErrConfiguration.Wrap(
+			fmt.Errorf("the key prefix must contain a hash tag like {dns4acme} with Redis Cluster"),
+		).WithAttr(slog.String("prefix", c.Prefix))
+	}
+	if c.AnswerTTL < 0 {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the answer TTL must not be negative"))
+	}
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, backend.ErrConfiguration.Wrap(err)
+	}
+
+	logger := c.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	logger = logger.WithGroup("redis")
+
+	client := c.client(tlsConfig)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, backend.ErrBackendRequestFailed.Wrap(err)
+	}
+	p := &provider{
+		client:    client,
+		prefix:    c.Prefix,
+		answerTTL: c.AnswerTTL,
+	}
+	if c.Cache {
+		if c.Cluster {
+			// Keyspace notifications are only published by the node holding the key, so a single subscription would
+			// miss changes after a resharding or failover.
+			logger.InfoContext(ctx, "Caching is not available with Redis Cluster and has been turned off")
+		} else {
+			if err := checkKeyspaceNotifications(ctx, client, logger); err != nil {
+				_ = client.Close()
+				return nil, err
+			}
+			p.cache = cache.NewNotified()
+			p.listener = startListener(client, c.DB, c.Prefix, p.cache, logger)
+		}
+	}
+	return p, nil
+}
+
+func (c Config) client(tlsConfig *tls.Config) goredis.UniversalClient {
+	switch {
+	case c.SentinelMaster != "":
+		return goredis.NewFailoverClient(&goredis.FailoverOptions{
+			MasterName:       c.SentinelMaster,
+			SentinelAddrs:    c.Addresses,
+			SentinelPassword: c.SentinelPassword,
+			Username:         c.Username,
+			Password:         c.Password,
+			DB:               c.DB,
+			DialTimeout:      c.DialTimeout,
+			TLSConfig:        tlsConfig,
+		})
+	case c.Cluster:
+		return goredis.NewClusterClient(&goredis.ClusterOptions{
+			Addrs:       c.Addresses,
+			Username:    c.Username,
+			Password:    c.Password,
+			DialTimeout: c.DialTimeout,
+			TLSConfig:   tlsConfig,
+		})
+	default:
+		return goredis.NewClient(&goredis.Options{
+			Addr:        c.Addresses[0],
+			Username:    c.Username,
+			Password:    c.Password,
+			DB:          c.DB,
+			DialTimeout: c.DialTimeout,
+			TLSConfig:   tlsConfig,
+		})
+	}
+}
+
+func (c Config) tlsConfig() (*tls.Config, error) {
+	if !c.TLS {
+		return nil, nil //nolint:nilnil // No TLS configuration means a plain text connection.
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// checkKeyspaceNotifications makes sure Redis publishes the keyspace notifications the cache relies on. Managed Redis
+// services often disable the CONFIG command, in which case the setting cannot be checked and a warning is logged.
+func checkKeyspaceNotifications(ctx context.Context, client goredis.UniversalClient, logger *slog.Logger) error {
+	values, err := client.ConfigGet(ctx, "notify-keyspace-events").Result()
+	if err != nil {
+		logger.WarnContext(
+			ctx,
+			"Cannot check whether keyspace notifications are enabled, make sure notify-keyspace-events includes K and A",
+			E.ToSLogAttr(err)...,
+		)
+		return nil
+	}
+	flags := values["notify-keyspace-events"]
+	if strings.Contains(flags, "K") && (strings.Contains(flags, "A") || containsAll(flags, "g$shx")) {
+		return nil
+	}
+	return backend.ErrConfiguration.Wrap(
+		fmt.Errorf("keyspace notifications are required for caching, set notify-keyspace-events to KA or turn off caching"),
+	).WithAttr(slog.String("notify_keyspace_events", flags))
+}
+
+func containsAll(flags string, required string) bool {
+	for _, flag := range required {
+		if !strings.ContainsRune(flags, flag) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasHashTag returns true if the prefix contains a non-empty hash tag, which makes Redis Cluster store all keys with
+// the prefix in the same hash slot.
+func hasHashTag(prefix string) bool {
+	_, rest, found := strings.Cut(prefix, "{")
+	if !found {
+		return false
+	}
+	end := strings.Index(rest, "}")
+	return end > 0
+}
@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+type descriptor struct {
+}
+
+func (d descriptor) Name() string {
+	return "Redis"
+}
+
+func (d descriptor) Description() string {
+	return "Backend that stores zones and update keys in Redis, including Sentinel and cluster deployments."
+}
+
+func (d descriptor) Config() backend.Config {
+	return &Config{}
+}
@@ -0,0 +1,3 @@
+package redis
+
+const ID = "redis"
@@ -0,0 +1,120 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/dns4acme/dns4acme/lang/E"
+	goredis "github.com/redis/go-redis/v9"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// listenerRetryInterval is the time to wait before subscribing again after the subscription failed.
+	listenerRetryInterval = 5 * time.Second
+	// listenerPingInterval is the time without notifications after which the subscription connection is checked.
+	listenerPingInterval = 30 * time.Second
+)
+
+// listener subscribes to the keyspace notifications of the keys under the prefix and invalidates the cache
+// accordingly, which covers the changes made by other replicas as well as expired challenge answers.
+type listener struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func startListener(client goredis.UniversalClient, db int, prefix string, c *cache.Notified, logger *slog.Logger) *listener {
+	// The listener runs until the provider is closed, so it must not use the context passed to Build.
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &listener{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	channelPrefix := "__keyspace@" + strconv.Itoa(db) + "__:" + prefix
+	go func() {
+		defer close(l.done)
+		for {
+			err := listen(ctx, client, channelPrefix, c)
+			c.SetValid(false)
+			if ctx.Err() != nil {
+				return
+			}
+			logger.WarnContext(
+				ctx,
+				"Redis keyspace notification subscription lost, caching is disabled until it is restored",
+				E.ToSLogAttr(err)...,
+			)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(listenerRetryInterval):
+			}
+		}
+	}()
+	return l
+}
+
+// listen subscribes to the keyspace notifications and invalidates the cache on every notification until the
+// subscription fails.
+func listen(ctx context.Context, client goredis.UniversalClient, channelPrefix string, c *cache.Notified) error {
+	pubsub := client.PSubscribe(ctx, escapePattern(channelPrefix)+"*")
+	defer func() {
+		_ = pubsub.Close()
+	}()
+	// Closing the subscription is the only way to interrupt a pending receive.
+	stop := context.AfterFunc(ctx, func() {
+		_ = pubsub.Close()
+	})
+	defer stop()
+
+	pingPending := false
+	for {
+		message, err := pubsub.ReceiveTimeout(ctx, listenerPingInterval)
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				return err
+			}
+			// A silently dropped connection would otherwise go unnoticed, leaving a stale cache behind.
+			if pingPending {
+				return fmt.Errorf("no response from Redis within %s", listenerPingInterval)
+			}
+			if err := pubsub.Ping(ctx); err != nil {
+				return err
+			}
+			pingPending = true
+			continue
+		}
+		pingPending = false
+		switch message := message.(type) {
+		case *goredis.Subscription:
+			// Changes made before the subscription took effect were never cached, so the cache can start out empty.
+			c.SetValid(true)
+		case *goredis.Message:
+			invalidate(c, strings.TrimPrefix(message.Channel, channelPrefix))
+		}
+	}
+}
+
+// escapePattern escapes the characters that have a special meaning in Redis glob-style patterns.
+func escapePattern(s string) string {
+	var escaped strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+func (l *listener) stop() {
+	l.cancel()
+	<-l.done
+}
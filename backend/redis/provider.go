@@ -0,0 +1,529 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	goredis "github.com/redis/go-redis/v9"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// All Redis keys start with the configured prefix, followed by the kind of the key and, except for the indexes, the
+// name of the zone or update key, separated by a colon.
+const (
+	// zonesSet and keysSet index the names of all zones and update keys for listing.
+	zonesSet = "zones"
+	keysSet  = "keys"
+	// zoneHash holds the serial and the debug flag of a zone.
+	zoneHash = "zone"
+	// answersString holds the ACME challenge answers of a zone as a JSON list. It is a separate key so Redis can
+	// expire it on its own.
+	answersString = "answers"
+	// keyHash holds the secret and the allowed networks of an update key.
+	keyHash = "key"
+	// keyZonesSet and zoneKeysSet hold the key bindings in both directions, so deleting either side can remove them.
+	keyZonesSet = "key-zones"
+	zoneKeysSet = "zone-keys"
+)
+
+// Every change is made by a Lua script, which Redis runs atomically. The scripts derive the names of the reverse
+// key bindings from the prefix passed as an argument, which works with Redis Cluster because the prefix places all
+// keys in the same hash slot.
+var (
+	createScript = goredis.NewScript(`
+if redis.call('SADD', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[2], unpack(ARGV, 2))
+return 1
+`)
+	setZoneScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+-- The serial wraps around like an RFC 1982 serial number.
+if redis.call('HINCRBY', KEYS[1], 'serial', 1) > 4294967295 then
+	redis.call('HSET', KEYS[1], 'serial', 0)
+end
+if ARGV[1] == '' then
+	redis.call('DEL', KEYS[2])
+elseif tonumber(ARGV[2]) > 0 then
+	redis.call('SET', KEYS[2], ARGV[1], 'PX', ARGV[2])
+else
+	redis.call('SET', KEYS[2], ARGV[1])
+end
+return 1
+`)
+	setFieldScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+	bindKeyScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return -1
+end
+redis.call('SADD', KEYS[3], ARGV[2])
+redis.call('SADD', KEYS[4], ARGV[1])
+return 1
+`)
+	unbindKeyScript = goredis.NewScript(`
+if redis.call('SREM', KEYS[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call('SREM', KEYS[2], ARGV[1])
+return 1
+`)
+	// deleteScript removes a zone or an update key along with its key bindings. ARGV[3] is the kind of the reverse
+	// binding sets.
+	deleteScript = goredis.NewScript(`
+if redis.call('SREM', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+for _, name in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	redis.call('SREM', ARGV[2] .. ARGV[3] .. ':' .. name, ARGV[1])
+end
+redis.call('DEL', unpack(KEYS, 2))
+return 1
+`)
+)
+
+type provider struct {
+	client    goredis.UniversalClient
+	prefix    string
+	answerTTL time.Duration
+	// cache and listener are nil if caching is turned off.
+	cache    *cache.Notified
+	listener *listener
+}
+
+func (p *provider) GetKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	if p.cache == nil {
+		return p.getKey(ctx, keyName)
+	}
+	return p.cache.GetKey(ctx, keyName, p.getKey)
+}
+
+func (p *provider) getKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	var read keyRead
+	if err := p.read(ctx, func(pipe goredis.Pipeliner) {
+		read = p.queueKey(ctx, pipe, keyName)
+	}); err != nil {
+		return backend.ProviderKeyResponse{}, backend.RequestFailed(err, "key", keyName)
+	}
+	key, found, err := read.result()
+	if err != nil {
+		return backend.ProviderKeyResponse{}, backend.RequestFailed(err, "key", keyName)
+	}
+	if !found {
+		return backend.ProviderKeyResponse{}, backend.ErrKeyNotFoundInBackend
+	}
+	return key, nil
+}
+
+func (p *provider) GetZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	if p.cache == nil {
+		return p.getZone(ctx, zoneName)
+	}
+	return p.cache.GetZone(ctx, zoneName, p.getZone)
+}
+
+func (p *provider) getZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	var read zoneRead
+	if err := p.read(ctx, func(pipe goredis.Pipeliner) {
+		read = p.queueZone(ctx, pipe, zoneName)
+	}); err != nil {
+		return backend.ProviderZoneResponse{}, backend.RequestFailed(err, "zone", zoneName)
+	}
+	zone, found, err := read.result()
+	if err != nil {
+		return backend.ProviderZoneResponse{}, backend.RequestFailed(err, "zone", zoneName)
+	}
+	if !found {
+		return backend.ProviderZoneResponse{}, backend.ErrZoneNotInBackend
+	}
+	return zone, nil
+}
+
+func (p *provider) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	answers := ""
+	if len(acmeChallengeAnswers) > 0 {
+		data, err := json.Marshal(acmeChallengeAnswers)
+		if err != nil {
+			return backend.RequestFailed(err, "zone", zoneName)
+		}
+		answers = string(data)
+	}
+	result, err := setZoneScript.Run(
+		ctx,
+		p.client,
+		[]string{p.key(zoneHash, zoneName), p.key(answersString, zoneName)},
+		answers,
+		p.answerTTL.Milliseconds(),
+	).Int()
+	p.invalidate(zoneHash, zoneName)
+	if err != nil {
+		return backend.RequestFailed(err, "zone", zoneName)
+	}
+	if result == 0 {
+		return backend.ErrZoneNotInBackend
+	}
+	return nil
+}
+
+func (p *provider) SetZoneDebug(ctx context.Context, zoneName string, debug bool) error {
+	return p.setField(ctx, zoneHash, zoneName, "debug", formatBool(debug), backend.ErrZoneNotInBackend)
+}
+
+func (p *provider) CreateKey(ctx context.Context, keyName string, secret string) error {
+	return p.create(ctx, keysSet, keyHash, keyName, "secret", secret, "allowFrom", "[]")
+}
+
+func (p *provider) DeleteKey(ctx context.Context, keyName string) error {
+	return p.delete(ctx, keysSet, keyName, []string{p.key(keyZonesSet, keyName), p.key(keyHash, keyName)}, zoneKeysSet)
+}
+
+func (p *provider) SetKeySecret(ctx context.Context, keyName string, secret string) error {
+	return p.setField(ctx, keyHash, keyName, "secret", secret, backend.ErrObjectNotInBackend)
+}
+
+func (p *provider) SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error {
+	data, err := json.Marshal(backend.NonNil(allowFrom))
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	return p.setField(ctx, keyHash, keyName, "allowFrom", string(data), backend.ErrObjectNotInBackend)
+}
+
+func (p *provider) BindKey(ctx context.Context, keyName string, zoneName string) error {
+	result, err := bindKeyScript.Run(
+		ctx,
+		p.client,
+		[]string{
+			p.key(keyHash, keyName),
+			p.key(zoneHash, zoneName),
+			p.key(keyZonesSet, keyName),
+			p.key(zoneKeysSet, zoneName),
+		},
+		keyName,
+		zoneName,
+	).Int()
+	p.invalidate(keyHash, keyName)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	switch result {
+	case 0:
+		return backend.ErrObjectNotInBackend
+	case -1:
+		return backend.ErrZoneNotInBackend
+	}
+	return nil
+}
+
+func (p *provider) UnbindKey(ctx context.Context, keyName string, zoneName string) error {
+	result, err := unbindKeyScript.Run(
+		ctx,
+		p.client,
+		[]string{p.key(keyZonesSet, keyName), p.key(zoneKeysSet, zoneName)},
+		keyName,
+		zoneName,
+	).Int()
+	p.invalidate(keyHash, keyName)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	if result == 0 {
+		return backend.ErrObjectNotInBackend
+	}
+	return nil
+}
+
+func (p *provider) CreateZone(ctx context.Context, zoneName string) error {
+	return p.create(ctx, zonesSet, zoneHash, zoneName, "serial", "0", "debug", formatBool(false))
+}
+
+func (p *provider) DeleteZone(ctx context.Context, zoneName string) error {
+	err := p.delete(
+		ctx,
+		zonesSet,
+		zoneName,
+		[]string{p.key(zoneKeysSet, zoneName), p.key(zoneHash, zoneName), p.key(answersString, zoneName)},
+		keyZonesSet,
+	)
+	// Deleting the zone removes its key bindings, which changes the zones of every key bound to it.
+	if p.cache != nil {
+		p.cache.InvalidateKeys()
+	}
+	return err
+}
+
+func (p *provider) ListZones(ctx context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	names, continueToken, err := p.listNames(ctx, zonesSet, options)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	reads := make([]zoneRead, len(names))
+	if err := p.read(ctx, func(pipe goredis.Pipeliner) {
+		for i, name := range names {
+			reads[i] = p.queueZone(ctx, pipe, name)
+		}
+	}); err != nil {
+		return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	var zones []backend.ProviderZoneListItem
+	for i, read := range reads {
+		zone, found, err := read.result()
+		if err != nil {
+			return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "zone", names[i])
+		}
+		// The zone was deleted after the names were listed.
+		if !found {
+			continue
+		}
+		zones = append(zones, backend.ProviderZoneListItem{Name: names[i], ProviderZoneResponse: zone})
+	}
+	return backend.ProviderZoneListResponse{Zones: zones, Continue: continueToken}, nil
+}
+
+func (p *provider) ListKeys(ctx context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	names, continueToken, err := p.listNames(ctx, keysSet, options)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	reads := make([]keyRead, len(names))
+	if err := p.read(ctx, func(pipe goredis.Pipeliner) {
+		for i, name := range names {
+			reads[i] = p.queueKey(ctx, pipe, name)
+		}
+	}); err != nil {
+		return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	var keys []backend.ProviderKeyListItem
+	for i, read := range reads {
+		key, found, err := read.result()
+		if err != nil {
+			return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "key", names[i])
+		}
+		// The key was deleted after the names were listed.
+		if !found {
+			continue
+		}
+		keys = append(keys, backend.ProviderKeyListItem{Name: names[i], Zones: key.Zones, AllowFrom: key.AllowFrom})
+	}
+	return backend.ProviderKeyListResponse{Keys: keys, Continue: continueToken}, nil
+}
+
+func (p *provider) Close(_ context.Context) error {
+	if p.listener != nil {
+		p.listener.stop()
+	}
+	return p.client.Close()
+}
+
+// key returns the Redis key of the specified kind for a zone or update key.
+func (p *provider) key(kind string, name string) string {
+	return p.prefix + kind + ":" + name
+}
+
+// parseKey splits a Redis key without the prefix into its kind and the name of the zone or update key.
+func parseKey(redisKey string) (string, string) {
+	kind, name, _ := strings.Cut(redisKey, ":")
+	return kind, name
+}
+
+func (p *provider) create(ctx context.Context, set string, kind string, name string, fields ...string) error {
+	args := []any{name}
+	for _, field := range fields {
+		args = append(args, field)
+	}
+	result, err := createScript.Run(ctx, p.client, []string{p.prefix + set, p.key(kind, name)}, args...).Int()
+	p.invalidate(kind, name)
+	if err != nil {
+		return backend.RequestFailed(err, kind, name)
+	}
+	if result == 0 {
+		return backend.ErrObjectBackendConflict
+	}
+	return nil
+}
+
+func (p *provider) setField(
+	ctx context.Context,
+	kind string,
+	name string,
+	field string,
+	value string,
+	notFound error,
+) error {
+	result, err := setFieldScript.Run(ctx, p.client, []string{p.key(kind, name)}, field, value).Int()
+	p.invalidate(kind, name)
+	if err != nil {
+		return backend.RequestFailed(err, kind, name)
+	}
+	if result == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// delete removes a zone or an update key. The first of the keys must be its key binding set, which holds the names
+// of the objects whose reverse binding sets of the specified kind need to be updated.
+func (p *provider) delete(ctx context.Context, set string, name string, keys []string, reverseKind string) error {
+	kind := zoneHash
+	notFound := backend.ErrZoneNotInBackend
+	if set == keysSet {
+		kind = keyHash
+		notFound = backend.ErrObjectNotInBackend
+	}
+	result, err := deleteScript.Run(
+		ctx,
+		p.client,
+		append([]string{p.prefix + set}, keys...),
+		name,
+		p.prefix,
+		reverseKind,
+	).Int()
+	p.invalidate(kind, name)
+	if err != nil {
+		return backend.RequestFailed(err, kind, name)
+	}
+	if result == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// invalidate removes a changed object from the cache right away. The keyspace notification arrives later, and reads
+// on this replica must reflect the change as soon as the write returns. Failed writes invalidate as well, since the
+// change may have been applied anyway.
+func (p *provider) invalidate(kind string, name string) {
+	if p.cache != nil {
+		invalidate(p.cache, kind+":"+name)
+	}
+}
+
+// read runs the commands queued by f in a transaction, so they see a consistent state. Missing keys are reported by
+// the individual commands.
+func (p *provider) read(ctx context.Context, f func(pipe goredis.Pipeliner)) error {
+	_, err := p.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		f(pipe)
+		return nil
+	})
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return err
+	}
+	return nil
+}
+
+// listNames returns the sorted names in the specified index set that are on the page selected by options.
+func (p *provider) listNames(ctx context.Context, set string, options backend.ListOptions) ([]string, string, error) {
+	if options.Limit < 0 {
+		return nil, "", backend.ErrInvalidListOptions.WithAttr(slog.Int("limit", options.Limit))
+	}
+	names, err := p.client.SMembers(ctx, p.prefix+set).Result()
+	if err != nil {
+		return nil, "", backend.RequestFailed(err, "", "")
+	}
+	slices.Sort(names)
+	return backend.Paginate(names, func(name string) string { return name }, options)
+}
+
+type zoneRead struct {
+	hash    *goredis.MapStringStringCmd
+	answers *goredis.StringCmd
+}
+
+func (p *provider) queueZone(ctx context.Context, pipe goredis.Pipeliner, zoneName string) zoneRead {
+	return zoneRead{
+		hash:    pipe.HGetAll(ctx, p.key(zoneHash, zoneName)),
+		answers: pipe.Get(ctx, p.key(answersString, zoneName)),
+	}
+}
+
+// result returns the zone read by the transaction and whether it exists.
+func (r zoneRead) result() (backend.ProviderZoneResponse, bool, error) {
+	hash, err := r.hash.Result()
+	if err != nil {
+		return backend.ProviderZoneResponse{}, false, err
+	}
+	if len(hash) == 0 {
+		return backend.ProviderZoneResponse{}, false, nil
+	}
+	serial, err := strconv.ParseUint(hash["serial"], 10, 32)
+	if err != nil {
+		return backend.ProviderZoneResponse{}, false, fmt.Errorf("invalid serial: %w", err)
+	}
+	zone := backend.ProviderZoneResponse{
+		Serial: uint32(serial),
+		Debug:  hash["debug"] == formatBool(true),
+	}
+	answers, err := r.answers.Result()
+	switch {
+	case errors.Is(err, goredis.Nil):
+	case err != nil:
+		return backend.ProviderZoneResponse{}, false, err
+	default:
+		if err := json.Unmarshal([]byte(answers), &zone.ACMEChallengeAnswers); err != nil {
+			return backend.ProviderZoneResponse{}, false, fmt.Errorf("invalid ACME challenge answers: %w", err)
+		}
+		zone.ACMEChallengeAnswers = backend.NilIfEmpty(zone.ACMEChallengeAnswers)
+	}
+	return zone, true, nil
+}
+
+type keyRead struct {
+	hash  *goredis.MapStringStringCmd
+	zones *goredis.StringSliceCmd
+}
+
+func (p *provider) queueKey(ctx context.Context, pipe goredis.Pipeliner, keyName string) keyRead {
+	return keyRead{
+		hash:  pipe.HGetAll(ctx, p.key(keyHash, keyName)),
+		zones: pipe.SMembers(ctx, p.key(keyZonesSet, keyName)),
+	}
+}
+
+// result returns the update key read by the transaction and whether it exists.
+func (r keyRead) result() (backend.ProviderKeyResponse, bool, error) {
+	hash, err := r.hash.Result()
+	if err != nil {
+		return backend.ProviderKeyResponse{}, false, err
+	}
+	if len(hash) == 0 {
+		return backend.ProviderKeyResponse{}, false, nil
+	}
+	key := backend.ProviderKeyResponse{Secret: hash["secret"]}
+	if allowFrom := hash["allowFrom"]; allowFrom != "" {
+		if err := json.Unmarshal([]byte(allowFrom), &key.AllowFrom); err != nil {
+			return backend.ProviderKeyResponse{}, false, fmt.Errorf("invalid allowed networks: %w", err)
+		}
+	}
+	key.AllowFrom = backend.NilIfEmpty(key.AllowFrom)
+	zones, err := r.zones.Result()
+	if err != nil {
+		return backend.ProviderKeyResponse{}, false, err
+	}
+	slices.Sort(zones)
+	key.Zones = backend.NilIfEmpty(zones)
+	return key, true, nil
+}
+
+func formatBool(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
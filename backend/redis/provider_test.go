@@ -0,0 +1,184 @@
+package redis_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/redis"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+const prefix = "{dns4acme}:"
+
+func open(t *testing.T, m *miniredis.Miniredis, config redis.Config) backend.ExtendedProvider {
+	t.Helper()
+	config.Addresses = []string{m.Addr()}
+	config.Prefix = prefix
+	config.DialTimeout = 5 * time.Second
+	provider, err := config.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to connect to Redis: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Close(context.Background())
+	})
+	return provider
+}
+
+func TestProviderSerialWraps(t *testing.T) {
+	m := miniredis.RunT(t)
+	provider := open(t, m, redis.Config{})
+	ctx := t.Context()
+	if err := provider.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	m.HSet(prefix+"zone:example.com", "serial", "4294967295")
+	if err := provider.SetZone(ctx, "example.com", nil); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	if zone, err := provider.GetZone(ctx, "example.com"); err != nil || zone.Serial != 0 {
+		t.Fatalf("The serial did not wrap around: %v (%v)", zone, err)
+	}
+}
+
+func TestProviderAnswerTTL(t *testing.T) {
+	m := miniredis.RunT(t)
+	provider := open(t, m, redis.Config{AnswerTTL: time.Minute})
+	ctx := t.Context()
+	if err := provider.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	if err := provider.SetZone(ctx, "example.com", []string{"answer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	m.FastForward(30 * time.Second)
+	if zone, err := provider.GetZone(ctx, "example.com"); err != nil || !slices.Equal(zone.ACMEChallengeAnswers, []string{"answer"}) {
+		t.Fatalf("The answers expired too early: %v (%v)", zone, err)
+	}
+	m.FastForward(time.Minute)
+	if zone, err := provider.GetZone(ctx, "example.com"); err != nil || zone.ACMEChallengeAnswers != nil || zone.Serial != 1 {
+		t.Fatalf("The answers did not expire: %v (%v)", zone, err)
+	}
+}
+
+func TestProviderCacheInvalidation(t *testing.T) {
+	m := miniredis.RunT(t)
+	provider := open(t, m, redis.Config{Cache: true})
+	ctx := t.Context()
+	if err := provider.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+
+	// Changing the data behind the back of the provider shows whether the zone is served from the cache, which only
+	// happens once the listener is subscribed.
+	zoneKey := prefix + "zone:example.com"
+	stored := false
+	backendtest.Eventually(t, func() bool {
+		zone, err := provider.GetZone(ctx, "example.com")
+		if err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		stored = !zone.Debug
+		m.HSet(zoneKey, "debug", map[bool]string{false: "0", true: "1"}[stored])
+		cached, err := provider.GetZone(ctx, "example.com")
+		return err == nil && cached.Debug == zone.Debug
+	})
+
+	// The stand-in does not publish keyspace notifications on its own, so send the one Redis would send.
+	m.Publish("__keyspace@0__:"+zoneKey, "hset")
+	backendtest.Eventually(t, func() bool {
+		zone, err := provider.GetZone(ctx, "example.com")
+		return err == nil && zone.Debug == stored
+	})
+}
+
+func TestProviderBypassCache(t *testing.T) {
+	m := miniredis.RunT(t)
+	// Two providers on the same Redis behave like two DNS4ACME replicas.
+	writer := open(t, m, redis.Config{Cache: true})
+	reader := open(t, m, redis.Config{Cache: true})
+	ctx := t.Context()
+	if err := writer.CreateZone(ctx, "example.com"); err != nil {
+		t.Fatalf("Failed to create zone: %v", err)
+	}
+	backendtest.Eventually(t, func() bool {
+		if _, err := reader.GetZone(ctx, "example.com"); err != nil {
+			t.Fatalf("Failed to get zone: %v", err)
+		}
+		m.HSet(prefix+"zone:example.com", "debug", "1")
+		cached, err := reader.GetZone(ctx, "example.com")
+		m.HSet(prefix+"zone:example.com", "debug", "0")
+		return err == nil && !cached.Debug
+	})
+
+	// The stand-in does not publish keyspace notifications, so the reader keeps serving the cached zone as if the
+	// notification of the writer was still on its way.
+	if err := writer.SetZone(ctx, "example.com", []string{"writer"}); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	if zone, err := reader.GetZone(ctx, "example.com"); err != nil || zone.ACMEChallengeAnswers != nil {
+		t.Fatalf("Expected the cached zone, got %v (%v)", zone, err)
+	}
+	zone, err := reader.GetZone(backend.BypassCache(ctx), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zone.ACMEChallengeAnswers, []string{"writer"}) {
+		t.Fatalf("Incorrect answers when bypassing the cache: %v", zone.ACMEChallengeAnswers)
+	}
+	if err := reader.SetZone(ctx, "example.com", append(zone.ACMEChallengeAnswers, "reader")); err != nil {
+		t.Fatalf("Failed to set zone: %v", err)
+	}
+	zone, err = writer.GetZone(backend.BypassCache(ctx), "example.com")
+	if err != nil {
+		t.Fatalf("Failed to get zone: %v", err)
+	}
+	if !slices.Equal(zone.ACMEChallengeAnswers, []string{"writer", "reader"}) {
+		t.Fatalf("Incorrect answers after both replicas updated the zone: %v", zone.ACMEChallengeAnswers)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	for name, config := range map[string]redis.Config{
+		"no addresses":           {},
+		"sentinel and cluster":   {Addresses: []string{"localhost:6379"}, SentinelMaster: "mymaster", Cluster: true},
+		"cluster database":       {Addresses: []string{"localhost:6379"}, Cluster: true, DB: 1, Prefix: prefix},
+		"cluster without tag":    {Addresses: []string{"localhost:6379"}, Cluster: true, Prefix: "dns4acme:"},
+		"negative answer TTL":    {Addresses: []string{"localhost:6379"}, AnswerTTL: -time.Second},
+		"missing CA file":        {Addresses: []string{"localhost:6379"}, TLS: true, CAFile: "/nonexistent/ca.pem"},
+		"cluster with empty tag": {Addresses: []string{"localhost:6379"}, Cluster: true, Prefix: "{}dns4acme:"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := config.BuildExtended(t.Context()); !E.Is(err, backend.ErrConfiguration) {
+				t.Fatalf("Expected a configuration error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestConformance(t *testing.T) {
+	for _, cache := range []bool{false, true} {
+		t.Run(map[bool]string{false: "uncached", true: "cached"}[cache], func(t *testing.T) {
+			backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+				return open(t, miniredis.RunT(t), redis.Config{Cache: cache})
+			})
+			sharedServer := func(t *testing.T) backendtest.ExtendedProviderFactory {
+				m := miniredis.RunT(t)
+				return func(t *testing.T) backend.ExtendedProvider {
+					return open(t, m, redis.Config{Cache: cache})
+				}
+			}
+			backendtest.RunPersistent(t, sharedServer)
+			// The stand-in does not publish keyspace notifications, so cached replicas never see each other's changes.
+			if !cache {
+				backendtest.RunReplicas(t, sharedServer)
+			}
+		})
+	}
+}
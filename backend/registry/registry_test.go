@@ -0,0 +1,154 @@
+package registry_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	_ "github.com/dns4acme/dns4acme/backend/bbolt"
+	_ "github.com/dns4acme/dns4acme/backend/etcd"
+	_ "github.com/dns4acme/dns4acme/backend/file"
+	_ "github.com/dns4acme/dns4acme/backend/inmemory"
+	_ "github.com/dns4acme/dns4acme/backend/kubernetes"
+	_ "github.com/dns4acme/dns4acme/backend/postgres"
+	_ "github.com/dns4acme/dns4acme/backend/redis"
+	"github.com/dns4acme/dns4acme/backend/registry"
+	_ "github.com/dns4acme/dns4acme/backend/sqlite"
+)
+
+// backendDir is the directory of the backend package, relative to this package. The backends are in its
+// subdirectories.
+const backendDir = ".."
+
+// conformanceFunctions are the backendtest functions that run the conformance test suite.
+var conformanceFunctions = []string{"RunProvider", "RunExtendedProvider"}
+
+// TestAllBackendsImported makes sure the other tests see every backend, since backends only register themselves when
+// they are imported.
+func TestAllBackendsImported(t *testing.T) {
+	registered := map[string]bool{}
+	for _, descriptor := range registry.Backends {
+		registered[packageDir(descriptor)] = true
+	}
+	dirs, err := os.ReadDir(backendDir)
+	if err != nil {
+		t.Fatalf("Failed to read backend directory: %v", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		name := dir.Name()
+		files := parseDir(t, filepath.Join(backendDir, name), false)
+		if !containsNode(files, isRegistration) {
+			continue
+		}
+		if !registered[name] {
+			t.Errorf("Backend package %s registers a backend, but the registry tests don't import it.", name)
+		}
+	}
+}
+
+// TestAllBackendsRunConformance fails for any registered backend whose tests don't run the conformance test suite.
+func TestAllBackendsRunConformance(t *testing.T) {
+	for id, descriptor := range registry.Backends {
+		dir := packageDir(descriptor)
+		files := parseDir(t, filepath.Join(backendDir, dir), true)
+		if !containsNode(files, isConformanceRun) {
+			t.Errorf(
+				"The tests of backend %s in backend/%s don't run the conformance test suite with backendtest.%s.",
+				id,
+				dir,
+				strings.Join(conformanceFunctions, " or backendtest."),
+			)
+		}
+	}
+}
+
+// packageDir returns the directory of the package the descriptor is defined in, relative to the backend package.
+func packageDir(descriptor backend.Descriptor) string {
+	backendPackage := reflect.TypeFor[backend.Descriptor]().PkgPath()
+	descriptorType := reflect.TypeOf(descriptor)
+	if descriptorType.Kind() == reflect.Ptr {
+		descriptorType = descriptorType.Elem()
+	}
+	return strings.TrimPrefix(descriptorType.PkgPath(), backendPackage+"/")
+}
+
+// parseDir parses either the test files or the other Go files in dir.
+func parseDir(t *testing.T, dir string, tests bool) []*ast.File {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		t.Fatalf("Failed to list files in %s: %v", dir, err)
+	}
+	var files []*ast.File
+	fileSet := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") != tests {
+			continue
+		}
+		file, err := parser.ParseFile(fileSet, path, nil, 0)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %v", path, err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func containsNode(files []*ast.File, match func(node ast.Node) bool) bool {
+	found := false
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if match(node) {
+				found = true
+			}
+			return !found
+		})
+	}
+	return found
+}
+
+// isRegistration returns true for assignments to registry.Backends[...].
+func isRegistration(node ast.Node) bool {
+	assignment, ok := node.(*ast.AssignStmt)
+	if !ok {
+		return false
+	}
+	for _, lhs := range assignment.Lhs {
+		if index, ok := lhs.(*ast.IndexExpr); ok && isSelector(index.X, "registry", "Backends") {
+			return true
+		}
+	}
+	return false
+}
+
+// isConformanceRun returns true for calls to the backendtest functions running the conformance test suite.
+func isConformanceRun(node ast.Node) bool {
+	call, ok := node.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	for _, function := range conformanceFunctions {
+		if isSelector(call.Fun, "backendtest", function) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSelector(expr ast.Expr, packageName string, name string) bool {
+	selector, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := selector.X.(*ast.Ident)
+	return ok && ident.Name == packageName && selector.Sel.Name == name
+}
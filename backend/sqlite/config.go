@@ -0,0 +1,51 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	// Registers the pure-Go "sqlite" database/sql driver, which does not require cgo.
+	_ "modernc.org/sqlite"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	Path        string        `config:"path" default:"dns4acme.sqlite" description:"Path to the SQLite database file. The file is created if it does not exist."`
+	BusyTimeout time.Duration `config:"busy-timeout" default:"5s" description:"Maximum time to wait for a lock held by another connection or process."`
+}
+
+func (c Config) Build(ctx context.Context) (backend.Provider, error) {
+	return c.BuildExtended(ctx)
+}
+
+func (c Config) BuildExtended(ctx context.Context) (backend.ExtendedProvider, error) {
+	if c.Path == "" {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("the SQLite database path must not be empty"))
+	}
+	db, err := sql.Open("sqlite", c.dsn())
+	if err != nil {
+		return nil, backend.ErrConfiguration.Wrap(err)
+	}
+	if err := migrate(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &provider{db: db}, nil
+}
+
+// dsn returns the data source name for the driver. The pragmas are applied to every connection in the pool: WAL mode
+// lets queries run while an update is written, and foreign keys remove the key bindings of deleted zones and keys.
+// Transactions take the write lock immediately so that concurrent writers wait for the busy timeout instead of failing
+// on a lock upgrade.
+func (c Config) dsn() string {
+	query := url.Values{}
+	query.Add("_pragma", "busy_timeout("+strconv.FormatInt(c.BusyTimeout.Milliseconds(), 10)+")")
+	query.Add("_pragma", "journal_mode(WAL)")
+	query.Add("_pragma", "synchronous(NORMAL)")
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Set("_txlock", "immediate")
+	return c.Path + "?" + query.Encode()
+}
@@ -0,0 +1,20 @@
+package sqlite
+
+import (
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+type descriptor struct {
+}
+
+func (d descriptor) Name() string {
+	return "SQLite"
+}
+
+func (d descriptor) Description() string {
+	return "Backend that stores zones and update keys in a local SQLite database file."
+}
+
+func (d descriptor) Config() backend.Config {
+	return &Config{}
+}
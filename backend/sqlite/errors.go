@@ -0,0 +1,6 @@
+package sqlite
+
+import "github.com/dns4acme/dns4acme/lang/E"
+
+var ErrMigrationFailed = E.New("SQLITE_MIGRATION_FAILED", "failed to migrate the SQLite database schema")
+var ErrSchemaTooNew = E.New("SQLITE_SCHEMA_TOO_NEW", "the SQLite database was created by a newer version of DNS4ACME")
@@ -0,0 +1,3 @@
+package sqlite
+
+const ID = "sqlite"
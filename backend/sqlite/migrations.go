@@ -0,0 +1,65 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+// migrations contains the schema changes in order. The number of migrations applied is stored in the user_version
+// pragma of the database. Never change a migration that has been released, add a new one instead.
+var migrations = []string{
+	`CREATE TABLE zones (
+		name    TEXT    NOT NULL PRIMARY KEY,
+		serial  INTEGER NOT NULL DEFAULT 0,
+		answers TEXT    NOT NULL DEFAULT '[]',
+		debug   INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE keys (
+		name       TEXT NOT NULL PRIMARY KEY,
+		secret     TEXT NOT NULL,
+		allow_from TEXT NOT NULL DEFAULT '[]'
+	);
+	CREATE TABLE key_bindings (
+		key_name  TEXT NOT NULL REFERENCES keys (name) ON DELETE CASCADE,
+		zone_name TEXT NOT NULL REFERENCES zones (name) ON DELETE CASCADE,
+		PRIMARY KEY (key_name, zone_name)
+	);
+	CREATE INDEX key_bindings_zone_name ON key_bindings (zone_name);`,
+}
+
+// migrate applies the migrations missing from the database, each in its own transaction.
+func migrate(ctx context.Context, db *sql.DB) error {
+	var version int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return ErrMigrationFailed.Wrap(err)
+	}
+	if version > len(migrations) {
+		return ErrSchemaTooNew.WithAttr(slog.Int("version", version)).WithAttr(slog.Int("supported_version", len(migrations)))
+	}
+	for ; version < len(migrations); version++ {
+		if err := applyMigration(ctx, db, version); err != nil {
+			return ErrMigrationFailed.Wrap(err).WithAttr(slog.Int("version", version+1))
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, version int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if _, err := tx.ExecContext(ctx, migrations[version]); err != nil {
+		return err
+	}
+	// Pragmas do not support parameters. The version is an integer, so formatting it into the statement is safe.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version+1)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
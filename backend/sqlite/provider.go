@@ -0,0 +1,311 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+// keyColumns selects an update key with its zone bindings as a JSON array ordered by zone name.
+const keyColumns = `keys.name, keys.secret, keys.allow_from, (
+	SELECT json_group_array(zone_name) FROM (
+		SELECT zone_name FROM key_bindings WHERE key_name = keys.name ORDER BY zone_name
+	)
+)`
+
+type provider struct {
+	db *sql.DB
+}
+
+func (p *provider) GetKey(ctx context.Context, keyName string) (backend.ProviderKeyResponse, error) {
+	row := p.db.QueryRowContext(ctx, `SELECT `+keyColumns+` FROM keys WHERE name = ?`, keyName)
+	var name string
+	var key backend.ProviderKeyResponse
+	if err := scanKey(row, &name, &key); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return backend.ProviderKeyResponse{}, backend.ErrKeyNotFoundInBackend
+		}
+		return backend.ProviderKeyResponse{}, backend.RequestFailed(err, "key", keyName)
+	}
+	return key, nil
+}
+
+func (p *provider) GetZone(ctx context.Context, zoneName string) (backend.ProviderZoneResponse, error) {
+	row := p.db.QueryRowContext(ctx, `SELECT name, serial, answers, debug FROM zones WHERE name = ?`, zoneName)
+	var name string
+	var zone backend.ProviderZoneResponse
+	if err := scanZone(row, &name, &zone); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return backend.ProviderZoneResponse{}, backend.ErrZoneNotInBackend
+		}
+		return backend.ProviderZoneResponse{}, backend.RequestFailed(err, "zone", zoneName)
+	}
+	return zone, nil
+}
+
+func (p *provider) SetZone(ctx context.Context, zoneName string, acmeChallengeAnswers []string) error {
+	answers, err := encodeList(acmeChallengeAnswers)
+	if err != nil {
+		return backend.RequestFailed(err, "zone", zoneName)
+	}
+	// The serial is incremented in the same transaction as the answers are written, so concurrent updates never
+	// reuse a serial. It wraps around like an RFC 1982 serial number.
+	return p.transaction(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE zones SET serial = (serial + 1) % 4294967296, answers = ? WHERE name = ?`,
+			answers,
+			zoneName,
+		)
+		if err != nil {
+			return backend.RequestFailed(err, "zone", zoneName)
+		}
+		return requireAffected(result, backend.ErrZoneNotInBackend, "zone", zoneName)
+	})
+}
+
+func (p *provider) SetZoneDebug(ctx context.Context, zoneName string, debug bool) error {
+	result, err := p.db.ExecContext(ctx, `UPDATE zones SET debug = ? WHERE name = ?`, debug, zoneName)
+	if err != nil {
+		return backend.RequestFailed(err, "zone", zoneName)
+	}
+	return requireAffected(result, backend.ErrZoneNotInBackend, "zone", zoneName)
+}
+
+func (p *provider) CreateKey(ctx context.Context, keyName string, secret string) error {
+	result, err := p.db.ExecContext(ctx, `INSERT INTO keys (name, secret) VALUES (?, ?) ON CONFLICT DO NOTHING`, keyName, secret)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	return requireAffected(result, backend.ErrObjectBackendConflict, "key", keyName)
+}
+
+func (p *provider) DeleteKey(ctx context.Context, keyName string) error {
+	result, err := p.db.ExecContext(ctx, `DELETE FROM keys WHERE name = ?`, keyName)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	return requireAffected(result, backend.ErrObjectNotInBackend, "key", keyName)
+}
+
+func (p *provider) SetKeySecret(ctx context.Context, keyName string, secret string) error {
+	result, err := p.db.ExecContext(ctx, `UPDATE keys SET secret = ? WHERE name = ?`, secret, keyName)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	return requireAffected(result, backend.ErrObjectNotInBackend, "key", keyName)
+}
+
+func (p *provider) SetKeyAllowFrom(ctx context.Context, keyName string, allowFrom []string) error {
+	encoded, err := encodeList(allowFrom)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	result, err := p.db.ExecContext(ctx, `UPDATE keys SET allow_from = ? WHERE name = ?`, encoded, keyName)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	return requireAffected(result, backend.ErrObjectNotInBackend, "key", keyName)
+}
+
+func (p *provider) BindKey(ctx context.Context, keyName string, zoneName string) error {
+	return p.transaction(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(ctx, `SELECT 1 FROM keys WHERE name = ?`, keyName)
+		if err := backend.RequireRow(row.Scan, sql.ErrNoRows, backend.ErrObjectNotInBackend, "key", keyName); err != nil {
+			return err
+		}
+		row = tx.QueryRowContext(ctx, `SELECT 1 FROM zones WHERE name = ?`, zoneName)
+		if err := backend.RequireRow(row.Scan, sql.ErrNoRows, backend.ErrZoneNotInBackend, "zone", zoneName); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO key_bindings (key_name, zone_name) VALUES (?, ?) ON CONFLICT DO NOTHING`,
+			keyName,
+			zoneName,
+		); err != nil {
+			return backend.RequestFailed(err, "key", keyName)
+		}
+		return nil
+	})
+}
+
+func (p *provider) UnbindKey(ctx context.Context, keyName string, zoneName string) error {
+	result, err := p.db.ExecContext(ctx, `DELETE FROM key_bindings WHERE key_name = ? AND zone_name = ?`, keyName, zoneName)
+	if err != nil {
+		return backend.RequestFailed(err, "key", keyName)
+	}
+	return requireAffected(result, backend.ErrObjectNotInBackend, "key", keyName)
+}
+
+func (p *provider) CreateZone(ctx context.Context, zoneName string) error {
+	result, err := p.db.ExecContext(ctx, `INSERT INTO zones (name) VALUES (?) ON CONFLICT DO NOTHING`, zoneName)
+	if err != nil {
+		return backend.RequestFailed(err, "zone", zoneName)
+	}
+	return requireAffected(result, backend.ErrObjectBackendConflict, "zone", zoneName)
+}
+
+func (p *provider) DeleteZone(ctx context.Context, zoneName string) error {
+	// The key bindings of the zone are removed by the foreign key.
+	result, err := p.db.ExecContext(ctx, `DELETE FROM zones WHERE name = ?`, zoneName)
+	if err != nil {
+		return backend.RequestFailed(err, "zone", zoneName)
+	}
+	return requireAffected(result, backend.ErrZoneNotInBackend, "zone", zoneName)
+}
+
+func (p *provider) ListZones(ctx context.Context, options backend.ListOptions) (backend.ProviderZoneListResponse, error) {
+	limit, err := backend.QueryLimit(options)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, err
+	}
+	rows, err := p.db.QueryContext(
+		ctx,
+		`SELECT name, serial, answers, debug FROM zones WHERE name > ? ORDER BY name LIMIT ?`,
+		options.Continue,
+		limit,
+	)
+	if err != nil {
+		return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var zones []backend.ProviderZoneListItem
+	for rows.Next() {
+		var zone backend.ProviderZoneListItem
+		if err := scanZone(rows, &zone.Name, &zone.ProviderZoneResponse); err != nil {
+			return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "", "")
+		}
+		zones = append(zones, zone)
+	}
+	if err := rows.Err(); err != nil {
+		return backend.ProviderZoneListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	zones, continueToken := backend.Page(zones, func(zone backend.ProviderZoneListItem) string {
+		return zone.Name
+	}, options)
+	return backend.ProviderZoneListResponse{Zones: zones, Continue: continueToken}, nil
+}
+
+func (p *provider) ListKeys(ctx context.Context, options backend.ListOptions) (backend.ProviderKeyListResponse, error) {
+	limit, err := backend.QueryLimit(options)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, err
+	}
+	rows, err := p.db.QueryContext(
+		ctx,
+		`SELECT `+keyColumns+` FROM keys WHERE name > ? ORDER BY name LIMIT ?`,
+		options.Continue,
+		limit,
+	)
+	if err != nil {
+		return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var keys []backend.ProviderKeyListItem
+	for rows.Next() {
+		var name string
+		var key backend.ProviderKeyResponse
+		if err := scanKey(rows, &name, &key); err != nil {
+			return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "", "")
+		}
+		keys = append(keys, backend.ProviderKeyListItem{Name: name, Zones: key.Zones, AllowFrom: key.AllowFrom})
+	}
+	if err := rows.Err(); err != nil {
+		return backend.ProviderKeyListResponse{}, backend.RequestFailed(err, "", "")
+	}
+	keys, continueToken := backend.Page(keys, func(key backend.ProviderKeyListItem) string {
+		return key.Name
+	}, options)
+	return backend.ProviderKeyListResponse{Keys: keys, Continue: continueToken}, nil
+}
+
+func (p *provider) Close(_ context.Context) error {
+	return p.db.Close()
+}
+
+// transaction runs f in a transaction and commits it if f succeeds.
+func (p *provider) transaction(ctx context.Context, f func(tx *sql.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return backend.RequestFailed(err, "", "")
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	if err := f(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return backend.RequestFailed(err, "", "")
+	}
+	return nil
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner, name *string, key *backend.ProviderKeyResponse) error {
+	var allowFrom, zones string
+	if err := row.Scan(name, &key.Secret, &allowFrom, &zones); err != nil {
+		return err
+	}
+	var err error
+	if key.AllowFrom, err = decodeList(allowFrom); err != nil {
+		return err
+	}
+	key.Zones, err = decodeList(zones)
+	return err
+}
+
+func scanZone(row scanner, name *string, zone *backend.ProviderZoneResponse) error {
+	var answers string
+	var serial int64
+	if err := row.Scan(name, &serial, &answers, &zone.Debug); err != nil {
+		return err
+	}
+	zone.Serial = uint32(serial) //nolint:gosec // The serial is kept in the uint32 range by SetZone.
+	var err error
+	zone.ACMEChallengeAnswers, err = decodeList(answers)
+	return err
+}
+
+// encodeList stores lists as JSON arrays, which keeps the order of ACME challenge answers.
+func encodeList(items []string) (string, error) {
+	if items == nil {
+		items = []string{}
+	}
+	encoded, err := json.Marshal(items)
+	return string(encoded), err
+}
+
+// decodeList returns nil for empty lists, like the other backends.
+func decodeList(encoded string) ([]string, error) {
+	var items []string
+	if err := json.Unmarshal([]byte(encoded), &items); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items, nil
+}
+
+func requireAffected(result sql.Result, notAffected error, kind string, name string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return backend.RequestFailed(err, kind, name)
+	}
+	if affected == 0 {
+		return notAffected
+	}
+	return nil
+}
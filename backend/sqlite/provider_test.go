@@ -0,0 +1,61 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/backendtest"
+	"github.com/dns4acme/dns4acme/backend/sqlite"
+)
+
+func open(t *testing.T, path string) backend.ExtendedProvider {
+	t.Helper()
+	provider, err := sqlite.Config{Path: path, BusyTimeout: 5 * time.Second}.BuildExtended(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = provider.Close(context.Background())
+	})
+	return provider
+}
+
+// TestProviderJournalMode checks that the database uses write-ahead logging, which lets several processes read while
+// one of them writes.
+func TestProviderJournalMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns4acme.sqlite")
+	open(t, path)
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	var journalMode string
+	if err := db.QueryRowContext(t.Context(), "PRAGMA journal_mode").Scan(&journalMode); err != nil {
+		t.Fatalf("Failed to query journal mode: %v", err)
+	}
+	if journalMode != "wal" {
+		t.Fatalf("Expected WAL mode, got %s", journalMode)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	backendtest.RunExtendedProvider(t, func(t *testing.T) backend.ExtendedProvider {
+		return open(t, filepath.Join(t.TempDir(), "dns4acme.sqlite"))
+	})
+	sharedFile := func(t *testing.T) backendtest.ExtendedProviderFactory {
+		path := filepath.Join(t.TempDir(), "dns4acme.sqlite")
+		return func(t *testing.T) backend.ExtendedProvider {
+			return open(t, path)
+		}
+	}
+	backendtest.RunPersistent(t, sharedFile)
+	backendtest.RunReplicas(t, sharedFile)
+}
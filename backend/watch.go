@@ -0,0 +1,143 @@
+package backend
+
+import (
+	"context"
+	"sync"
+)
+
+// ChangeKind is the kind of object a ChangeEvent refers to.
+type ChangeKind string
+
+const (
+	// ChangeKindZone is reported when the serial, the ACME challenge answers or the debug flag of a zone change, and
+	// when a zone is created or deleted.
+	ChangeKindZone ChangeKind = "zone"
+	// ChangeKindKey is reported when the secret, the allowed networks or the zone bindings of an update key change, and
+	// when an update key is created or deleted.
+	ChangeKindKey ChangeKind = "key"
+	// ChangeKindAll is reported instead of the individual events when a watcher has fallen too far behind. Any zone or
+	// update key may have changed; the event has no name.
+	ChangeKindAll ChangeKind = "all"
+)
+
+// maxQueuedEvents is the number of events queued for a watcher before they are replaced with a single ChangeKindAll
+// event.
+const maxQueuedEvents = 10000
+
+// ChangeEvent describes a change to a zone or an update key. It only identifies the object; call GetZone or GetKey to
+// read its current state.
+type ChangeEvent struct {
+	Kind ChangeKind
+	Name string
+	// Deleted is true if the object has been deleted, otherwise it has been created or updated.
+	Deleted bool
+}
+
+// WatchProvider is implemented by backends that report changes to zones and update keys as they happen, including
+// changes made by other DNS4ACME replicas or directly in the backend.
+type WatchProvider interface {
+	// Watch returns a channel receiving an event for every change, in the order the backend applied them. The channel
+	// is closed when ctx is canceled or the provider is closed. Events are queued for slow receivers, so receiving
+	// never blocks the backend. If too many events are queued, they are replaced with a single ChangeKindAll event.
+	Watch(ctx context.Context) (<-chan ChangeEvent, error)
+}
+
+// Broadcaster delivers change events to the channels returned by WatchProvider.Watch. Backends publish every change
+// and return Watch from their own Watch function. The zero value is ready to use.
+type Broadcaster struct {
+	lock        sync.Mutex
+	closed      bool
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	lock  sync.Mutex
+	queue []ChangeEvent
+	// wake has a buffer of one and signals that events have been queued.
+	wake chan struct{}
+	done chan struct{}
+}
+
+// Watch returns a channel receiving the events published after the call until ctx is canceled or the broadcaster is
+// closed.
+func (b *Broadcaster) Watch(ctx context.Context) <-chan ChangeEvent {
+	events := make(chan ChangeEvent)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		close(events)
+		return events
+	}
+	s := &subscriber{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	if b.subscribers == nil {
+		b.subscribers = map[*subscriber]struct{}{}
+	}
+	b.subscribers[s] = struct{}{}
+	go b.deliver(ctx, s, events)
+	return events
+}
+
+func (b *Broadcaster) deliver(ctx context.Context, s *subscriber, events chan<- ChangeEvent) {
+	defer close(events)
+	defer func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		delete(b.subscribers, s)
+	}()
+	for {
+		s.lock.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.lock.Unlock()
+		for _, event := range queue {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case <-s.wake:
+		case <-ctx.Done():
+			return
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Publish queues the event for all current watchers. It never blocks. Watchers that stopped receiving don't make the
+// queue grow forever: once maxQueuedEvents are queued, the queue is replaced with a single ChangeKindAll event.
+func (b *Broadcaster) Publish(event ChangeEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for s := range b.subscribers {
+		s.lock.Lock()
+		if len(s.queue) >= maxQueuedEvents {
+			s.queue = []ChangeEvent{{Kind: ChangeKindAll}}
+		}
+		s.queue = append(s.queue, event)
+		s.lock.Unlock()
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close closes the channels of all watchers. Events that have not been received yet are dropped. Watch calls after
+// Close return a closed channel.
+func (b *Broadcaster) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	for s := range b.subscribers {
+		close(s.done)
+	}
+	clear(b.subscribers)
+}
@@ -0,0 +1,85 @@
+package backend_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/backend"
+)
+
+func receive(t *testing.T, events <-chan backend.ChangeEvent) (backend.ChangeEvent, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		return event, ok
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timeout while waiting for an event")
+		return backend.ChangeEvent{}, false
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	broadcaster := &backend.Broadcaster{}
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	first := broadcaster.Watch(ctx)
+	second := broadcaster.Watch(t.Context())
+
+	// Publishing does not wait for the receivers.
+	const count = 1000
+	for i := range count {
+		broadcaster.Publish(backend.ChangeEvent{Kind: backend.ChangeKindZone, Name: "example.com", Deleted: i%2 == 1})
+	}
+	for _, events := range []<-chan backend.ChangeEvent{first, second} {
+		for i := range count {
+			event, ok := receive(t, events)
+			if !ok || event.Deleted != (i%2 == 1) {
+				t.Fatalf("Incorrect event %d: %v (%t)", i, event, ok)
+			}
+		}
+	}
+
+	cancel()
+	if _, ok := receive(t, first); ok {
+		t.Fatalf("The channel was not closed after canceling the context")
+	}
+	broadcaster.Close()
+	if _, ok := receive(t, second); ok {
+		t.Fatalf("The channel was not closed after closing the broadcaster")
+	}
+	if _, ok := receive(t, broadcaster.Watch(t.Context())); ok {
+		t.Fatalf("Watch after Close returned an open channel")
+	}
+}
+
+func TestBroadcaster_SlowReceiver(t *testing.T) {
+	broadcaster := &backend.Broadcaster{}
+	defer broadcaster.Close()
+	events := broadcaster.Watch(t.Context())
+
+	// A receiver that doesn't keep up gets a single event asking it to invalidate everything instead of an ever
+	// growing queue.
+	const count = 50000
+	for i := range count {
+		broadcaster.Publish(backend.ChangeEvent{Kind: backend.ChangeKindZone, Name: "example.com", Deleted: i == count-1})
+	}
+	received := 0
+	invalidated := false
+	for {
+		event, ok := receive(t, events)
+		if !ok {
+			t.Fatalf("The channel was closed")
+		}
+		received++
+		if event.Kind == backend.ChangeKindAll {
+			invalidated = true
+		}
+		if event.Deleted {
+			break
+		}
+	}
+	if !invalidated || received >= count {
+		t.Fatalf("All %d events were queued", received)
+	}
+}
@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// challengeTTL is the TTL sent with updates. DNS4ACME ignores it and always answers with a TTL of 60 seconds.
+const challengeTTL = 60
+
+// tsigFudge is the allowed clock skew between the client and the server in seconds.
+const tsigFudge = 300
+
+// New creates a client that sends TSIG-signed RFC 2136 updates to a DNS4ACME server.
+func New(config Config) (Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	algorithm, err := config.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	return &client{
+		config:    config,
+		keyName:   dns.Fqdn(strings.ToLower(config.KeyName)),
+		algorithm: algorithm,
+	}, nil
+}
+
+// Client updates the ACME challenge answers of zones on a DNS4ACME server. Zones are passed as the zone name, e.g.
+// example.com; the _acme-challenge. prefix and a trailing dot are accepted and ignored.
+type Client interface {
+	// Present adds value to the ACME challenge answers of zone.
+	Present(ctx context.Context, zone string, value string) error
+	// CleanUp removes value from the ACME challenge answers of zone and keeps the other answers. The current answers
+	// are queried first, so answers added concurrently by other clients may be lost.
+	CleanUp(ctx context.Context, zone string, value string) error
+	// WaitForPropagation blocks until all nameservers of zone return value, or ctx is done.
+	WaitForPropagation(ctx context.Context, zone string, value string) error
+	// Answers returns the current ACME challenge answers of zone.
+	Answers(ctx context.Context, zone string) ([]string, error)
+}
+
+type client struct {
+	config    Config
+	keyName   string
+	algorithm string
+}
+
+func (c *client) Present(ctx context.Context, zone string, value string) error {
+	name, err := challengeName(zone)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return ErrInvalidValue
+	}
+	msg := &dns.Msg{}
+	msg.SetUpdate(name)
+	msg.Insert([]dns.RR{newTXT(name, value)})
+	return c.update(ctx, msg)
+}
+
+func (c *client) CleanUp(ctx context.Context, zone string, value string) error {
+	name, err := challengeName(zone)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return ErrInvalidValue
+	}
+	answers, err := c.answers(ctx, c.config.Server, name)
+	if err != nil {
+		return err
+	}
+	remaining := slices.DeleteFunc(slices.Clone(answers), func(answer string) bool {
+		return answer == value
+	})
+	if len(remaining) == len(answers) {
+		return nil
+	}
+	// DNS4ACME treats the deletion of a single record like adding it, so the whole RRset is replaced instead.
+	msg := &dns.Msg{}
+	msg.SetUpdate(name)
+	msg.RemoveRRset([]dns.RR{&dns.TXT{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeTXT}}})
+	if len(remaining) > 0 {
+		records := make([]dns.RR, len(remaining))
+		for i, answer := range remaining {
+			records[i] = newTXT(name, answer)
+		}
+		msg.Insert(records)
+	}
+	return c.update(ctx, msg)
+}
+
+func (c *client) WaitForPropagation(ctx context.Context, zone string, value string) error {
+	name, err := challengeName(zone)
+	if err != nil {
+		return err
+	}
+	pending := slices.Clone(c.config.PropagationNameservers)
+	if len(pending) == 0 {
+		if pending, err = c.nameservers(ctx, name); err != nil {
+			return err
+		}
+	}
+	for {
+		pending = slices.DeleteFunc(pending, func(nameserver string) bool {
+			answers, err := c.answers(ctx, nameserver, name)
+			return err == nil && slices.Contains(answers, value)
+		})
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ErrPropagationFailed.Wrap(ctx.Err()).WithAttr(slog.Any("nameservers", pending))
+		case <-time.After(c.config.PollInterval):
+		}
+	}
+}
+
+func (c *client) Answers(ctx context.Context, zone string) ([]string, error) {
+	name, err := challengeName(zone)
+	if err != nil {
+		return nil, err
+	}
+	return c.answers(ctx, c.config.Server, name)
+}
+
+// answers queries the TXT records of name from server. Queries are not signed since DNS4ACME answers them without
+// authentication.
+func (c *client) answers(ctx context.Context, server string, name string) ([]string, error) {
+	msg := &dns.Msg{}
+	msg.SetQuestion(name, dns.TypeTXT)
+	response, err := c.exchange(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+	var answers []string
+	for _, rr := range response.Answer {
+		if txt, ok := rr.(*dns.TXT); ok && strings.EqualFold(txt.Hdr.Name, name) {
+			answers = append(answers, strings.Join(txt.Txt, ""))
+		}
+	}
+	return answers, nil
+}
+
+// nameservers returns the addresses of the nameservers the DNS4ACME server returns for name.
+func (c *client) nameservers(ctx context.Context, name string) ([]string, error) {
+	msg := &dns.Msg{}
+	msg.SetQuestion(name, dns.TypeNS)
+	response, err := c.exchange(ctx, msg, c.config.Server)
+	if err != nil {
+		return nil, err
+	}
+	var nameservers []string
+	for _, rr := range response.Answer {
+		if ns, ok := rr.(*dns.NS); ok {
+			nameservers = append(nameservers, net.JoinHostPort(strings.TrimSuffix(ns.Ns, "."), "53"))
+		}
+	}
+	if len(nameservers) == 0 {
+		return nil, ErrNoNameservers.WithAttr(slog.String("zone", name))
+	}
+	return nameservers, nil
+}
+
+// update signs msg with the update key and sends it to the DNS4ACME server.
+func (c *client) update(ctx context.Context, msg *dns.Msg) error {
+	msg.SetTsig(c.keyName, c.algorithm, tsigFudge, time.Now().Unix())
+	_, err := c.exchange(ctx, msg, c.config.Server)
+	return err
+}
+
+// exchange sends msg to server over UDP, falling back to TCP if the response is truncated, and converts error response
+// codes to errors.
+func (c *client) exchange(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+	dnsClient := &dns.Client{
+		Net:     "udp",
+		Timeout: c.config.Timeout,
+	}
+	if c.config.TCP {
+		dnsClient.Net = "tcp"
+	}
+	if msg.IsTsig() != nil {
+		dnsClient.TsigSecret = map[string]string{c.keyName: c.config.Secret}
+	}
+	response, _, err := dnsClient.ExchangeContext(ctx, msg, server)
+	if err == nil && response.Truncated && dnsClient.Net == "udp" {
+		dnsClient.Net = "tcp"
+		response, _, err = dnsClient.ExchangeContext(ctx, msg, server)
+	}
+	if err != nil {
+		if errors.Is(err, dns.ErrAuth) || errors.Is(err, dns.ErrSig) || errors.Is(err, dns.ErrTime) {
+			return nil, ErrNotAuthorized.Wrap(err).WithAttr(slog.String("server", server))
+		}
+		return nil, ErrExchangeFailed.Wrap(err).WithAttr(slog.String("server", server))
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		cause, ok := rcodeErrors[response.Rcode]
+		if !ok {
+			cause = ErrUnexpectedRcode
+		}
+		return nil, cause.
+			WithAttr(slog.String("server", server)).
+			WithAttr(slog.String("rcode", dns.RcodeToString[response.Rcode]))
+	}
+	return response, nil
+}
+
+// challengeName returns the name of the ACME challenge TXT record of zone.
+func challengeName(zone string) (string, error) {
+	name := strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(zone), "."), "_acme-challenge.")
+	if _, ok := dns.IsDomainName(name); !ok || name == "" {
+		return "", ErrInvalidZone.WithAttr(slog.String("zone", zone))
+	}
+	return "_acme-challenge." + name + ".", nil
+}
+
+// newTXT returns a TXT record with value split into strings of at most 255 bytes, which DNS4ACME joins again.
+func newTXT(name string, value string) *dns.TXT {
+	var txt []string
+	for len(value) > 0 {
+		l := min(len(value), 255)
+		txt = append(txt, value[:l])
+		value = value[l:]
+	}
+	return &dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    challengeTTL,
+		},
+		Txt: txt,
+	}
+}
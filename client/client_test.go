@@ -0,0 +1,162 @@
+package client_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/client"
+	"github.com/dns4acme/dns4acme/core"
+	"github.com/dns4acme/dns4acme/internal/testlogger"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+var testSecret = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+// startServer starts a DNS4ACME server on a fixed port with the key "test" bound to example.com and returns its
+// address.
+func startServer(t *testing.T) string {
+	t.Helper()
+	addrPort := netip.MustParseAddrPort("127.0.0.1:30063")
+	provider, err := inmemory.Config{
+		Keys: map[string]*backend.ProviderKeyResponse{
+			"test": {Secret: testSecret, Zones: []string{"example.com"}},
+		},
+		Zones: map[string]*backend.ProviderZoneResponse{
+			"example.com": {},
+			"example.org": {},
+		},
+	}.Build(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	srv, err := core.New(core.Config{Listen: &addrPort, Nameservers: []string{"ns.example.com"}}, provider, testlogger.New(t))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	running, err := srv.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := running.Stop(context.Background()); err != nil {
+			t.Fatalf("Failed to stop server: %v", err)
+		}
+	})
+	return addrPort.String()
+}
+
+func newClient(t *testing.T, config client.Config) client.Client {
+	t.Helper()
+	c, err := client.New(config)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return c
+}
+
+func TestClient(t *testing.T) {
+	server := startServer(t)
+	ctx := t.Context()
+
+	for _, config := range []client.Config{
+		{Server: server, KeyName: "test", Secret: testSecret},
+		{Server: server, KeyName: "test", Secret: testSecret, Algorithm: "HMACSHA512", TCP: true},
+	} {
+		t.Run(config.Algorithm, func(t *testing.T) {
+			c := newClient(t, config)
+			// A value longer than a single TXT string must survive the round trip.
+			long := strings.Repeat("x", 300)
+			for _, value := range []string{"first", long} {
+				if err := c.Present(ctx, "example.com", value); err != nil {
+					t.Fatalf("Present failed: %v", err)
+				}
+			}
+			answers, err := c.Answers(ctx, "_acme-challenge.example.com.")
+			if err != nil {
+				t.Fatalf("Failed to query answers: %v", err)
+			}
+			if !slices.Equal(answers, []string{"first", long}) {
+				t.Fatalf("Incorrect answers after present: %v", answers)
+			}
+			for _, value := range []string{"first", "first", long} {
+				if err := c.CleanUp(ctx, "example.com", value); err != nil {
+					t.Fatalf("CleanUp failed: %v", err)
+				}
+			}
+			if answers, err = c.Answers(ctx, "example.com"); err != nil || len(answers) != 0 {
+				t.Fatalf("Incorrect answers after cleanup: %v (%v)", answers, err)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	server := startServer(t)
+	ctx := t.Context()
+
+	wrongSecret := base64.StdEncoding.EncodeToString([]byte("wrong"))
+	for _, testCase := range []struct {
+		name   string
+		config client.Config
+		zone   string
+	}{
+		{"wrong secret", client.Config{Server: server, KeyName: "test", Secret: wrongSecret}, "example.com"},
+		{"unknown key", client.Config{Server: server, KeyName: "unknown", Secret: testSecret}, "example.com"},
+		{"zone not bound", client.Config{Server: server, KeyName: "test", Secret: testSecret}, "example.org"},
+		{"unknown zone", client.Config{Server: server, KeyName: "test", Secret: testSecret}, "example.net"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := newClient(t, testCase.config).Present(ctx, testCase.zone, "value")
+			if !E.Is(err, client.ErrNotAuthorized) {
+				t.Fatalf("Expected a not authorized error, got %v", err)
+			}
+		})
+	}
+
+	c := newClient(t, client.Config{Server: server, KeyName: "test", Secret: testSecret})
+	if _, err := c.Answers(ctx, "example.net"); !E.Is(err, client.ErrRefused) {
+		t.Fatalf("Expected a refused error for an unknown zone, got %v", err)
+	}
+	if err := c.Present(ctx, "", "value"); !E.Is(err, client.ErrInvalidZone) {
+		t.Fatalf("Expected an invalid zone error, got %v", err)
+	}
+
+	if _, err := client.New(client.Config{Server: server, KeyName: "test", Secret: testSecret, Algorithm: "hmac-md5"}); !E.Is(err, client.ErrUnsupportedAlgorithm) {
+		t.Fatalf("Expected an unsupported algorithm error, got %v", err)
+	}
+	if _, err := client.New(client.Config{Server: server, KeyName: "test"}); !E.Is(err, client.ErrMissingKey) {
+		t.Fatalf("Expected a missing key error, got %v", err)
+	}
+}
+
+func TestClientWaitForPropagation(t *testing.T) {
+	server := startServer(t)
+	ctx := t.Context()
+	c := newClient(t, client.Config{
+		Server:                 server,
+		KeyName:                "test",
+		Secret:                 testSecret,
+		PropagationNameservers: []string{server},
+		PollInterval:           10 * time.Millisecond,
+	})
+
+	if err := c.Present(ctx, "example.com", "value"); err != nil {
+		t.Fatalf("Present failed: %v", err)
+	}
+	if err := c.WaitForPropagation(ctx, "example.com", "value"); err != nil {
+		t.Fatalf("WaitForPropagation failed: %v", err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if err := c.WaitForPropagation(timeoutCtx, "example.com", "missing"); !E.Is(err, client.ErrPropagationFailed) {
+		t.Fatalf("Expected a propagation error, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"encoding/base64"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const defaultTimeout = 10 * time.Second
+const defaultPollInterval = 2 * time.Second
+
+type Config struct {
+	// Server is the address of the DNS4ACME server in the host:port form. This field is required.
+	Server string
+
+	// KeyName is the name of the update key. This field is required.
+	KeyName string
+
+	// Secret is the base64-encoded secret of the update key. This field is required.
+	Secret string
+
+	// Algorithm is the TSIG algorithm used to sign updates, either hmac-sha256 or hmac-sha512. Defaults to
+	// hmac-sha256.
+	Algorithm string
+
+	// TCP sends all messages over TCP. By default, messages are sent over UDP and retried over TCP if the response is
+	// truncated.
+	TCP bool
+
+	// Timeout is the maximum time to wait for a single response. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// PropagationNameservers are the addresses in the host:port form WaitForPropagation checks. By default, the
+	// nameservers the DNS4ACME server returns for the zone are checked on port 53.
+	PropagationNameservers []string
+
+	// PollInterval is the time WaitForPropagation waits between checks. Defaults to 2 seconds.
+	PollInterval time.Duration
+}
+
+func (c Config) Validate() error {
+	if c.Server == "" {
+		return ErrInvalidConfiguration.Wrap(ErrMissingServer)
+	}
+	if c.KeyName == "" || c.Secret == "" {
+		return ErrInvalidConfiguration.Wrap(ErrMissingKey)
+	}
+	if _, ok := dns.IsDomainName(c.KeyName); !ok {
+		return ErrInvalidConfiguration.Wrap(ErrMissingKey).WithAttr(slog.String("key", c.KeyName))
+	}
+	if _, err := base64.StdEncoding.DecodeString(c.Secret); err != nil {
+		return ErrInvalidConfiguration.Wrap(ErrInvalidSecret.Wrap(err))
+	}
+	if _, err := c.algorithm(); err != nil {
+		return ErrInvalidConfiguration.Wrap(err)
+	}
+	if c.Timeout < 0 || c.PollInterval < 0 {
+		return ErrInvalidConfiguration.Wrap(ErrInvalidTimeout)
+	}
+	return nil
+}
+
+// algorithm returns the canonical name of the configured TSIG algorithm. The names used by nsupdate (hmac-sha256) and
+// cert-manager (HMACSHA256) are both accepted.
+func (c Config) algorithm() (string, error) {
+	switch strings.ReplaceAll(strings.TrimSuffix(strings.ToLower(c.Algorithm), "."), "-", "") {
+	case "", "hmacsha256":
+		return dns.HmacSHA256, nil
+	case "hmacsha512":
+		return dns.HmacSHA512, nil
+	default:
+		return "", ErrUnsupportedAlgorithm.WithAttr(slog.String("algorithm", c.Algorithm))
+	}
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"github.com/dns4acme/dns4acme/lang/E"
+	"github.com/miekg/dns"
+)
+
+var ErrInvalidConfiguration = E.New("CLIENT_INVALID_CONFIGURATION", "invalid DNS4ACME client configuration")
+var ErrMissingServer = E.New("CLIENT_MISSING_SERVER", "the DNS4ACME server address is required")
+var ErrMissingKey = E.New("CLIENT_MISSING_KEY", "the update key name and secret are required")
+var ErrInvalidSecret = E.New("CLIENT_INVALID_SECRET", "the update key secret must be base64-encoded")
+var ErrUnsupportedAlgorithm = E.New("CLIENT_UNSUPPORTED_ALGORITHM", "unsupported TSIG algorithm, must be hmac-sha256 or hmac-sha512")
+var ErrInvalidTimeout = E.New("CLIENT_INVALID_TIMEOUT", "the timeout and poll interval must not be negative")
+var ErrInvalidZone = E.New("CLIENT_INVALID_ZONE", "invalid zone name")
+var ErrInvalidValue = E.New("CLIENT_INVALID_VALUE", "the challenge answer must not be empty")
+
+var ErrExchangeFailed = E.New("CLIENT_EXCHANGE_FAILED", "failed to send the DNS message to the server")
+var ErrPropagationFailed = E.New("CLIENT_PROPAGATION_FAILED", "the challenge answer did not propagate to all nameservers")
+var ErrNoNameservers = E.New("CLIENT_NO_NAMESERVERS", "the server did not return any nameservers for the zone")
+
+// The errors below correspond to the response codes the DNS4ACME server sends.
+
+var ErrNotAuthorized = E.New("CLIENT_NOT_AUTHORIZED", "the server rejected the update key; check the secret, the zone binding and the allowed networks of the key")
+var ErrRefused = E.New("CLIENT_REFUSED", "the server refused the request; the zone may not exist")
+var ErrServerFailure = E.New("CLIENT_SERVER_FAILURE", "the server failed to process the request")
+var ErrFormatError = E.New("CLIENT_FORMAT_ERROR", "the server could not parse the request")
+var ErrNotImplemented = E.New("CLIENT_NOT_IMPLEMENTED", "the server does not support the request")
+var ErrUnexpectedRcode = E.New("CLIENT_UNEXPECTED_RCODE", "the server responded with an unexpected response code")
+
+// rcodeErrors maps DNS response codes to the errors returned to the caller.
+var rcodeErrors = map[int]E.Error{
+	dns.RcodeNotAuth:        ErrNotAuthorized,
+	dns.RcodeRefused:        ErrRefused,
+	dns.RcodeServerFailure:  ErrServerFailure,
+	dns.RcodeFormatError:    ErrFormatError,
+	dns.RcodeNotImplemented: ErrNotImplemented,
+	dns.RcodeBadSig:         ErrNotAuthorized,
+	dns.RcodeBadKey:         ErrNotAuthorized,
+	dns.RcodeBadTime:        ErrNotAuthorized,
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// backupResult is the output of the backend backup command.
+type backupResult struct {
+	File string `json:"file"`
+	Size int64  `json:"size"`
+}
+
+// compactResult is the output of the backend compact command.
+type compactResult struct {
+	SizeBefore int64 `json:"size_before"`
+	SizeAfter  int64 `json:"size_after"`
+}
+
+// maintenanceOptions are the options for commands backing up and compacting the backend.
+type maintenanceOptions struct {
+	manageOptions
+
+	ServerURL   string `config:"server-url" description:"Base URL of the HTTP API of a running DNS4ACME server, for example http://127.0.0.1:8080. The server runs the operation, which is required for backends only one process can open, such as bbolt. The backend is opened directly if not set."`
+	ServerToken string `config:"server-token" sensitive:"true" file:"true" description:"Token for the management API of the server."`
+}
+
+func newMaintenanceOptions() *maintenanceOptions {
+	return &maintenanceOptions{manageOptions: *newManageOptions()}
+}
+
+func newBackendCommand() *command {
+	return &command{
+		name:        "backend",
+		description: "Back up and compact the configured backend.",
+		subcommands: []*command{
+			newCommand("backup", "FILE", "Write a consistent copy of the backend to a file.", true, newMaintenanceOptions, runBackendBackup),
+			newCommand("compact", "", "Reclaim the space freed by deleted zones and keys.", true, newMaintenanceOptions, runBackendCompact),
+		},
+	}
+}
+
+// withMaintenanceBackend builds the backend, runs f if the backend supports backups and compaction, and closes the
+// backend afterwards.
+func withMaintenanceBackend(ctx context.Context, options *maintenanceOptions, f func(provider backend.MaintenanceProvider) error) error {
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		maintenanceProvider, ok := provider.(backend.MaintenanceProvider)
+		if !ok {
+			return backend.ErrMaintenanceNotSupported.WithAttr(slog.String("backend", options.Backend))
+		}
+		return f(maintenanceProvider)
+	})
+}
+
+// serverRequest sends a request to the management API of the server configured in options. The caller must close the
+// body of the returned response.
+func serverRequest(ctx context.Context, options *maintenanceOptions, method string, path string) (*http.Response, error) {
+	url := strings.TrimSuffix(options.ServerURL, "/") + "/api/v1" + path
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, ErrServerRequestFailed.Wrap(err).WithAttr(slog.String("url", url))
+	}
+	if options.ServerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+options.ServerToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, ErrServerRequestFailed.Wrap(err).WithAttr(slog.String("url", url))
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		var response struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		}
+		cause := fmt.Errorf("unexpected status: %s", resp.Status)
+		if err := json.NewDecoder(resp.Body).Decode(&response); err == nil && response.Code != "" {
+			cause = fmt.Errorf("%s: %s", response.Code, response.Message)
+		}
+		return nil, ErrServerRequestFailed.Wrap(cause).WithAttr(slog.String("url", url)).WithAttr(slog.Int("status", resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// writeFileAtomically writes a file using write and renames it into place once complete, so an interrupted write never
+// leaves a truncated file at path.
+func writeFileAtomically(path string, write func(w io.Writer) (int64, error)) (int64, error) {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, ErrWriteFailed.Wrap(err).WithAttr(slog.String("file", path))
+	}
+	tmpPath := file.Name()
+	written, err := write(file)
+	if err == nil {
+		if err = file.Sync(); err != nil {
+			err = ErrWriteFailed.Wrap(err).WithAttr(slog.String("file", path))
+		}
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = ErrWriteFailed.Wrap(closeErr).WithAttr(slog.String("file", path))
+	}
+	if err == nil {
+		if err = os.Rename(tmpPath, path); err != nil {
+			err = ErrWriteFailed.Wrap(err).WithAttr(slog.String("file", path))
+		}
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, err
+	}
+	return written, nil
+}
+
+func runBackendBackup(ctx context.Context, env *commandEnv, options *maintenanceOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	path := args[0]
+	written, err := writeFileAtomically(path, func(w io.Writer) (int64, error) {
+		if options.ServerURL != "" {
+			resp, err := serverRequest(ctx, options, http.MethodGet, "/backup")
+			if err != nil {
+				return 0, err
+			}
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			written, err := io.Copy(w, resp.Body)
+			if err != nil {
+				return 0, ErrServerRequestFailed.Wrap(err).WithAttr(slog.String("url", options.ServerURL))
+			}
+			return written, nil
+		}
+		var written int64
+		err := withMaintenanceBackend(ctx, options, func(provider backend.MaintenanceProvider) error {
+			var err error
+			written, err = provider.Backup(ctx, w)
+			return err
+		})
+		return written, err
+	})
+	if err != nil {
+		return err
+	}
+	return printResult(
+		env,
+		options.Output,
+		backupResult{File: path, Size: written},
+		fmt.Sprintf("Backup written to %s (%d bytes).", path, written),
+	)
+}
+
+func runBackendCompact(ctx context.Context, env *commandEnv, options *maintenanceOptions, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+	var result compactResult
+	if options.ServerURL != "" {
+		resp, err := serverRequest(ctx, options, http.MethodPost, "/compact")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return ErrServerRequestFailed.Wrap(err).WithAttr(slog.String("url", options.ServerURL))
+		}
+	} else {
+		err := withMaintenanceBackend(ctx, options, func(provider backend.MaintenanceProvider) error {
+			response, err := provider.Compact(ctx)
+			if err != nil {
+				return err
+			}
+			result = compactResult{SizeBefore: response.SizeBefore, SizeAfter: response.SizeAfter}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return printResult(
+		env,
+		options.Output,
+		result,
+		"Backend compacted.",
+		fmt.Sprintf("Size before: %d bytes", result.SizeBefore),
+		fmt.Sprintf("Size after: %d bytes", result.SizeAfter),
+	)
+}
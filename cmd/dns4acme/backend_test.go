@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+func TestBackendBackupNotSupported(t *testing.T) {
+	env := &commandEnv{stdout: &bytes.Buffer{}}
+	root := newRootCommand()
+	path := filepath.Join(t.TempDir(), "backup.db")
+	args := []string{"backend", "backup", path, "--backend", inmemory.ID}
+	if err := root.execute(context.Background(), env, []string{root.name}, args); !E.Is(err, backend.ErrMaintenanceNotSupported) {
+		t.Fatalf("Expected a maintenance not supported error, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("The failed backup left a file behind (%v)", err)
+	}
+}
+
+func TestBackendServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"code":"UNAUTHORIZED","message":"unauthorized"}`))
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/v1/backup":
+			_, _ = w.Write([]byte("backup"))
+		case "POST /api/v1/compact":
+			_, _ = w.Write([]byte(`{"size_before":200,"size_after":100}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	root := newRootCommand()
+	env := &commandEnv{stdout: &bytes.Buffer{}}
+	args := []string{"backend", "backup", path, "--server-url", server.URL + "/", "--server-token", "token"}
+	if err := root.execute(context.Background(), env, []string{root.name}, args); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "backup" {
+		t.Fatalf("Incorrect backup file: %q (%v)", data, err)
+	}
+
+	stdout := &bytes.Buffer{}
+	env = &commandEnv{stdout: stdout}
+	args = []string{"backend", "compact", "--server-url", server.URL, "--server-token", "token", "--output", "json"}
+	if err := root.execute(context.Background(), env, []string{root.name}, args); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+	var result compactResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode output: %v (%s)", err, stdout.String())
+	}
+	if result.SizeBefore != 200 || result.SizeAfter != 100 {
+		t.Fatalf("Incorrect result: %v", result)
+	}
+
+	args = []string{"backend", "compact", "--server-url", server.URL}
+	if err := root.execute(context.Background(), env, []string{root.name}, args); !E.Is(err, ErrServerRequestFailed) {
+		t.Fatalf("Expected a server request error without a token, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/dns4acme/dns4acme/internal/config"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// command is a subcommand of the dns4acme binary. A command either has subcommands or a run function.
+type command struct {
+	name string
+	// args describes the positional arguments in the help text, e.g. "ZONE".
+	args        string
+	description string
+	subcommands []*command
+	// newOptions returns a pointer to a new struct holding the options of the command. The struct is filled using
+	// config.Parser, so the same struct tags apply as for the server configuration.
+	newOptions func() any
+	// readConfig indicates that the options are also read from the configuration file and the environment variables,
+	// not just the command line. Commands working with the server configuration should set this.
+	readConfig bool
+	run        func(ctx context.Context, env *commandEnv, options any, args []string) error
+}
+
+// newCommand creates a command without subcommands and with typed options.
+func newCommand[T any](
+	name string,
+	args string,
+	description string,
+	readConfig bool,
+	newOptions func() *T,
+	run func(ctx context.Context, env *commandEnv, options *T, args []string) error,
+) *command {
+	return &command{
+		name:        name,
+		args:        args,
+		description: description,
+		newOptions: func() any {
+			return newOptions()
+		},
+		readConfig: readConfig,
+		run: func(ctx context.Context, env *commandEnv, options any, args []string) error {
+			return run(ctx, env, options.(*T), args)
+		},
+	}
+}
+
+// noOptions is the options struct for commands that don't take any options.
+type noOptions struct{}
+
+func newNoOptions() *noOptions {
+	return &noOptions{}
+}
+
+// commandEnv holds the state shared between the command framework and the command being run.
+type commandEnv struct {
+	stdout io.Writer
+	logger *slog.Logger
+	// configFile is the configuration file passed in --config or DNS4ACME_CONFIG.
+	configFile string
+	// flags contains the command line flags passed to the command. Commands can use it to reload their options.
+	flags []string
+	// parser is the parser that filled the command options.
+	parser *config.Parser
+}
+
+// execute runs the command or the subcommand selected by args. The path contains the names of the parent commands
+// and is used for help texts and error messages.
+func (c *command) execute(ctx context.Context, env *commandEnv, path []string, args []string) error {
+	if len(c.subcommands) > 0 {
+		if len(args) == 0 || isHelp(args[0]) || args[0] == "help" {
+			c.printHelp(env.stdout, path)
+			return nil
+		}
+		for _, subcommand := range c.subcommands {
+			if subcommand.name == args[0] {
+				return subcommand.execute(ctx, env, append(path, subcommand.name), args[1:])
+			}
+		}
+		return ErrUnknownCommand.WithAttr(slog.String("command", strings.Join(append(path, args[0]), " ")))
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if isHelp(arg) {
+			c.printHelp(env.stdout, path)
+			return nil
+		}
+	}
+
+	positional, flags := splitArgs(args)
+	options := c.newOptions()
+	parser, err := loadOptions(options, flags, env.configFile, c.readConfig)
+	if err != nil {
+		return err
+	}
+	env.flags = flags
+	env.parser = parser
+	return c.run(ctx, env, options, append(positional, parser.Args...))
+}
+
+func (c *command) printHelp(wr io.Writer, path []string) {
+	buf := &bytes.Buffer{}
+	usage := strings.Join(path, " ")
+	if len(c.subcommands) > 0 {
+		buf.WriteString("Usage: " + usage + " COMMAND [OPTIONS]\n\n")
+		if c.description != "" {
+			buf.WriteString(c.description + "\n\n")
+		}
+		buf.WriteString("Commands:\n")
+		maxLength := 0
+		for _, subcommand := range c.subcommands {
+			maxLength = max(maxLength, len(subcommand.name))
+		}
+		for _, subcommand := range c.subcommands {
+			buf.WriteString("  " + subcommand.name + strings.Repeat(" ", maxLength-len(subcommand.name)+2) + subcommand.description + "\n")
+		}
+		buf.WriteString("\nRun '" + usage + " COMMAND --help' for more information on a command.\n")
+		_, _ = wr.Write(buf.Bytes())
+		return
+	}
+	if c.args != "" {
+		usage += " " + c.args
+	}
+	buf.WriteString("Usage: " + usage + " [OPTIONS]\n\n")
+	if c.description != "" {
+		buf.WriteString(c.description + "\n\n")
+	}
+	help := config.New(c.newOptions()).CLIHelp()
+	if c.readConfig {
+		buf.WriteString("Options:\n")
+		buf.WriteString("  --config  Configuration file to load (.yaml, .yml, .json or .toml).\n")
+	} else if len(help) > 0 {
+		buf.WriteString("Options:\n")
+	}
+	buf.Write(help)
+	_, _ = wr.Write(buf.Bytes())
+}
+
+// loadOptions fills the options from the defaults, the configuration file, the environment and the command line, in
+// this order. The configuration file and the environment are only used if readConfig is true.
+func loadOptions(options any, flags []string, configFile string, readConfig bool) (*config.Parser, error) {
+	parser := config.New(options)
+	if err := parser.ApplyDefaults(); err != nil {
+		return nil, err
+	}
+	if readConfig {
+		if configFile != "" {
+			if err := parser.ApplyFile(configFile); err != nil {
+				return nil, err
+			}
+		}
+		if err := parser.ApplyEnv("DNS4ACME_", os.Environ()); err != nil {
+			return nil, err
+		}
+	}
+	if err := parser.ApplyCMD(append([]string{os.Args[0]}, flags...)); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// splitArgs separates the leading positional arguments from the flags. Positional arguments must come before the
+// first flag, or after the -- terminator.
+func splitArgs(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			return args[:i], args[i:]
+		}
+	}
+	return args, nil
+}
+
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "--help"
+}
+
+// requireArgs checks that the number of positional arguments is exactly n.
+func requireArgs(args []string, n int) error {
+	if len(args) != n {
+		return ErrInvalidArguments.
+			WithAttr(slog.Int("expected", n)).
+			WithAttr(slog.Int("got", len(args)))
+	}
+	return nil
+}
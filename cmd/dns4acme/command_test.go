@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+type testOptions struct {
+	Name string `config:"name" default:"default"`
+}
+
+func TestCommand_Execute(t *testing.T) {
+	var gotOptions *testOptions
+	var gotArgs []string
+	root := &command{
+		name: "test",
+		subcommands: []*command{
+			{
+				name: "group",
+				subcommands: []*command{
+					newCommand("leaf", "ARG", "Test command.", false, func() *testOptions {
+						return &testOptions{}
+					}, func(_ context.Context, _ *commandEnv, options *testOptions, args []string) error {
+						gotOptions = options
+						gotArgs = args
+						return nil
+					}),
+				},
+			},
+		},
+	}
+
+	stdout := &bytes.Buffer{}
+	env := &commandEnv{stdout: stdout}
+	if err := root.execute(context.Background(), env, []string{"test"}, []string{"group", "leaf", "a", "--name", "foo", "--", "--b"}); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+	if gotOptions == nil || gotOptions.Name != "foo" {
+		t.Fatalf("Incorrect options: %v", gotOptions)
+	}
+	if !slices.Equal(gotArgs, []string{"a", "--b"}) {
+		t.Fatalf("Incorrect arguments: %v", gotArgs)
+	}
+
+	if err := root.execute(context.Background(), env, []string{"test"}, []string{"group", "leaf", "--help"}); err != nil {
+		t.Fatalf("Failed to print help: %v", err)
+	}
+	if !strings.Contains(stdout.String(), "Usage: test group leaf ARG [OPTIONS]") || !strings.Contains(stdout.String(), "--name") {
+		t.Fatalf("Incorrect help text: %s", stdout.String())
+	}
+
+	err := root.execute(context.Background(), env, []string{"test"}, []string{"group", "nonexistent"})
+	if !E.Is(err, ErrUnknownCommand) {
+		t.Fatalf("Incorrect error for unknown command: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme"
+	"github.com/dns4acme/dns4acme/internal/config"
+	"log/slog"
+)
+
+func newConfigCommand() *command {
+	return &command{
+		name:        "config",
+		description: "Inspect and validate the configuration.",
+		subcommands: []*command{
+			newCommand(
+				"print",
+				"[yaml|json]",
+				"Print the effective configuration along with the source of each value. Secrets are redacted.",
+				true,
+				dns4acme.NewConfig,
+				runConfigPrint,
+			),
+			newCommand(
+				"validate",
+				"",
+				"Validate the configuration without starting the server or connecting to the backend.",
+				true,
+				dns4acme.NewConfig,
+				runConfigValidate,
+			),
+		},
+	}
+}
+
+func runConfigPrint(_ context.Context, env *commandEnv, _ *dns4acme.Config, args []string) error {
+	format := config.FileFormatYAML
+	switch len(args) {
+	case 0:
+	case 1:
+		format = config.FileFormat(args[0])
+	default:
+		return ErrInvalidArguments.WithAttr(slog.Int("expected", 1)).WithAttr(slog.Int("got", len(args)))
+	}
+	output, err := env.parser.Dump(format)
+	if err != nil {
+		return err
+	}
+	_, err = env.stdout.Write(output)
+	return err
+}
+
+func runConfigValidate(_ context.Context, env *commandEnv, cfg *dns4acme.Config, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	_, err := env.stdout.Write([]byte("The configuration is valid.\n"))
+	return err
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+var ErrUnknownCommand = E.New("UNKNOWN_COMMAND", "unknown command")
+var ErrInvalidArguments = E.New("INVALID_ARGUMENTS", "invalid number of arguments")
+var ErrInvalidOutputFormat = E.New("INVALID_OUTPUT_FORMAT", "invalid output format, must be text or json")
+var ErrManagementNotSupported = E.New("MANAGEMENT_NOT_SUPPORTED", "the selected backend does not support managing zones and keys")
+var ErrInvalidDebugState = E.New("INVALID_DEBUG_STATE", "invalid debug state, must be on or off")
+var ErrServerRequestFailed = E.New("SERVER_REQUEST_FAILED", "the request to the DNS4ACME server failed")
+var ErrWriteFailed = E.New("WRITE_FAILED", "failed to write the output file")
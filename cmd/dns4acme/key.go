@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/internal/secret"
+	"strings"
+)
+
+// keyResult is the output of commands returning an update key. The secret is only included when it has been set by
+// the command.
+type keyResult struct {
+	Key       string   `json:"key"`
+	Secret    string   `json:"secret,omitempty"`
+	Zones     []string `json:"zones"`
+	AllowFrom []string `json:"allow_from,omitempty"`
+}
+
+// keyListResult is the output of the key list command.
+type keyListResult struct {
+	Keys     []keyResult `json:"keys"`
+	Continue string      `json:"continue,omitempty"`
+}
+
+// keyBindingResult is the output of commands modifying the zone bindings of a key.
+type keyBindingResult struct {
+	Key    string `json:"key"`
+	Zone   string `json:"zone"`
+	Action string `json:"action"`
+}
+
+// keySecretOptions are the options for commands setting the secret of an update key.
+type keySecretOptions struct {
+	manageOptions
+
+	Secret string `config:"secret" sensitive:"true" file:"true" description:"Base64-encoded TSIG secret to use. A random secret is generated if not set."`
+}
+
+func newKeySecretOptions() *keySecretOptions {
+	return &keySecretOptions{manageOptions: *newManageOptions()}
+}
+
+// keyCreateOptions are the options for the key create command.
+type keyCreateOptions struct {
+	keySecretOptions
+
+	Bind      []string `config:"bind" description:"Zones to bind the new key to. Can be repeated."`
+	AllowFrom []string `config:"allow-from" description:"CIDR prefixes the new key may be used from. Can be repeated. The key may be used from anywhere if not set."`
+}
+
+func newKeyCreateOptions() *keyCreateOptions {
+	return &keyCreateOptions{keySecretOptions: *newKeySecretOptions()}
+}
+
+func newKeyCommand() *command {
+	return &command{
+		name:        "key",
+		description: "Manage the update keys in the configured backend.",
+		subcommands: []*command{
+			newCommand("create", "KEY", "Create a new update key and print its secret.", true, newKeyCreateOptions, runKeyCreate),
+			newCommand("delete", "KEY", "Delete an update key.", true, newManageOptions, runKeyDelete),
+			newCommand("list", "", "List the update keys and the zones they are bound to, ordered by name.", true, newListOptions, runKeyList),
+			newCommand("get", "KEY", "Show the zones an update key is bound to.", true, newManageOptions, runKeyGet),
+			newCommand("rotate", "KEY", "Replace the secret of an update key and print the new secret.", true, newKeySecretOptions, runKeyRotate),
+			newCommand("bind", "KEY ZONE", "Allow an update key to be used for a zone.", true, newManageOptions, runKeyBind),
+			newCommand("unbind", "KEY ZONE", "Remove the binding between an update key and a zone.", true, newManageOptions, runKeyUnbind),
+		},
+	}
+}
+
+func (o keySecretOptions) secret() (string, error) {
+	if o.Secret != "" {
+		if err := secret.Validate(o.Secret); err != nil {
+			return "", err
+		}
+		return o.Secret, nil
+	}
+	return secret.Generate()
+}
+
+// allowFromText formats the allowed networks of a key for text output.
+func allowFromText(allowFrom []string) string {
+	if len(allowFrom) == 0 {
+		return "anywhere"
+	}
+	return strings.Join(allowFrom, ", ")
+}
+
+func runKeyCreate(ctx context.Context, env *commandEnv, options *keyCreateOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	key := normalizeName(args[0])
+	secret, err := options.secret()
+	if err != nil {
+		return err
+	}
+	zones := make([]string, len(options.Bind))
+	for i, zone := range options.Bind {
+		zones[i] = normalizeName(zone)
+	}
+	if err := backend.ValidateAllowFrom(options.AllowFrom); err != nil {
+		return err
+	}
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.CreateKey(ctx, key, secret); err != nil {
+			return err
+		}
+		if err := configureKey(ctx, provider, key, options.AllowFrom, zones); err != nil {
+			// Don't leave a half-configured key behind, otherwise running the command again fails because the key
+			// already exists.
+			if deleteErr := provider.DeleteKey(ctx, key); deleteErr != nil {
+				return errors.Join(err, deleteErr)
+			}
+			return err
+		}
+		return printResult(
+			env,
+			options.Output,
+			keyResult{Key: key, Secret: secret, Zones: zones, AllowFrom: options.AllowFrom},
+			"Key "+key+" created.",
+			"Secret: "+secret,
+			"Zones: "+strings.Join(zones, ", "),
+			"Allow from: "+allowFromText(options.AllowFrom),
+		)
+	})
+}
+
+// configureKey sets the allowed networks of a newly created key and binds it to the zones.
+func configureKey(ctx context.Context, provider backend.ExtendedProvider, key string, allowFrom []string, zones []string) error {
+	if len(allowFrom) > 0 {
+		if err := provider.SetKeyAllowFrom(ctx, key, allowFrom); err != nil {
+			return err
+		}
+	}
+	for _, zone := range zones {
+		if err := provider.BindKey(ctx, key, zone); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runKeyDelete(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	key := normalizeName(args[0])
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.DeleteKey(ctx, key); err != nil {
+			return err
+		}
+		return printResult(env, options.Output, keyResult{Key: key, Zones: []string{}}, "Key "+key+" deleted.")
+	})
+}
+
+func runKeyGet(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	key := normalizeName(args[0])
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		keyData, err := provider.GetKey(ctx, key)
+		if err != nil {
+			return err
+		}
+		zones := keyData.Zones
+		if zones == nil {
+			zones = []string{}
+		}
+		return printResult(
+			env,
+			options.Output,
+			keyResult{Key: key, Zones: zones, AllowFrom: keyData.AllowFrom},
+			"Key "+key,
+			"Zones: "+strings.Join(zones, ", "),
+			"Allow from: "+allowFromText(keyData.AllowFrom),
+		)
+	})
+}
+
+func runKeyList(ctx context.Context, env *commandEnv, options *listOptions, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		response, err := provider.ListKeys(ctx, options.backendOptions())
+		if err != nil {
+			return err
+		}
+		result := keyListResult{
+			Keys:     make([]keyResult, len(response.Keys)),
+			Continue: response.Continue,
+		}
+		lines := make([]string, len(response.Keys))
+		for i, keyData := range response.Keys {
+			zones := keyData.Zones
+			if zones == nil {
+				zones = []string{}
+			}
+			result.Keys[i] = keyResult{Key: keyData.Name, Zones: zones}
+			lines[i] = keyData.Name + "\tzones=" + strings.Join(zones, ",")
+		}
+		if result.Continue != "" {
+			lines = append(lines, "More keys available, use --continue "+result.Continue+" to list them.")
+		}
+		return printResult(env, options.Output, result, fmt.Sprintf("%d keys", len(result.Keys)), lines...)
+	})
+}
+
+func runKeyRotate(ctx context.Context, env *commandEnv, options *keySecretOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	key := normalizeName(args[0])
+	secret, err := options.secret()
+	if err != nil {
+		return err
+	}
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.SetKeySecret(ctx, key, secret); err != nil {
+			return err
+		}
+		keyData, err := provider.GetKey(ctx, key)
+		if err != nil {
+			return err
+		}
+		zones := keyData.Zones
+		if zones == nil {
+			zones = []string{}
+		}
+		return printResult(
+			env,
+			options.Output,
+			keyResult{Key: key, Secret: secret, Zones: zones},
+			"Secret of key "+key+" rotated.",
+			"Secret: "+secret,
+		)
+	})
+}
+
+func runKeyBind(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 2); err != nil {
+		return err
+	}
+	key := normalizeName(args[0])
+	zone := normalizeName(args[1])
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.BindKey(ctx, key, zone); err != nil {
+			return err
+		}
+		return printResult(env, options.Output, keyBindingResult{Key: key, Zone: zone, Action: "bound"}, "Key "+key+" bound to zone "+zone+".")
+	})
+}
+
+func runKeyUnbind(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 2); err != nil {
+		return err
+	}
+	key := normalizeName(args[0])
+	zone := normalizeName(args[1])
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.UnbindKey(ctx, key, zone); err != nil {
+			return err
+		}
+		return printResult(env, options.Output, keyBindingResult{Key: key, Zone: zone, Action: "unbound"}, "Key "+key+" unbound from zone "+zone+".")
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/backend/registry"
+	"github.com/dns4acme/dns4acme/internal/secret"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// zonesBackendID selects an in-memory backend that already contains the zones the tests bind keys to. Each command
+// builds its own backend, so the zones cannot be created with the zone create command.
+const zonesBackendID = "inmemory-zones"
+
+type zonesDescriptor struct{}
+
+func (zonesDescriptor) Name() string {
+	return "In-Memory with zones"
+}
+
+func (zonesDescriptor) Description() string {
+	return "In-memory backend containing example.com and example.org."
+}
+
+func (zonesDescriptor) Config() backend.Config {
+	return &inmemory.Config{Zones: map[string]*backend.ProviderZoneResponse{
+		"example.com": {},
+		"example.org": {},
+	}}
+}
+
+// registerBackend registers a backend until the test ends. The commands look up their backend in the registry, so the
+// tests cannot pass it in directly.
+func registerBackend(t *testing.T, id string, descriptor backend.Descriptor) {
+	t.Helper()
+	if _, ok := registry.Backends[id]; ok {
+		t.Fatalf("Backend %s is already registered", id)
+	}
+	registry.Backends[id] = descriptor
+	t.Cleanup(func() {
+		delete(registry.Backends, id)
+	})
+}
+
+func TestKeyCreate(t *testing.T) {
+	registerBackend(t, zonesBackendID, zonesDescriptor{})
+	stdout := &bytes.Buffer{}
+	env := &commandEnv{stdout: stdout}
+	root := newRootCommand()
+	args := []string{"key", "create", "test.", "--bind", "example.com.", "--bind", "example.org", "--backend", zonesBackendID, "--output", "json"}
+	if err := root.execute(context.Background(), env, []string{root.name}, args); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	var result keyResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to decode output: %v (%s)", err, stdout.String())
+	}
+	if result.Key != "test" {
+		t.Fatalf("Incorrect key name: %s", result.Key)
+	}
+	if !slices.Equal(result.Zones, []string{"example.com", "example.org"}) {
+		t.Fatalf("Incorrect zones: %v", result.Zones)
+	}
+	secret, err := base64.StdEncoding.DecodeString(result.Secret)
+	if err != nil || len(secret) != 32 {
+		t.Fatalf("Incorrect generated secret: %s", result.Secret)
+	}
+}
+
+// sharedKeysBackendID selects an in-memory backend whose keys outlive the command, so tests can check what a command
+// left behind.
+const sharedKeysBackendID = "inmemory-shared-keys"
+
+type sharedKeysDescriptor struct {
+	keys map[string]*backend.ProviderKeyResponse
+}
+
+func (sharedKeysDescriptor) Name() string {
+	return "In-Memory with shared keys"
+}
+
+func (sharedKeysDescriptor) Description() string {
+	return "In-memory backend containing example.com, keeping the keys between commands."
+}
+
+func (d sharedKeysDescriptor) Config() backend.Config {
+	return &inmemory.Config{
+		Keys:  d.keys,
+		Zones: map[string]*backend.ProviderZoneResponse{"example.com": {}},
+	}
+}
+
+func TestKeyCreate_BindFailed(t *testing.T) {
+	sharedKeys := map[string]*backend.ProviderKeyResponse{}
+	registerBackend(t, sharedKeysBackendID, sharedKeysDescriptor{keys: sharedKeys})
+	env := &commandEnv{stdout: &bytes.Buffer{}}
+	root := newRootCommand()
+	args := []string{"key", "create", "Test.", "--bind", "example.com", "--bind", "example.net", "--backend", sharedKeysBackendID}
+	if err := root.execute(context.Background(), env, []string{root.name}, args); err == nil {
+		t.Fatalf("Binding to a nonexistent zone did not fail.")
+	}
+	if len(sharedKeys) != 0 {
+		t.Fatalf("The key has not been deleted after the failed bind: %v", sharedKeys)
+	}
+}
+
+func TestKeyCreate_InvalidSecret(t *testing.T) {
+	sharedKeys := map[string]*backend.ProviderKeyResponse{}
+	registerBackend(t, sharedKeysBackendID, sharedKeysDescriptor{keys: sharedKeys})
+	env := &commandEnv{stdout: &bytes.Buffer{}}
+	root := newRootCommand()
+	args := []string{"key", "create", "test", "--secret", "not base64!", "--backend", sharedKeysBackendID}
+	if err := root.execute(context.Background(), env, []string{root.name}, args); !E.Is(err, secret.ErrInvalidSecret) {
+		t.Fatalf("Expected an invalid secret error, got %v", err)
+	}
+	if len(sharedKeys) != 0 {
+		t.Fatalf("A key has been created with an invalid secret: %v", sharedKeys)
+	}
+}
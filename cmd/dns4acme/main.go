@@ -3,53 +3,79 @@ package main
 import (
 	"context"
 	"errors"
-	"github.com/dns4acme/dns4acme"
 	"github.com/dns4acme/dns4acme/internal/config"
 	"github.com/dns4acme/dns4acme/lang/E"
 	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 )
 
-func main() {
-	cfg := dns4acme.NewConfig()
-	configParser := config.New(cfg)
-	if len(os.Args) == 2 && (os.Args[1] == "-h" || os.Args[1] == "--help") {
-		_, _ = os.Stdout.Write([]byte("Usage: ./dns4acme [OPTIONS]\n\nOptions:\n"))
-		_, _ = os.Stdout.Write(configParser.CLIHelp())
-		os.Exit(0)
+func newRootCommand() *command {
+	return &command{
+		name:        "dns4acme",
+		description: "DNS4ACME is a DNS server for ACME DNS-01 challenges. Running dns4acme without a command starts the server.",
+		subcommands: []*command{
+			newServeCommand(),
+			newZoneCommand(),
+			newKeyCommand(),
+			newBackendCommand(),
+			newConfigCommand(),
+			newVersionCommand(),
+		},
 	}
+}
+
+func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
-	if err := configParser.ApplyDefaults(); err != nil {
+	args, configFile, err := config.ExtractFileOption(os.Args[1:], "config")
+	if err != nil {
 		fatal(logger, err)
 	}
-	if err := configParser.ApplyEnv("DNS4ACME_", os.Environ()); err != nil {
-		fatal(logger, err)
+	if configFile == "" {
+		configFile = os.Getenv("DNS4ACME_CONFIG")
 	}
-	if err := configParser.ApplyCMD(os.Args); err != nil {
-		fatal(logger, err)
+	// Running without a command starts the server to stay compatible with existing deployments.
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !isHelp(args[0])) {
+		args = append([]string{"serve"}, args...)
 	}
-	ctx := context.Background()
-	srv, err := dns4acme.New(ctx, cfg, os.Stdout)
-	if err != nil {
-		fatal(logger, err)
+	if args[0] == "serve" {
+		// --print-config is kept as a shorthand for the config print command.
+		flags, printFormat, printConfig := extractPrintConfig(args[1:])
+		if printConfig {
+			args = append([]string{"config", "print", string(printFormat)}, flags...)
+		}
 	}
-	runningSrv, err := srv.Start(ctx)
-	if err != nil {
-		fatal(logger, err)
+
+	root := newRootCommand()
+	env := &commandEnv{
+		stdout:     os.Stdout,
+		logger:     logger,
+		configFile: configFile,
 	}
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-	<-done
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-	if err := runningSrv.Stop(ctx); err != nil {
+	if err := root.execute(context.Background(), env, []string{root.name}, args); err != nil {
 		fatal(logger, err)
 	}
 }
 
+// extractPrintConfig removes the --print-config flag from the command line and returns the requested output format.
+func extractPrintConfig(args []string) ([]string, config.FileFormat, bool) {
+	result := make([]string, 0, len(args))
+	format := config.FileFormatYAML
+	found := false
+	for _, arg := range args {
+		switch {
+		case arg == "--print-config":
+			found = true
+		case strings.HasPrefix(arg, "--print-config="):
+			found = true
+			format = config.FileFormat(strings.TrimPrefix(arg, "--print-config="))
+		default:
+			result = append(result, arg)
+		}
+	}
+	return result, format, found
+}
+
 func fatal(logger *slog.Logger, err error) {
 	var typedErr E.Error
 	var attrs []any
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/dns4acme/dns4acme"
+	"github.com/dns4acme/dns4acme/backend"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// outputFormat selects how management commands print their results.
+type outputFormat string
+
+const (
+	outputFormatText outputFormat = "text"
+	outputFormatJSON outputFormat = "json"
+)
+
+func (o *outputFormat) UnmarshalText(text []byte) error {
+	switch outputFormat(text) {
+	case outputFormatText, outputFormatJSON:
+		*o = outputFormat(text)
+		return nil
+	default:
+		return ErrInvalidOutputFormat.WithAttr(slog.String("output", string(text)))
+	}
+}
+
+func (o outputFormat) MarshalText() ([]byte, error) {
+	return []byte(o), nil
+}
+
+// manageOptions are the options shared by all commands that manage zones and keys in the backend.
+type manageOptions struct {
+	dns4acme.Config
+
+	Output outputFormat `config:"output" default:"text" description:"Output format of management commands, text or json."`
+}
+
+func newManageOptions() *manageOptions {
+	return &manageOptions{Config: *dns4acme.NewConfig()}
+}
+
+// listOptions are the options for commands listing zones or keys.
+type listOptions struct {
+	manageOptions
+
+	Limit    int    `config:"limit" default:"0" description:"Maximum number of items to return. 0 returns all items."`
+	Continue string `config:"continue" description:"Continue token returned by the previous page."`
+}
+
+func newListOptions() *listOptions {
+	return &listOptions{manageOptions: *newManageOptions()}
+}
+
+func (o listOptions) backendOptions() backend.ListOptions {
+	return backend.ListOptions{
+		Limit:    o.Limit,
+		Continue: o.Continue,
+	}
+}
+
+// buildExtendedBackend builds the configured backend with management capabilities.
+func buildExtendedBackend(ctx context.Context, cfg dns4acme.Config) (backend.ExtendedProvider, error) {
+	backendConfig, ok := cfg.BackendConfigs[cfg.Backend]
+	if !ok {
+		return nil, backend.ErrConfiguration.Wrap(fmt.Errorf("backend %s does not exist", cfg.Backend))
+	}
+	extendedConfig, ok := backendConfig.(backend.ExtendedConfig)
+	if !ok {
+		return nil, ErrManagementNotSupported.WithAttr(slog.String("backend", cfg.Backend))
+	}
+	return extendedConfig.BuildExtended(ctx)
+}
+
+// withBackend builds the backend, runs f, and closes the backend afterwards.
+func withBackend(ctx context.Context, cfg dns4acme.Config, f func(provider backend.ExtendedProvider) error) error {
+	provider, err := buildExtendedBackend(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		_ = provider.Close(closeCtx)
+	}()
+	return f(provider)
+}
+
+// printResult prints the result of a management command. The JSON output contains the result object, the text output
+// the message followed by the details.
+func printResult(env *commandEnv, format outputFormat, result any, message string, details ...string) error {
+	if format == outputFormatJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = env.stdout.Write(append(data, '\n'))
+		return err
+	}
+	text := message + "\n"
+	for _, detail := range details {
+		text += detail + "\n"
+	}
+	_, err := env.stdout.Write([]byte(text))
+	return err
+}
+
+// normalizeName lowercases zone and key names and removes the trailing dot, matching how the server looks them up.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"github.com/dns4acme/dns4acme"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func newServeCommand() *command {
+	return newCommand(
+		"serve",
+		"",
+		"Start the DNS server. This is the default if no command is given.",
+		true,
+		dns4acme.NewConfig,
+		runServe,
+	)
+}
+
+func runServe(ctx context.Context, env *commandEnv, cfg *dns4acme.Config, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+	srv, err := dns4acme.New(ctx, cfg, env.stdout)
+	if err != nil {
+		return err
+	}
+	runningSrv, err := srv.Start(ctx)
+	if err != nil {
+		return err
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := range signals {
+		if sig != syscall.SIGHUP {
+			break
+		}
+		reload(ctx, env, runningSrv)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return runningSrv.Stop(ctx)
+}
+
+// reload re-reads the configuration and applies it to the running server. Errors are logged and the previous
+// configuration stays in effect.
+func reload(ctx context.Context, env *commandEnv, runningSrv dns4acme.RunningServer) {
+	env.logger.InfoContext(ctx, "Reloading configuration...")
+	cfg := dns4acme.NewConfig()
+	if _, err := loadOptions(cfg, env.flags, env.configFile, true); err != nil {
+		env.logger.ErrorContext(ctx, "Configuration reload failed, keeping previous configuration", E.ToSLogAttr(err)...)
+		return
+	}
+	restartRequired, err := runningSrv.Reload(ctx, cfg)
+	if err != nil {
+		env.logger.ErrorContext(ctx, "Configuration reload failed, keeping previous configuration", E.ToSLogAttr(err)...)
+		return
+	}
+	if len(restartRequired) > 0 {
+		env.logger.WarnContext(ctx, "Some configuration changes require a restart to take effect", slog.Any("options", restartRequired))
+	}
+}
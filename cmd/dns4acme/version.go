@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// These variables are set at build time by goreleaser using -ldflags "-X main.version=...".
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+func newVersionCommand() *command {
+	return newCommand(
+		"version",
+		"",
+		"Print the version of DNS4ACME.",
+		false,
+		newNoOptions,
+		runVersion,
+	)
+}
+
+func runVersion(_ context.Context, env *commandEnv, _ *noOptions, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+	v, c, d := version, commit, date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" && info.Main.Version != "" {
+			v = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && c == "":
+				c = setting.Value
+			case setting.Key == "vcs.time" && d == "":
+				d = setting.Value
+			}
+		}
+	}
+	if v == "" {
+		v = "(devel)"
+	}
+	_, err := fmt.Fprintf(env.stdout, "dns4acme %s\ncommit: %s\nbuilt: %s\ngo: %s %s/%s\n", v, c, d, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return err
+}
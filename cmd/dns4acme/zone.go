@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/dns4acme/dns4acme/backend"
+	"log/slog"
+	"strings"
+)
+
+// zoneResult is the output of commands returning a zone.
+type zoneResult struct {
+	Zone                 string   `json:"zone"`
+	Serial               uint32   `json:"serial"`
+	Debug                bool     `json:"debug"`
+	ACMEChallengeAnswers []string `json:"acme_challenge_answers"`
+}
+
+// zoneListResult is the output of the zone list command.
+type zoneListResult struct {
+	Zones    []zoneResult `json:"zones"`
+	Continue string       `json:"continue,omitempty"`
+}
+
+// zoneActionResult is the output of commands modifying a zone.
+type zoneActionResult struct {
+	Zone   string `json:"zone"`
+	Action string `json:"action"`
+}
+
+func newZoneResult(zone string, zoneData backend.ProviderZoneResponse) zoneResult {
+	result := zoneResult{
+		Zone:                 zone,
+		Serial:               zoneData.Serial,
+		Debug:                zoneData.Debug,
+		ACMEChallengeAnswers: zoneData.ACMEChallengeAnswers,
+	}
+	if result.ACMEChallengeAnswers == nil {
+		result.ACMEChallengeAnswers = []string{}
+	}
+	return result
+}
+
+func newZoneCommand() *command {
+	return &command{
+		name:        "zone",
+		description: "Manage the zones in the configured backend.",
+		subcommands: []*command{
+			newCommand("create", "ZONE", "Create a new zone.", true, newManageOptions, runZoneCreate),
+			newCommand("delete", "ZONE", "Delete a zone and remove all key bindings to it.", true, newManageOptions, runZoneDelete),
+			newCommand("list", "", "List the zones ordered by name.", true, newListOptions, runZoneList),
+			newCommand("get", "ZONE", "Show the serial, debug state and current ACME challenge answers of a zone.", true, newManageOptions, runZoneGet),
+			newCommand("debug", "ZONE on|off", "Turn debug logging on or off for a zone.", true, newManageOptions, runZoneDebug),
+		},
+	}
+}
+
+func runZoneCreate(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	zone := normalizeName(args[0])
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.CreateZone(ctx, zone); err != nil {
+			return err
+		}
+		return printResult(env, options.Output, zoneActionResult{Zone: zone, Action: "created"}, "Zone "+zone+" created.")
+	})
+}
+
+func runZoneDelete(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	zone := normalizeName(args[0])
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.DeleteZone(ctx, zone); err != nil {
+			return err
+		}
+		return printResult(env, options.Output, zoneActionResult{Zone: zone, Action: "deleted"}, "Zone "+zone+" deleted.")
+	})
+}
+
+func runZoneGet(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 1); err != nil {
+		return err
+	}
+	zone := normalizeName(args[0])
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		zoneData, err := provider.GetZone(ctx, zone)
+		if err != nil {
+			return err
+		}
+		result := newZoneResult(zone, zoneData)
+		return printResult(
+			env,
+			options.Output,
+			result,
+			"Zone "+zone,
+			fmt.Sprintf("Serial: %d", result.Serial),
+			fmt.Sprintf("Debug: %t", result.Debug),
+			"ACME challenge answers: "+strings.Join(result.ACMEChallengeAnswers, ", "),
+		)
+	})
+}
+
+func runZoneList(ctx context.Context, env *commandEnv, options *listOptions, args []string) error {
+	if err := requireArgs(args, 0); err != nil {
+		return err
+	}
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		response, err := provider.ListZones(ctx, options.backendOptions())
+		if err != nil {
+			return err
+		}
+		result := zoneListResult{
+			Zones:    make([]zoneResult, len(response.Zones)),
+			Continue: response.Continue,
+		}
+		lines := make([]string, len(response.Zones))
+		for i, zoneData := range response.Zones {
+			result.Zones[i] = newZoneResult(zoneData.Name, zoneData.ProviderZoneResponse)
+			lines[i] = fmt.Sprintf("%s\tserial=%d\tdebug=%t", zoneData.Name, zoneData.Serial, zoneData.Debug)
+		}
+		if result.Continue != "" {
+			lines = append(lines, "More zones available, use --continue "+result.Continue+" to list them.")
+		}
+		return printResult(env, options.Output, result, fmt.Sprintf("%d zones", len(result.Zones)), lines...)
+	})
+}
+
+func runZoneDebug(ctx context.Context, env *commandEnv, options *manageOptions, args []string) error {
+	if err := requireArgs(args, 2); err != nil {
+		return err
+	}
+	zone := normalizeName(args[0])
+	var debug bool
+	switch args[1] {
+	case "on":
+		debug = true
+	case "off":
+		debug = false
+	default:
+		return ErrInvalidDebugState.WithAttr(slog.String("state", args[1]))
+	}
+	return withBackend(ctx, options.Config, func(provider backend.ExtendedProvider) error {
+		if err := provider.SetZoneDebug(ctx, zone, debug); err != nil {
+			return err
+		}
+		return printResult(
+			env,
+			options.Output,
+			zoneActionResult{Zone: zone, Action: "debug-" + args[1]},
+			"Debugging turned "+args[1]+" for zone "+zone+".",
+		)
+	})
+}
@@ -2,7 +2,10 @@ package dns4acme
 
 import (
 	"fmt"
+	"github.com/dns4acme/dns4acme/api"
 	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/dns4acme/dns4acme/backend/middleware"
 	"github.com/dns4acme/dns4acme/backend/registry"
 	"github.com/dns4acme/dns4acme/core"
 	"log/slog"
@@ -30,8 +33,32 @@ type Config struct {
 	core.Config
 	BackendConfigs
 
-	Log     LogConfig `config:"log"`
-	Backend string    `config:"backend" description:"Select the backend to use"`
+	Log             LogConfig         `config:"log"`
+	API             api.Config        `config:"api"`
+	Backend         string            `config:"backend" description:"Select the backend to use"`
+	BackendCache    cache.Config      `config:"backend-cache"`
+	BackendRequests middleware.Config `config:"backend-requests"`
+}
+
+// Validate checks the server configuration and the backend selection. It does not connect to the backend.
+func (c Config) Validate() error {
+	backendConfig, ok := c.BackendConfigs[c.Backend]
+	if !ok {
+		return core.ErrInvalidConfiguration.Wrap(fmt.Errorf("backend %s does not exist", c.Backend))
+	}
+	if err := c.BackendRequests.Validate(); err != nil {
+		return core.ErrInvalidConfiguration.Wrap(err)
+	}
+	if err := c.BackendCache.Validate(); err != nil {
+		return core.ErrInvalidConfiguration.Wrap(err)
+	}
+	if err := c.API.Validate(); err != nil {
+		return core.ErrInvalidConfiguration.Wrap(err)
+	}
+	if _, ok := backendConfig.(backend.ExtendedConfig); c.API.Enabled() && !ok {
+		return core.ErrInvalidConfiguration.Wrap(fmt.Errorf("backend %s does not support the HTTP API", c.Backend))
+	}
+	return c.Config.Validate()
 }
 
 type LogConfig struct {
@@ -4,6 +4,7 @@ import (
 	"log/slog"
 	"net/netip"
 
+	"github.com/dns4acme/dns4acme/dnstap"
 	"github.com/miekg/dns"
 )
 
@@ -16,6 +17,9 @@ type Config struct {
 
 	// DebugZoneNotFound enables logging a debug message if a queried zone was not found.
 	DebugZoneNotFound bool `config:"debug-zone-not-found" description:"Debug if a zone queried was not found."`
+
+	// Dnstap configures the optional dnstap output for queries and updates.
+	Dnstap dnstap.Config `config:"dnstap"`
 }
 
 func (c Config) Validate() error {
@@ -30,5 +34,8 @@ func (c Config) Validate() error {
 			return ErrInvalidConfiguration.Wrap(ErrInvalidNameserver).WithAttr(slog.Int("item", i))
 		}
 	}
+	if err := c.Dnstap.Validate(); err != nil {
+		return ErrInvalidConfiguration.Wrap(err)
+	}
 	return nil
 }
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"net"
+	"time"
+
+	"github.com/dns4acme/dns4acme/dnstap"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"github.com/miekg/dns"
+)
+
+// tapQuery sends the incoming message to the dnstap output and returns a response writer that sends the response
+// to the dnstap output once it has been written.
+func (r runningServer) tapQuery(writer dns.ResponseWriter, msg *dns.Msg) dns.ResponseWriter {
+	queryTime := time.Now()
+	query, err := msg.Pack()
+	if err != nil {
+		r.logger.DebugContext(r.ctx, "Cannot pack query for dnstap", E.ToSLogAttr(err)...)
+		return writer
+	}
+	protocol := dnstap.SocketProtocolUDP
+	if _, ok := writer.RemoteAddr().(*net.TCPAddr); ok {
+		protocol = dnstap.SocketProtocolTCP
+	}
+	// Updates are logged like queries by default, since that is what most dnstap consumers expect.
+	queryType, responseType := dnstap.MessageTypeClientQuery, dnstap.MessageTypeAuthResponse
+	if msg.Opcode == dns.OpcodeUpdate && r.config.Load().Dnstap.UpdateMessageTypes {
+		queryType, responseType = dnstap.MessageTypeUpdateQuery, dnstap.MessageTypeUpdateResponse
+	}
+	r.dnstap.Log(dnstap.Message{
+		Type:            queryType,
+		Protocol:        protocol,
+		QueryAddress:    writer.RemoteAddr(),
+		ResponseAddress: writer.LocalAddr(),
+		QueryTime:       queryTime,
+		QueryMessage:    query,
+	})
+	return &dnstapResponseWriter{
+		ResponseWriter: writer,
+		runningServer:  r,
+		protocol:       protocol,
+		responseType:   responseType,
+		queryTime:      queryTime,
+		query:          query,
+	}
+}
+
+type dnstapResponseWriter struct {
+	dns.ResponseWriter
+	runningServer runningServer
+	protocol      dnstap.SocketProtocol
+	responseType  dnstap.MessageType
+	queryTime     time.Time
+	query         []byte
+}
+
+func (w *dnstapResponseWriter) WriteMsg(msg *dns.Msg) error {
+	if err := w.ResponseWriter.WriteMsg(msg); err != nil {
+		return err
+	}
+	response, err := msg.Pack()
+	if err != nil {
+		w.runningServer.logger.DebugContext(w.runningServer.ctx, "Cannot pack response for dnstap", E.ToSLogAttr(err)...)
+		return nil
+	}
+	w.runningServer.dnstap.Log(dnstap.Message{
+		Type:            w.responseType,
+		Protocol:        w.protocol,
+		QueryAddress:    w.RemoteAddr(),
+		ResponseAddress: w.LocalAddr(),
+		QueryTime:       w.queryTime,
+		QueryMessage:    w.query,
+		ResponseTime:    time.Now(),
+		ResponseMessage: response,
+	})
+	return nil
+}
@@ -3,11 +3,16 @@ package core
 import (
 	"context"
 	"log/slog"
+	"net"
+	"net/netip"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/dnstap"
 	"github.com/dns4acme/dns4acme/lang/E"
 	"github.com/miekg/dns"
 )
@@ -35,6 +40,9 @@ type Server interface {
 
 type RunningServer interface {
 	Stop(ctx context.Context) error
+	// Reload validates the new configuration and applies the changes that can be applied without restarting the
+	// server. Changes that require a restart are not applied, the names of the affected options are returned instead.
+	Reload(ctx context.Context, config Config) ([]string, error)
 }
 
 type server struct {
@@ -46,13 +54,22 @@ type server struct {
 func (s server) Start(ctx context.Context) (RunningServer, error) {
 	srv := &runningServer{
 		ctx:               ctx,
-		config:            s.config,
+		config:            &atomic.Pointer[Config]{},
 		backend:           s.backend,
 		logger:            s.logger,
 		dnsServersRunning: map[*dns.Server]bool{},
 		dnsServersClose:   map[*dns.Server]chan struct{}{},
 		dnsServerLocks:    map[*dns.Server]*sync.Mutex{},
 	}
+	config := s.config
+	srv.config.Store(&config)
+	if s.config.Dnstap.Enabled() {
+		dnstapOutput, err := dnstap.New(s.config.Dnstap, s.logger)
+		if err != nil {
+			return nil, err
+		}
+		srv.dnstap = dnstapOutput
+	}
 	for _, proto := range []string{"tcp", "udp"} {
 		s.logger.DebugContext(
 			ctx,
@@ -131,22 +148,26 @@ func (s server) Start(ctx context.Context) (RunningServer, error) {
 			slog.String("address", s.config.Listen.String()),
 		)
 	}
-	s.logger.InfoContext(ctx, "DNS4ACME running", slog.String("listen", srv.config.Listen.String()))
+	s.logger.InfoContext(ctx, "DNS4ACME running", slog.String("listen", s.config.Listen.String()))
 	return srv, nil
 }
 
 type runningServer struct {
 	ctx               context.Context
-	config            Config
+	config            *atomic.Pointer[Config]
 	backend           backend.Provider
 	dnsServers        []*dns.Server
 	dnsServersRunning map[*dns.Server]bool
 	dnsServersClose   map[*dns.Server]chan struct{}
 	dnsServerLocks    map[*dns.Server]*sync.Mutex
 	logger            *slog.Logger
+	dnstap            dnstap.Output
 }
 
 func (r runningServer) ServeDNS(writer dns.ResponseWriter, msg *dns.Msg) {
+	if r.dnstap != nil {
+		writer = r.tapQuery(writer, msg)
+	}
 	switch msg.Opcode {
 	case dns.OpcodeQuery:
 		r.serveQuery(r.ctx, writer, msg)
@@ -200,7 +221,8 @@ func (r runningServer) serveUpdate(ctx context.Context, writer dns.ResponseWrite
 		}
 		return
 	}
-	zone, err := r.getZone(ctx, msg.Question[0].Name)
+	// The answers are changed and written back, so they must not come from the cache.
+	zone, err := r.getZone(backend.BypassCache(ctx), msg.Question[0].Name)
 	if err != nil {
 		response.SetRcode(msg, dns.RcodeNotAuth)
 		response.Extra = append(response.Extra, tsig)
@@ -225,6 +247,17 @@ func (r runningServer) serveUpdate(ctx context.Context, writer dns.ResponseWrite
 		return
 	}
 	logger = logger.With(slog.String("key", tsig.Hdr.Name))
+	if !isAllowedFrom(writer.RemoteAddr(), key.AllowFrom) {
+		if zone.Debug {
+			logger.DebugContext(ctx, "Cannot update zone", slog.String("error_message", "Key is not allowed to be used from this address"))
+		}
+		response.SetRcode(msg, dns.RcodeNotAuth)
+		response.Extra = append(response.Extra, tsig)
+		if err := writer.WriteMsg(response); err != nil {
+			logger.DebugContext(ctx, "Cannot write response for disallowed address.", E.ToSLogAttr(err)...)
+		}
+		return
+	}
 	if !slices.Contains(key.Zones, strings.TrimPrefix(strings.TrimSuffix(msg.Question[0].Name, "."), "_acme-challenge.")) {
 		if zone.Debug {
 			logger.DebugContext(ctx, "Cannot update zone", slog.String("error_message", "Key is not authorized to modify zone"))
@@ -301,7 +334,7 @@ func (r runningServer) serveQuery(ctx context.Context, writer dns.ResponseWriter
 	zoneData, err := r.getZone(ctx, question.Name)
 	if err != nil {
 		if E.Is(err, backend.ErrZoneNotInBackend) {
-			if r.config.DebugZoneNotFound {
+			if r.config.Load().DebugZoneNotFound {
 				logger.DebugContext(ctx, "Zone not found in backend.", E.ToSLogAttr(err)...)
 			}
 			response.SetRcode(msg, dns.RcodeRefused)
@@ -327,6 +360,7 @@ func (r runningServer) serveQuery(ctx context.Context, writer dns.ResponseWriter
 		logger.DebugContext(ctx, "Query", slog.String("query", question.String()))
 	}
 
+	nameservers := r.config.Load().Nameservers
 	switch question.Qtype {
 	case dns.TypeTXT:
 		response.SetRcode(msg, dns.RcodeSuccess)
@@ -359,8 +393,8 @@ func (r runningServer) serveQuery(ctx context.Context, writer dns.ResponseWriter
 					Class:  dns.ClassINET,
 					Ttl:    86400,
 				},
-				Ns:      r.config.Nameservers[0] + ".",
-				Mbox:    "nomail." + r.config.Nameservers[0] + ".",
+				Ns:      nameservers[0] + ".",
+				Mbox:    "nomail." + nameservers[0] + ".",
 				Serial:  zoneData.Serial,
 				Refresh: 86400,
 				Retry:   7200,
@@ -370,8 +404,8 @@ func (r runningServer) serveQuery(ctx context.Context, writer dns.ResponseWriter
 		}
 	case dns.TypeNS:
 		response.SetRcode(msg, dns.RcodeSuccess)
-		response.Answer = make([]dns.RR, len(r.config.Nameservers))
-		for i, ns := range r.config.Nameservers {
+		response.Answer = make([]dns.RR, len(nameservers))
+		for i, ns := range nameservers {
 			response.Answer[i] = &dns.NS{
 				Hdr: dns.RR_Header{
 					Name:   question.Name,
@@ -442,10 +476,53 @@ func (r runningServer) Stop(ctx context.Context) error {
 			return ErrServerShutdownFailed.Wrap(err)
 		}
 	}
+	if r.dnstap != nil {
+		if err := r.dnstap.Close(ctx); err != nil {
+			r.logger.ErrorContext(ctx, "DNS4ACME shutdown failed", E.ToSLogAttr(err)...)
+			return ErrServerShutdownFailed.Wrap(err)
+		}
+	}
 	r.logger.InfoContext(ctx, "DNS4ACME shutdown complete, no errors.")
 	return nil
 }
 
+func (r runningServer) Reload(ctx context.Context, config Config) ([]string, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	current := r.config.Load()
+	var restartRequired []string
+	// Listeners and the dnstap output are set up on start, keep the running values for these.
+	if !reflect.DeepEqual(current.Listen, config.Listen) {
+		restartRequired = append(restartRequired, "listen")
+		config.Listen = current.Listen
+	}
+	if current.Dnstap != config.Dnstap {
+		restartRequired = append(restartRequired, "dnstap")
+		config.Dnstap = current.Dnstap
+	}
+	r.config.Store(&config)
+	r.logger.InfoContext(
+		ctx,
+		"DNS4ACME configuration reloaded",
+		slog.Any("nameservers", config.Nameservers),
+		slog.Bool("debug_zone_not_found", config.DebugZoneNotFound),
+	)
+	return restartRequired, nil
+}
+
+// isAllowedFrom returns true if an update key with the specified allowed networks may be used from the remote address.
+func isAllowedFrom(remote net.Addr, allowFrom []string) bool {
+	if len(allowFrom) == 0 {
+		return true
+	}
+	addrPort, err := netip.ParseAddrPort(remote.String())
+	if err != nil {
+		return false
+	}
+	return backend.IsAllowedFrom(allowFrom, addrPort.Addr())
+}
+
 func getZone(ctx context.Context, backendProvider backend.Provider, name string) (backend.ProviderZoneResponse, error) {
 	name = strings.ToLower(name)
 	if !strings.HasPrefix(name, "_acme-challenge.") {
@@ -2,31 +2,179 @@ package dns4acme
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"github.com/dns4acme/dns4acme/api"
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/cache"
+	"github.com/dns4acme/dns4acme/backend/middleware"
 	"github.com/dns4acme/dns4acme/core"
 	"io"
 	"log/slog"
+	"reflect"
 )
 
-func New(ctx context.Context, config *Config, output io.Writer) (core.Server, error) {
-	if _, ok := config.BackendConfigs[config.Backend]; !ok {
-		return nil, core.ErrInvalidConfiguration.Wrap(fmt.Errorf("backend %s does not exist", config.Backend))
-	}
-	backendProvider, err := config.BackendConfigs[config.Backend].Build(ctx)
-	if err != nil {
-		// TODO better error handling
+// Server is a DNS4ACME server that has not been started yet.
+type Server interface {
+	Start(ctx context.Context) (RunningServer, error)
+}
+
+// RunningServer is a started DNS4ACME server.
+type RunningServer interface {
+	Stop(ctx context.Context) error
+	// Reload applies the changes in the configuration that can be applied at runtime, such as the log level or the
+	// nameservers. It returns the names of options that have changed, but require a restart to take effect.
+	Reload(ctx context.Context, config *Config) ([]string, error)
+}
+
+func New(ctx context.Context, config *Config, output io.Writer) (Server, error) {
+	if err := config.Validate(); err != nil {
 		return nil, err
 	}
-
+	level := &slog.LevelVar{}
+	level.Set(config.Log.Level)
 	logger := slog.New(slog.NewTextHandler(output, &slog.HandlerOptions{
 		AddSource:   false,
-		Level:       config.Log.Level,
+		Level:       level,
 		ReplaceAttr: nil,
 	}))
 
+	// WithLogger leaves the configuration untouched, so Reload can still compare it to the new configuration.
+	backendConfig := config.BackendConfigs[config.Backend]
+	if loggingConfig, ok := backendConfig.(backend.LoggingConfig); ok {
+		backendConfig = loggingConfig.WithLogger(logger)
+	}
+	var backendProvider backend.Provider
+	var extendedProvider backend.ExtendedProvider
+	var err error
+	if config.API.Enabled() {
+		// The HTTP API needs the management functions, validation has already checked that the backend supports them.
+		extendedProvider, err = backendConfig.(backend.ExtendedConfig).BuildExtended(ctx)
+		backendProvider = extendedProvider
+	} else {
+		backendProvider, err = backendConfig.Build(ctx)
+	}
+	if err != nil {
+		// TODO better error handling
+		return nil, err
+	}
+	// The cache wraps the middleware chain, so cached lookups skip it.
+	middlewares := config.BackendRequests.Middlewares(logger)
+	if extendedProvider != nil {
+		extendedProvider = middleware.NewExtended(extendedProvider, middlewares...)
+		backendProvider = extendedProvider
+	} else {
+		backendProvider = middleware.New(backendProvider, middlewares...)
+	}
+	if config.BackendCache.Enabled() {
+		// The DNS server and the HTTP API share the cache, so changes made through the HTTP API invalidate it at once.
+		if extendedProvider != nil {
+			extendedProvider = cache.NewExtended(extendedProvider, config.BackendCache, logger)
+			backendProvider = extendedProvider
+		} else {
+			backendProvider = cache.New(backendProvider, config.BackendCache, logger)
+		}
+	}
+
 	srv, err := core.New(config.Config, backendProvider, logger)
+	if err != nil {
+		_ = backendProvider.Close(ctx)
+		return nil, err
+	}
+	var apiSrv api.Server
+	if config.API.Enabled() {
+		if apiSrv, err = api.New(config.API, extendedProvider, logger); err != nil {
+			_ = backendProvider.Close(ctx)
+			return nil, err
+		}
+	}
+	return &server{
+		srv:      srv,
+		apiSrv:   apiSrv,
+		provider: backendProvider,
+		config:   config,
+		level:    level,
+	}, nil
+}
+
+type server struct {
+	srv      core.Server
+	apiSrv   api.Server
+	provider backend.Provider
+	config   *Config
+	level    *slog.LevelVar
+}
+
+func (s *server) Start(ctx context.Context) (RunningServer, error) {
+	running, err := s.srv.Start(ctx)
+	if err != nil {
+		_ = s.provider.Close(ctx)
+		return nil, err
+	}
+	var runningAPI api.RunningServer
+	if s.apiSrv != nil {
+		if runningAPI, err = s.apiSrv.Start(ctx); err != nil {
+			_ = running.Stop(ctx)
+			_ = s.provider.Close(ctx)
+			return nil, err
+		}
+	}
+	return &runningServer{
+		srv:      running,
+		apiSrv:   runningAPI,
+		provider: s.provider,
+		config:   s.config,
+		level:    s.level,
+	}, nil
+}
+
+type runningServer struct {
+	srv      core.RunningServer
+	apiSrv   api.RunningServer
+	provider backend.Provider
+	config   *Config
+	level    *slog.LevelVar
+}
+
+// Stop stops the DNS server and the HTTP API, then closes the backend once nothing uses it anymore.
+func (r *runningServer) Stop(ctx context.Context) error {
+	var errs []error
+	if r.apiSrv != nil {
+		if err := r.apiSrv.Stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.srv.Stop(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := r.provider.Close(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *runningServer) Reload(ctx context.Context, config *Config) ([]string, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	restartRequired, err := r.srv.Reload(ctx, config.Config)
 	if err != nil {
 		return nil, err
 	}
-	return srv, nil
+	// The backend is built on startup, so any change to it requires a restart.
+	if config.Backend != r.config.Backend ||
+		!reflect.DeepEqual(config.BackendConfigs[config.Backend], r.config.BackendConfigs[r.config.Backend]) {
+		restartRequired = append(restartRequired, "backend")
+	}
+	if !reflect.DeepEqual(config.BackendRequests, r.config.BackendRequests) {
+		restartRequired = append(restartRequired, "backend-requests")
+	}
+	if !reflect.DeepEqual(config.BackendCache, r.config.BackendCache) {
+		restartRequired = append(restartRequired, "backend-cache")
+	}
+	// The HTTP API listener and credentials are set up on startup.
+	if !reflect.DeepEqual(config.API, r.config.API) {
+		restartRequired = append(restartRequired, "api")
+	}
+	r.level.Set(config.Log.Level)
+	return restartRequired, nil
 }
@@ -0,0 +1,5 @@
+//go:build bbolt
+
+package dns4acme
+
+import _ "github.com/dns4acme/dns4acme/backend/bbolt"
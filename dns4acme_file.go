@@ -0,0 +1,5 @@
+//go:build file
+
+package dns4acme
+
+import _ "github.com/dns4acme/dns4acme/backend/file"
@@ -0,0 +1,5 @@
+//go:build sqlite
+
+package dns4acme
+
+import _ "github.com/dns4acme/dns4acme/backend/sqlite"
@@ -9,6 +9,7 @@ import (
 	"github.com/dns4acme/dns4acme/backend/inmemory"
 	"github.com/dns4acme/dns4acme/internal/testlogger"
 	"github.com/miekg/dns"
+	"log/slog"
 	"math/rand"
 	"net/netip"
 	"testing"
@@ -283,4 +284,162 @@ func TestIntegration(t *testing.T) {
 		}
 		t.Logf("Received TXT record: %s", txt.String())
 	})
+	t.Run("reload", func(t *testing.T) {
+		t.Logf("Reloading configuration with a different nameserver...")
+		newCfg := *cfg
+		newCfg.Nameservers = []string{"ns2.example.com"}
+		restartRequired, err := started.Reload(ctx, &newCfg)
+		if err != nil {
+			t.Fatalf("Failed to reload configuration: %v", err)
+		}
+		if len(restartRequired) != 0 {
+			t.Fatalf("Expected no options requiring a restart, got %v", restartRequired)
+		}
+		msg := &dns.Msg{}
+		msg.SetQuestion("_acme-challenge.example.com.", dns.TypeNS)
+
+		r, err := dns.Exchange(msg, addrPort.String())
+		if err != nil {
+			t.Fatalf("Failed to exchange: %v", err)
+		}
+		if len(r.Answer) != 1 {
+			t.Fatalf("Expected 1 answer, got %d", len(r.Answer))
+		}
+		ns := r.Answer[0].(*dns.NS)
+		if ns.Ns != "ns2.example.com." {
+			t.Fatalf("Expected ns2.example.com. as a nameserver, got %s", ns.Ns)
+		}
+		t.Logf("Received NS record: %s", ns.String())
+	})
+	t.Run("reload-restart-required", func(t *testing.T) {
+		t.Logf("Reloading configuration with a different listen address...")
+		newCfg := *cfg
+		newAddrPort := netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), 30054)
+		newCfg.Listen = &newAddrPort
+		restartRequired, err := started.Reload(ctx, &newCfg)
+		if err != nil {
+			t.Fatalf("Failed to reload configuration: %v", err)
+		}
+		if len(restartRequired) != 1 || restartRequired[0] != "listen" {
+			t.Fatalf("Expected listen to require a restart, got %v", restartRequired)
+		}
+	})
+	t.Run("reload-backend-restart-required", func(t *testing.T) {
+		t.Logf("Reloading configuration with a different backend configuration...")
+		newCfg := *cfg
+		newCfg.BackendConfigs = dns4acme.BackendConfigs{inmemory.ID: inmemory.Config{}}
+		restartRequired, err := started.Reload(ctx, &newCfg)
+		if err != nil {
+			t.Fatalf("Failed to reload configuration: %v", err)
+		}
+		if len(restartRequired) != 1 || restartRequired[0] != "backend" {
+			t.Fatalf("Expected backend to require a restart, got %v", restartRequired)
+		}
+	})
+	t.Run("reload-invalid", func(t *testing.T) {
+		t.Logf("Reloading invalid configuration...")
+		newCfg := *cfg
+		newCfg.Nameservers = nil
+		if _, err := started.Reload(ctx, &newCfg); err == nil {
+			t.Fatalf("Expected reload to fail with no nameservers")
+		}
+	})
+}
+
+// closeRecordingConfig builds an inmemory backend that records whether it has been closed.
+type closeRecordingConfig struct {
+	closed bool
+}
+
+func (c *closeRecordingConfig) Build(ctx context.Context) (backend.Provider, error) {
+	provider, err := inmemory.Config{}.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &closeRecordingProvider{Provider: provider, config: c}, nil
+}
+
+type closeRecordingProvider struct {
+	backend.Provider
+	config *closeRecordingConfig
+}
+
+func (p *closeRecordingProvider) Close(ctx context.Context) error {
+	p.config.closed = true
+	return p.Provider.Close(ctx)
+}
+
+func TestStopClosesBackend(t *testing.T) {
+	cfg := dns4acme.NewConfig()
+	addrPort := netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), 30055)
+	cfg.Listen = &addrPort
+	cfg.Nameservers = []string{"dns4acme.example.com"}
+	backendConfig := &closeRecordingConfig{}
+	cfg.Backend = "close-recording"
+	cfg.BackendConfigs[cfg.Backend] = backendConfig
+
+	ctx := t.Context()
+	srv, err := dns4acme.New(ctx, cfg, testlogger.NewWriter(t))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	started, err := srv.Start(ctx)
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	if backendConfig.closed {
+		t.Fatalf("Backend closed while the server is running")
+	}
+	if err := started.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop server (%v)", err)
+	}
+	if !backendConfig.closed {
+		t.Fatalf("Backend not closed after stopping the server")
+	}
+}
+
+// loggingConfig builds an inmemory backend and records the logger of the configuration it was built from.
+type loggingConfig struct {
+	logger      *slog.Logger
+	builtLogger **slog.Logger
+}
+
+func (c loggingConfig) WithLogger(logger *slog.Logger) backend.Config {
+	c.logger = logger
+	return &c
+}
+
+func (c *loggingConfig) Build(ctx context.Context) (backend.Provider, error) {
+	*c.builtLogger = c.logger
+	return inmemory.Config{}.Build(ctx)
+}
+
+func TestNewPassesLoggerToBackend(t *testing.T) {
+	cfg := dns4acme.NewConfig()
+	addrPort := netip.AddrPortFrom(netip.MustParseAddr("127.0.0.1"), 30056)
+	cfg.Listen = &addrPort
+	cfg.Nameservers = []string{"dns4acme.example.com"}
+	var builtLogger *slog.Logger
+	backendConfig := &loggingConfig{builtLogger: &builtLogger}
+	cfg.Backend = "logging"
+	cfg.BackendConfigs[cfg.Backend] = backendConfig
+
+	ctx := t.Context()
+	srv, err := dns4acme.New(ctx, cfg, testlogger.NewWriter(t))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	started, err := srv.Start(ctx)
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	if err := started.Stop(ctx); err != nil {
+		t.Fatalf("Failed to stop server (%v)", err)
+	}
+	if builtLogger == nil {
+		t.Fatalf("The backend was built without a logger")
+	}
+	if backendConfig.logger != nil {
+		t.Fatalf("Setting the logger changed the configuration")
+	}
 }
@@ -0,0 +1,69 @@
+package dnstap
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Mode describes the transport dnstap frames are written to.
+type Mode string
+
+const (
+	// ModeDisabled turns dnstap logging off.
+	ModeDisabled Mode = ""
+	// ModeUnix writes frames to a Frame Streams collector listening on a unix socket.
+	ModeUnix Mode = "unix"
+	// ModeTCP writes frames to a Frame Streams collector listening on a TCP socket.
+	ModeTCP Mode = "tcp"
+	// ModeFile writes frames to a file.
+	ModeFile Mode = "file"
+)
+
+// UnmarshalText parses the mode from its textual representation.
+func (m *Mode) UnmarshalText(text []byte) error {
+	mode := Mode(strings.ToLower(string(text)))
+	switch mode {
+	case ModeDisabled, ModeUnix, ModeTCP, ModeFile:
+		*m = mode
+		return nil
+	default:
+		return ErrInvalidMode.WithAttr(slog.String("mode", string(text)))
+	}
+}
+
+type Config struct {
+	Mode    Mode   `config:"mode" description:"Where to send dnstap messages. Must be unix, tcp or file. dnstap is disabled if empty."`
+	Address string `config:"address" description:"Socket path, host:port or file name to send dnstap messages to, depending on the mode."`
+
+	Identity string `config:"identity" description:"Identity to send in dnstap messages. Defaults to the hostname."`
+	Version  string `config:"version" default:"DNS4ACME" description:"Version string to send in dnstap messages."`
+
+	BufferSize        int           `config:"buffer-size" default:"1024" description:"Number of dnstap messages to buffer before dropping messages."`
+	ReconnectInterval time.Duration `config:"reconnect-interval" default:"5s" description:"Time to wait between reconnection attempts to the dnstap collector."`
+	FlushInterval     time.Duration `config:"flush-interval" default:"1s" description:"Maximum time dnstap messages are held in the write buffer."`
+
+	UpdateMessageTypes bool `config:"update-message-types" description:"Log dynamic updates as UPDATE_QUERY and UPDATE_RESPONSE instead of CLIENT_QUERY and AUTH_RESPONSE."`
+}
+
+// Enabled returns true if dnstap logging is turned on.
+func (c Config) Enabled() bool {
+	return c.Mode != ModeDisabled
+}
+
+func (c Config) Validate() error {
+	switch c.Mode {
+	case ModeDisabled:
+		return nil
+	case ModeUnix, ModeTCP, ModeFile:
+	default:
+		return ErrInvalidMode.WithAttr(slog.String("mode", string(c.Mode)))
+	}
+	if c.Address == "" {
+		return ErrMissingAddress.WithAttr(slog.String("mode", string(c.Mode)))
+	}
+	if c.BufferSize <= 0 {
+		return ErrInvalidBufferSize.WithAttr(slog.Int("buffer_size", c.BufferSize))
+	}
+	return nil
+}
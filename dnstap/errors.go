@@ -0,0 +1,12 @@
+package dnstap
+
+import (
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+var ErrInvalidMode = E.New("DNSTAP_INVALID_MODE", "invalid dnstap mode")
+var ErrMissingAddress = E.New("DNSTAP_MISSING_ADDRESS", "dnstap address is required")
+var ErrInvalidBufferSize = E.New("DNSTAP_INVALID_BUFFER_SIZE", "dnstap buffer size must be positive")
+var ErrOpenFailed = E.New("DNSTAP_OPEN_FAILED", "failed to open dnstap output")
+var ErrHandshakeFailed = E.New("DNSTAP_HANDSHAKE_FAILED", "frame streams handshake with dnstap collector failed")
+var ErrCloseTimeout = E.New("DNSTAP_CLOSE_TIMEOUT", "timeout while flushing dnstap output")
@@ -0,0 +1,93 @@
+package dnstap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// Frame Streams constants from https://farsightsec.github.io/fstrm/.
+const (
+	controlAccept uint32 = 0x01
+	controlStart  uint32 = 0x02
+	controlStop   uint32 = 0x03
+	controlReady  uint32 = 0x04
+	controlFinish uint32 = 0x05
+
+	controlFieldContentType uint32 = 0x01
+
+	maxControlFrameLength = 512
+)
+
+var contentType = []byte("protobuf:dnstap.Dnstap")
+
+// writeControlFrame writes a Frame Streams control frame, optionally including the dnstap content type.
+func writeControlFrame(w io.Writer, controlType uint32, withContentType bool) error {
+	payload := binary.BigEndian.AppendUint32(nil, controlType)
+	if withContentType {
+		payload = binary.BigEndian.AppendUint32(payload, controlFieldContentType)
+		payload = binary.BigEndian.AppendUint32(payload, uint32(len(contentType)))
+		payload = append(payload, contentType...)
+	}
+	frame := binary.BigEndian.AppendUint32(nil, 0)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload))) //nolint:gosec // Control frames are tiny
+	frame = append(frame, payload...)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readControlFrame reads a Frame Streams control frame and checks that it has the expected type. If the frame carries
+// content types, one of them must be the dnstap content type.
+func readControlFrame(r io.Reader, expectedType uint32) error {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if escape := binary.BigEndian.Uint32(header[:4]); escape != 0 {
+		return fmt.Errorf("expected control frame, got data frame")
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length < 4 || length > maxControlFrameLength {
+		return fmt.Errorf("invalid control frame length %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+	if controlType := binary.BigEndian.Uint32(payload[:4]); controlType != expectedType {
+		return fmt.Errorf("unexpected control frame type %d, expected %d", controlType, expectedType)
+	}
+	payload = payload[4:]
+	hasContentType := false
+	matchingContentType := false
+	for len(payload) >= 8 {
+		fieldType := binary.BigEndian.Uint32(payload[:4])
+		fieldLength := binary.BigEndian.Uint32(payload[4:8])
+		payload = payload[8:]
+		if uint32(len(payload)) < fieldLength {
+			return fmt.Errorf("truncated control frame field")
+		}
+		if fieldType == controlFieldContentType {
+			hasContentType = true
+			if bytes.Equal(payload[:fieldLength], contentType) {
+				matchingContentType = true
+			}
+		}
+		payload = payload[fieldLength:]
+	}
+	if hasContentType && !matchingContentType {
+		return ErrHandshakeFailed.Wrap(fmt.Errorf("collector does not accept %s", contentType)).
+			WithAttr(slog.String("content_type", string(contentType)))
+	}
+	return nil
+}
+
+// writeDataFrame writes a single Frame Streams data frame.
+func writeDataFrame(w io.Writer, data []byte) error {
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, len(data)+4), uint32(len(data))) //nolint:gosec // dnstap messages are bounded by the DNS message size
+	frame = append(frame, data...)
+	_, err := w.Write(frame)
+	return err
+}
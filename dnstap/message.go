@@ -0,0 +1,163 @@
+package dnstap
+
+import (
+	"encoding/binary"
+	"net"
+	"time"
+)
+
+// MessageType is the dnstap Message.Type enum.
+type MessageType uint64
+
+const (
+	MessageTypeAuthQuery      MessageType = 1
+	MessageTypeAuthResponse   MessageType = 2
+	MessageTypeClientQuery    MessageType = 5
+	MessageTypeClientResponse MessageType = 6
+	MessageTypeUpdateQuery    MessageType = 13
+	MessageTypeUpdateResponse MessageType = 14
+)
+
+// SocketProtocol is the dnstap SocketProtocol enum.
+type SocketProtocol uint64
+
+const (
+	SocketProtocolUDP SocketProtocol = 1
+	SocketProtocolTCP SocketProtocol = 2
+)
+
+// Message holds the information of a single dnstap message. Fields left empty are omitted from the encoded message.
+type Message struct {
+	Type            MessageType
+	Protocol        SocketProtocol
+	QueryAddress    net.Addr
+	ResponseAddress net.Addr
+	QueryTime       time.Time
+	QueryMessage    []byte
+	ResponseTime    time.Time
+	ResponseMessage []byte
+}
+
+// Field numbers and constants from https://github.com/dnstap/dnstap.pb/blob/master/dnstap.proto.
+const (
+	dnstapFieldIdentity = 1
+	dnstapFieldVersion  = 2
+	dnstapFieldMessage  = 14
+	dnstapFieldType     = 15
+
+	dnstapTypeMessage = 1
+
+	messageFieldType             = 1
+	messageFieldSocketFamily     = 2
+	messageFieldSocketProtocol   = 3
+	messageFieldQueryAddress     = 4
+	messageFieldResponseAddress  = 5
+	messageFieldQueryPort        = 6
+	messageFieldResponsePort     = 7
+	messageFieldQueryTimeSec     = 8
+	messageFieldQueryTimeNsec    = 9
+	messageFieldQueryMessage     = 10
+	messageFieldResponseTimeSec  = 12
+	messageFieldResponseTimeNsec = 13
+	messageFieldResponseMessage  = 14
+
+	socketFamilyINET  = 1
+	socketFamilyINET6 = 2
+
+	wireTypeVarint  = 0
+	wireTypeBytes   = 2
+	wireTypeFixed32 = 5
+)
+
+// marshal encodes the message wrapped in a Dnstap envelope using the protobuf wire format.
+func (m Message) marshal(identity []byte, version []byte) []byte {
+	var msg []byte
+	msg = appendVarintField(msg, messageFieldType, uint64(m.Type))
+	queryIP, queryPort := splitAddr(m.QueryAddress)
+	responseIP, responsePort := splitAddr(m.ResponseAddress)
+	familyIP := queryIP
+	if familyIP == nil {
+		familyIP = responseIP
+	}
+	if familyIP != nil {
+		if familyIP.To4() != nil {
+			msg = appendVarintField(msg, messageFieldSocketFamily, socketFamilyINET)
+		} else {
+			msg = appendVarintField(msg, messageFieldSocketFamily, socketFamilyINET6)
+		}
+	}
+	if m.Protocol != 0 {
+		msg = appendVarintField(msg, messageFieldSocketProtocol, uint64(m.Protocol))
+	}
+	if queryIP != nil {
+		msg = appendBytesField(msg, messageFieldQueryAddress, normalizeIP(queryIP))
+		msg = appendVarintField(msg, messageFieldQueryPort, uint64(queryPort))
+	}
+	if responseIP != nil {
+		msg = appendBytesField(msg, messageFieldResponseAddress, normalizeIP(responseIP))
+		msg = appendVarintField(msg, messageFieldResponsePort, uint64(responsePort))
+	}
+	if !m.QueryTime.IsZero() {
+		msg = appendVarintField(msg, messageFieldQueryTimeSec, uint64(m.QueryTime.Unix()))         //nolint:gosec // Times before 1970 are not a concern
+		msg = appendFixed32Field(msg, messageFieldQueryTimeNsec, uint32(m.QueryTime.Nanosecond())) //nolint:gosec // Nanoseconds always fit
+	}
+	if m.QueryMessage != nil {
+		msg = appendBytesField(msg, messageFieldQueryMessage, m.QueryMessage)
+	}
+	if !m.ResponseTime.IsZero() {
+		msg = appendVarintField(msg, messageFieldResponseTimeSec, uint64(m.ResponseTime.Unix()))         //nolint:gosec // Times before 1970 are not a concern
+		msg = appendFixed32Field(msg, messageFieldResponseTimeNsec, uint32(m.ResponseTime.Nanosecond())) //nolint:gosec // Nanoseconds always fit
+	}
+	if m.ResponseMessage != nil {
+		msg = appendBytesField(msg, messageFieldResponseMessage, m.ResponseMessage)
+	}
+
+	var result []byte
+	if len(identity) > 0 {
+		result = appendBytesField(result, dnstapFieldIdentity, identity)
+	}
+	if len(version) > 0 {
+		result = appendBytesField(result, dnstapFieldVersion, version)
+	}
+	result = appendBytesField(result, dnstapFieldMessage, msg)
+	result = appendVarintField(result, dnstapFieldType, dnstapTypeMessage)
+	return result
+}
+
+func splitAddr(addr net.Addr) (net.IP, int) {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.IP, a.Port
+	case *net.TCPAddr:
+		return a.IP, a.Port
+	default:
+		return nil, 0
+	}
+}
+
+func normalizeIP(ip net.IP) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip.To16()
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field<<3|wireType)) //nolint:gosec // Field numbers are constants
+}
+
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	b = appendTag(b, field, wireTypeVarint)
+	return binary.AppendUvarint(b, value)
+}
+
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = appendTag(b, field, wireTypeBytes)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+func appendFixed32Field(b []byte, field int, value uint32) []byte {
+	b = appendTag(b, field, wireTypeFixed32)
+	return binary.LittleEndian.AppendUint32(b, value)
+}
@@ -0,0 +1,254 @@
+package dnstap
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+const dialTimeout = 5 * time.Second
+const handshakeTimeout = 5 * time.Second
+
+// Output accepts dnstap messages and writes them to the configured destination in the background.
+type Output interface {
+	// Log queues a message for writing. This function never blocks. If the buffer is full because the collector is
+	// too slow or unreachable, the message is dropped.
+	Log(msg Message)
+	// Close flushes the buffered messages and closes the output.
+	Close(ctx context.Context) error
+}
+
+// New creates a new dnstap output based on the configuration and starts writing in the background. Connection errors
+// to the collector are not returned, the output keeps reconnecting until it is closed.
+func New(config Config, logger *slog.Logger) (Output, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if !config.Enabled() {
+		return discardOutput{}, nil
+	}
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	identity := config.Identity
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+	o := &output{
+		config:   config,
+		logger:   logger.With(slog.String("dnstap_mode", string(config.Mode)), slog.String("dnstap_address", config.Address)),
+		identity: []byte(identity),
+		version:  []byte(config.Version),
+		queue:    make(chan Message, config.BufferSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if config.Mode == ModeFile {
+		// Open the file upfront so that permission problems surface during startup.
+		conn, err := o.connect()
+		if err != nil {
+			return nil, err
+		}
+		go o.run(conn)
+	} else {
+		go o.run(nil)
+	}
+	return o, nil
+}
+
+type output struct {
+	config    Config
+	logger    *slog.Logger
+	identity  []byte
+	version   []byte
+	queue     chan Message
+	dropped   atomic.Uint64
+	stop      chan struct{}
+	stopOnce  sync.Once
+	done      chan struct{}
+	connected bool
+	opened    bool
+}
+
+func (o *output) Log(msg Message) {
+	select {
+	case o.queue <- msg:
+	default:
+		o.dropped.Add(1)
+	}
+}
+
+func (o *output) Close(ctx context.Context) error {
+	o.stopOnce.Do(func() {
+		close(o.stop)
+	})
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		return ErrCloseTimeout.Wrap(ctx.Err())
+	}
+}
+
+// connection is an open destination. The reader is only set for bidirectional Frame Streams connections.
+type connection struct {
+	io.WriteCloser
+	reader io.Reader
+	conn   net.Conn
+}
+
+func (o *output) connect() (*connection, error) {
+	switch o.config.Mode {
+	case ModeFile:
+		// Only truncate the file on the first open, reopening after a write error must keep the frames written so far.
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if o.opened {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		fh, err := os.OpenFile(o.config.Address, flags, 0o666)
+		if err != nil {
+			return nil, ErrOpenFailed.Wrap(err)
+		}
+		if err := writeControlFrame(fh, controlStart, true); err != nil {
+			_ = fh.Close()
+			return nil, ErrOpenFailed.Wrap(err)
+		}
+		o.opened = true
+		return &connection{WriteCloser: fh}, nil
+	default:
+		conn, err := net.DialTimeout(string(o.config.Mode), o.config.Address, dialTimeout)
+		if err != nil {
+			return nil, ErrOpenFailed.Wrap(err)
+		}
+		if err := o.handshake(conn); err != nil {
+			_ = conn.Close()
+			return nil, ErrHandshakeFailed.Wrap(err)
+		}
+		return &connection{WriteCloser: conn, reader: conn, conn: conn}, nil
+	}
+}
+
+func (o *output) handshake(conn net.Conn) error {
+	if err := conn.SetDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return err
+	}
+	if err := writeControlFrame(conn, controlReady, true); err != nil {
+		return err
+	}
+	if err := readControlFrame(conn, controlAccept); err != nil {
+		return err
+	}
+	if err := writeControlFrame(conn, controlStart, true); err != nil {
+		return err
+	}
+	return conn.SetDeadline(time.Time{})
+}
+
+func (o *output) run(conn *connection) {
+	defer close(o.done)
+	for {
+		if conn == nil {
+			var err error
+			conn, err = o.connect()
+			if err != nil {
+				if o.connected {
+					o.logger.Warn("Cannot connect to dnstap collector", E.ToSLogAttr(err)...)
+				} else {
+					o.logger.Debug("Cannot connect to dnstap collector", E.ToSLogAttr(err)...)
+				}
+				o.connected = false
+				select {
+				case <-o.stop:
+					return
+				case <-time.After(o.config.ReconnectInterval):
+					continue
+				}
+			}
+		}
+		o.connected = true
+		o.logger.Debug("dnstap output opened")
+		stopped, err := o.write(conn)
+		if stopped {
+			o.finish(conn)
+			return
+		}
+		o.logger.Warn("Error writing dnstap messages, reconnecting", E.ToSLogAttr(err)...)
+		_ = conn.Close()
+		conn = nil
+		select {
+		case <-o.stop:
+			return
+		case <-time.After(o.config.ReconnectInterval):
+		}
+	}
+}
+
+// write sends messages from the queue until an error happens or the output is stopped. It returns true if the output
+// has been stopped and all queued messages have been written.
+func (o *output) write(conn *connection) (bool, error) {
+	w := bufio.NewWriter(conn)
+	ticker := time.NewTicker(o.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-o.queue:
+			if err := writeDataFrame(w, msg.marshal(o.identity, o.version)); err != nil {
+				return false, err
+			}
+		case <-ticker.C:
+			if err := w.Flush(); err != nil {
+				return false, err
+			}
+			o.reportDropped()
+		case <-o.stop:
+			for {
+				select {
+				case msg := <-o.queue:
+					if err := writeDataFrame(w, msg.marshal(o.identity, o.version)); err != nil {
+						return false, err
+					}
+				default:
+					o.reportDropped()
+					return true, w.Flush()
+				}
+			}
+		}
+	}
+}
+
+// finish ends the Frame Streams session and closes the connection.
+func (o *output) finish(conn *connection) {
+	if err := writeControlFrame(conn, controlStop, false); err != nil {
+		o.logger.Debug("Cannot write dnstap stop frame", E.ToSLogAttr(err)...)
+	} else if conn.reader != nil {
+		_ = conn.conn.SetReadDeadline(time.Now().Add(handshakeTimeout))
+		if err := readControlFrame(conn.reader, controlFinish); err != nil {
+			o.logger.Debug("dnstap collector did not acknowledge stop frame", E.ToSLogAttr(err)...)
+		}
+	}
+	if err := conn.Close(); err != nil {
+		o.logger.Debug("Cannot close dnstap output", E.ToSLogAttr(err)...)
+	}
+}
+
+func (o *output) reportDropped() {
+	if dropped := o.dropped.Swap(0); dropped > 0 {
+		o.logger.Warn("dnstap buffer full, messages dropped", slog.Uint64("dropped", dropped))
+	}
+}
+
+type discardOutput struct{}
+
+func (discardOutput) Log(_ Message) {}
+
+func (discardOutput) Close(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,160 @@
+package dnstap
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dns4acme/dns4acme/internal/testlogger"
+)
+
+func testMessage() Message {
+	return Message{
+		Type:            MessageTypeClientQuery,
+		Protocol:        SocketProtocolUDP,
+		QueryAddress:    &net.UDPAddr{IP: net.ParseIP("192.0.2.1"), Port: 12345},
+		ResponseAddress: &net.UDPAddr{IP: net.ParseIP("192.0.2.2"), Port: 53},
+		QueryTime:       time.Now(),
+		QueryMessage:    []byte("query-message"),
+	}
+}
+
+func readDataFrames(r io.Reader, stopControl uint32) ([][]byte, error) {
+	var frames [][]byte
+	for {
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(header)
+		if length == 0 {
+			// Control frame, push back the escape sequence.
+			if err := readControlFrame(io.MultiReader(bytes.NewReader(header), r), stopControl); err != nil {
+				return nil, err
+			}
+			return frames, nil
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+}
+
+func TestOutput_File(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "dnstap.fstrm")
+	out, err := New(Config{
+		Mode:              ModeFile,
+		Address:           file,
+		Identity:          "test-identity",
+		Version:           "test-version",
+		BufferSize:        16,
+		ReconnectInterval: time.Second,
+		FlushInterval:     time.Second,
+	}, testlogger.New(t))
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	out.Log(testMessage())
+	if err := out.Close(t.Context()); err != nil {
+		t.Fatalf("Failed to close output: %v", err)
+	}
+
+	fh, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("Failed to open dnstap file: %v", err)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	if err := readControlFrame(fh, controlStart); err != nil {
+		t.Fatalf("Expected start frame: %v", err)
+	}
+	frames, err := readDataFrames(fh, controlStop)
+	if err != nil {
+		t.Fatalf("Failed to read data frames: %v", err)
+	}
+	if len(frames) != 1 {
+		t.Fatalf("Expected 1 data frame, got %d", len(frames))
+	}
+	for _, expected := range []string{"test-identity", "test-version", "query-message"} {
+		if !bytes.Contains(frames[0], []byte(expected)) {
+			t.Fatalf("Data frame does not contain %s", expected)
+		}
+	}
+}
+
+func TestOutput_Unix(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "dnstap.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() {
+		_ = listener.Close()
+	}()
+	received := make(chan [][]byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = conn.Close()
+		}()
+		if err := readControlFrame(conn, controlReady); err != nil {
+			t.Errorf("Expected ready frame: %v", err)
+			return
+		}
+		if err := writeControlFrame(conn, controlAccept, true); err != nil {
+			t.Errorf("Failed to write accept frame: %v", err)
+			return
+		}
+		if err := readControlFrame(conn, controlStart); err != nil {
+			t.Errorf("Expected start frame: %v", err)
+			return
+		}
+		frames, err := readDataFrames(conn, controlStop)
+		if err != nil {
+			t.Errorf("Failed to read data frames: %v", err)
+			return
+		}
+		received <- frames
+		_ = writeControlFrame(conn, controlFinish, false)
+	}()
+
+	out, err := New(Config{
+		Mode:              ModeUnix,
+		Address:           socket,
+		BufferSize:        16,
+		ReconnectInterval: 100 * time.Millisecond,
+		FlushInterval:     100 * time.Millisecond,
+	}, testlogger.New(t))
+	if err != nil {
+		t.Fatalf("Failed to create output: %v", err)
+	}
+	out.Log(testMessage())
+	out.Log(testMessage())
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+	defer cancel()
+	// Give the output time to connect before closing, otherwise the messages are discarded.
+	time.Sleep(500 * time.Millisecond)
+	if err := out.Close(ctx); err != nil {
+		t.Fatalf("Failed to close output: %v", err)
+	}
+	select {
+	case frames := <-received:
+		if len(frames) != 2 {
+			t.Fatalf("Expected 2 data frames, got %d", len(frames))
+		}
+	case <-ctx.Done():
+		t.Fatalf("Timeout while waiting for frames")
+	}
+}
@@ -0,0 +1,138 @@
+package config_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/internal/config"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+type cmdConfigStruct struct {
+	Name   string   `config:"name"`
+	Offset int      `config:"offset"`
+	Debug  bool     `config:"debug" default:"true"`
+	List   []string `config:"list"`
+}
+
+func TestParser_ApplyCMD(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cmd      []string
+		expected cmdConfigStruct
+		args     []string
+	}{
+		"separate-value": {
+			cmd:      []string{"--name", "foo", "--offset", "3"},
+			expected: cmdConfigStruct{Name: "foo", Offset: 3, Debug: true},
+		},
+		"equals": {
+			cmd:      []string{"--name=--foo=bar", "--offset=3"},
+			expected: cmdConfigStruct{Name: "--foo=bar", Offset: 3, Debug: true},
+		},
+		"negative-number": {
+			cmd:      []string{"--offset", "-5"},
+			expected: cmdConfigStruct{Offset: -5, Debug: true},
+		},
+		"bool-negation": {
+			cmd:      []string{"--no-debug", "--name", "foo"},
+			expected: cmdConfigStruct{Name: "foo", Debug: false},
+		},
+		"bool-explicit-value": {
+			cmd:      []string{"--debug", "false", "--name", "foo"},
+			expected: cmdConfigStruct{Name: "foo", Debug: false},
+		},
+		"bool-equals": {
+			cmd:      []string{"--debug=false"},
+			expected: cmdConfigStruct{Debug: false},
+		},
+		"repeated-list": {
+			cmd:      []string{"--list", "a", "--list", "b,c", "--list=d"},
+			expected: cmdConfigStruct{List: []string{"a", "b", "c", "d"}, Debug: true},
+		},
+		"terminator": {
+			cmd:      []string{"--name", "foo", "--", "--offset", "3"},
+			expected: cmdConfigStruct{Name: "foo", Debug: true},
+			args:     []string{"--offset", "3"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &cmdConfigStruct{}
+			parser := config.New(cfg)
+			if err := parser.ApplyDefaults(); err != nil {
+				t.Fatalf("ApplyDefaults failed: %v", err)
+			}
+			if err := parser.ApplyCMD(append([]string{"test"}, tc.cmd...)); err != nil {
+				t.Fatalf("ApplyCMD failed: %v", err)
+			}
+			if cfg.Name != tc.expected.Name || cfg.Offset != tc.expected.Offset || cfg.Debug != tc.expected.Debug || !slices.Equal(cfg.List, tc.expected.List) {
+				t.Fatalf("Incorrect configuration: %v (expected: %v)", *cfg, tc.expected)
+			}
+			if !slices.Equal(parser.Args, tc.args) {
+				t.Fatalf("Incorrect positional arguments: %v (expected: %v)", parser.Args, tc.args)
+			}
+		})
+	}
+}
+
+func TestParser_ApplyCMD_ListReplacesEnv(t *testing.T) {
+	cfg := &cmdConfigStruct{}
+	parser := config.New(cfg)
+	if err := parser.ApplyEnv("TEST_", []string{"TEST_LIST=x,y"}); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if err := parser.ApplyCMD([]string{"test", "--list", "a", "--list", "b"}); err != nil {
+		t.Fatalf("ApplyCMD failed: %v", err)
+	}
+	if !slices.Equal(cfg.List, []string{"a", "b"}) {
+		t.Fatalf("Incorrect list: %v", cfg.List)
+	}
+}
+
+func TestParser_ApplyCMD_Errors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cmd        []string
+		expected   E.Error
+		didYouMean string
+	}{
+		"missing-value": {
+			cmd:      []string{"--name"},
+			expected: config.ErrMissingOptionValue,
+		},
+		"missing-value-followed-by-flag": {
+			cmd:      []string{"--name", "--offset", "3"},
+			expected: config.ErrMissingOptionValue,
+		},
+		"positional": {
+			cmd:      []string{"foo"},
+			expected: config.ErrUnexpectedCLIOption,
+		},
+		"negated-non-bool": {
+			cmd:      []string{"--no-name"},
+			expected: config.ErrNoSuchOption,
+		},
+		"typo": {
+			cmd:        []string{"--ofset", "3"},
+			expected:   config.ErrNoSuchOption,
+			didYouMean: "--offset",
+		},
+		"unrelated": {
+			cmd:      []string{"--something-else", "3"},
+			expected: config.ErrNoSuchOption,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &cmdConfigStruct{}
+			err := config.New(cfg).ApplyCMD(append([]string{"test"}, tc.cmd...))
+			if !E.Is(err, tc.expected) {
+				t.Fatalf("Incorrect error: %v (expected: %v)", err, tc.expected)
+			}
+			var suggestion string
+			if attr := err.(E.Error).GetAttr("did_you_mean"); attr != nil {
+				suggestion = attr.Value.String()
+			}
+			if suggestion != tc.didYouMean {
+				t.Fatalf("Incorrect suggestion: %q (expected: %q)", suggestion, tc.didYouMean)
+			}
+		})
+	}
+}
@@ -13,5 +13,6 @@ var converters = []converter{
 	&uintConverter{},
 	&floatConverter{},
 	&stringConverter{},
+	&boolConverter{},
 	&durationConverter{},
 }
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"strconv"
+)
+
+type boolConverter struct{}
+
+func (b boolConverter) convert(sourceValue reflect.Value, targetValue reflect.Value) error {
+	if sourceValue.Kind() != reflect.String || targetValue.Kind() != reflect.Bool {
+		return ErrCannotConvertValue
+	}
+	val, err := strconv.ParseBool(sourceValue.String())
+	if err != nil {
+		return ErrCannotConvertValue.WithAttr(slog.String("source", sourceValue.Kind().String())).WithAttr(slog.String("target", targetValue.Kind().String())).Wrap(err)
+	}
+	targetValue.SetBool(val)
+	return nil
+}
+
+var _ converter = &boolConverter{}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue is printed instead of the value of options marked as sensitive.
+const RedactedValue = "<redacted>"
+
+// DumpedOption is the machine-readable representation of a single option and its effective value.
+type DumpedOption struct {
+	Option       string `json:"option"`
+	Value        any    `json:"value"`
+	Source       Source `json:"source,omitempty"`
+	SourceDetail string `json:"source_detail,omitempty"`
+	Redacted     bool   `json:"redacted,omitempty"`
+}
+
+// DumpOptions returns the effective value of every option along with the source of the value. Options marked as
+// sensitive are redacted if they are set.
+func (p *Parser) DumpOptions() []DumpedOption {
+	options := p.sortedOptions()
+	result := make([]DumpedOption, len(options))
+	for i, opt := range options {
+		result[i] = DumpedOption{
+			Option:       opt.Path.String(),
+			Value:        dumpValue(opt.Value),
+			Source:       opt.Source,
+			SourceDetail: opt.SourceDetail,
+		}
+		if opt.redacted() {
+			result[i].Value = RedactedValue
+			result[i].Redacted = true
+		}
+	}
+	return result
+}
+
+// Dump prints the effective configuration in the specified format. The YAML output uses the same structure as the
+// configuration file and annotates each option with a comment indicating its source. The JSON output is a list of
+// DumpedOption objects.
+func (p *Parser) Dump(format FileFormat) ([]byte, error) {
+	switch format {
+	case FileFormatJSON:
+		data, err := json.MarshalIndent(p.DumpOptions(), "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FileFormatYAML:
+		return p.dumpYAML()
+	default:
+		return nil, ErrUnsupportedFileFormat.WithAttr(slog.String("format", string(format)))
+	}
+}
+
+func (p *Parser) sortedOptions() []Option {
+	options := slices.Clone(p.Options)
+	slices.SortStableFunc(options, func(a, b Option) int {
+		return slices.Compare(a.Keys, b.Keys)
+	})
+	return options
+}
+
+func (o Option) redacted() bool {
+	return o.Sensitive && !o.Value.IsZero()
+}
+
+func (p *Parser) dumpYAML() ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, opt := range p.sortedOptions() {
+		parent := root
+		for _, key := range opt.Keys[:len(opt.Keys)-1] {
+			parent = yamlChildMapping(parent, key)
+		}
+		keyNode := &yaml.Node{
+			Kind:        yaml.ScalarNode,
+			Value:       opt.Keys[len(opt.Keys)-1],
+			LineComment: describeSource(opt),
+		}
+		valueNode := &yaml.Node{}
+		value := dumpValue(opt.Value)
+		if opt.redacted() {
+			value = RedactedValue
+		}
+		if err := valueNode.Encode(value); err != nil {
+			return nil, err
+		}
+		parent.Content = append(parent.Content, keyNode, valueNode)
+	}
+	buf := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func yamlChildMapping(parent *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key && parent.Content[i+1].Kind == yaml.MappingNode {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, child)
+	return child
+}
+
+func describeSource(opt Option) string {
+	parts := []string{string(opt.Source)}
+	if opt.Source == SourceUnset {
+		parts[0] = "not set"
+	}
+	if opt.SourceDetail != "" {
+		parts = append(parts, opt.SourceDetail)
+	}
+	if opt.redacted() {
+		parts = append(parts, "redacted")
+	}
+	return strings.Join(parts, " ")
+}
+
+// dumpValue converts an option value into a value that can be encoded as YAML or JSON and can be read back by the
+// configuration parser.
+func dumpValue(value reflect.Value) any {
+	if value.Kind() == reflect.Ptr && value.IsNil() {
+		return nil
+	}
+	if duration, ok := value.Interface().(time.Duration); ok {
+		return duration.String()
+	}
+	if marshaler, ok := value.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err == nil {
+			return string(text)
+		}
+	}
+	if value.CanAddr() {
+		if marshaler, ok := value.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := marshaler.MarshalText()
+			if err == nil {
+				return string(text)
+			}
+		}
+	}
+	if value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	switch value.Kind() {
+	case reflect.Slice:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return string(value.Bytes())
+		}
+		result := make([]any, value.Len())
+		for i := range value.Len() {
+			result[i] = dumpValue(value.Index(i))
+		}
+		return result
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return value.Uint()
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	default:
+		return value.Interface()
+	}
+}
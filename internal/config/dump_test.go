@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/internal/config"
+)
+
+type dumpConfigStruct struct {
+	FieldA string `config:"field-a" default:"hello"`
+	FieldB int    `config:"field-b"`
+	Secret string `config:"secret" sensitive:"true"`
+	Unset  string `config:"unset" sensitive:"true"`
+}
+
+func TestParser_DumpOptions(t *testing.T) {
+	cfg := &dumpConfigStruct{}
+	parser := config.New(cfg)
+	if err := parser.ApplyDefaults(); err != nil {
+		t.Fatalf("ApplyDefaults failed: %v", err)
+	}
+	if err := parser.ApplyEnv("TEST_", []string{"TEST_SECRET=s3cr3t"}); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if err := parser.ApplyCMD([]string{"test", "--field-b", "42"}); err != nil {
+		t.Fatalf("ApplyCMD failed: %v", err)
+	}
+
+	options := map[string]config.DumpedOption{}
+	for _, opt := range parser.DumpOptions() {
+		options[opt.Option] = opt
+	}
+	if opt := options["field-a"]; opt.Value != "hello" || opt.Source != config.SourceDefault {
+		t.Fatalf("Incorrect field-a: %v", opt)
+	}
+	if opt := options["field-b"]; opt.Value != int64(42) || opt.Source != config.SourceFlag || opt.SourceDetail != "--field-b" {
+		t.Fatalf("Incorrect field-b: %v", opt)
+	}
+	if opt := options["secret"]; opt.Value != config.RedactedValue || !opt.Redacted || opt.SourceDetail != "TEST_SECRET" {
+		t.Fatalf("Incorrect secret: %v", opt)
+	}
+	if opt := options["unset"]; opt.Value != "" || opt.Redacted || opt.Source != config.SourceUnset {
+		t.Fatalf("Incorrect unset: %v", opt)
+	}
+
+	for _, format := range []config.FileFormat{config.FileFormatYAML, config.FileFormatJSON} {
+		output, err := parser.Dump(format)
+		if err != nil {
+			t.Fatalf("Dump failed: %v", err)
+		}
+		if strings.Contains(string(output), "s3cr3t") {
+			t.Fatalf("The %s output contains the secret value:\n%s", format, output)
+		}
+	}
+
+	output, err := parser.Dump(config.FileFormatJSON)
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	var decoded []config.DumpedOption
+	if err := json.Unmarshal(output, &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON output: %v", err)
+	}
+	if len(decoded) != 4 {
+		t.Fatalf("Incorrect number of options in JSON output: %d", len(decoded))
+	}
+}
+
+func TestParser_DumpYAMLRoundTrip(t *testing.T) {
+	cfg := &fileConfigStruct{}
+	parser := config.New(cfg)
+	if err := parser.ApplyCMD([]string{"test", "--field-a", "Hello world!", "--field-b", "42", "--nested-field-e", "nested"}); err != nil {
+		t.Fatalf("ApplyCMD failed: %v", err)
+	}
+	output, err := parser.Dump(config.FileFormatYAML)
+	if err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	if !strings.Contains(string(output), "# flag --field-a") {
+		t.Fatalf("The YAML output does not contain the source comment:\n%s", output)
+	}
+
+	cfg2 := &fileConfigStruct{}
+	if err := config.New(cfg2).ApplyFileData("dump.yaml", config.FileFormatYAML, output); err != nil {
+		t.Fatalf("Failed to read back the YAML output: %v\n%s", err, output)
+	}
+	if cfg2.FieldA != cfg.FieldA || cfg2.FieldB != cfg.FieldB || cfg2.Nested.FieldE != cfg.Nested.FieldE {
+		t.Fatalf("Incorrect round trip: %v", cfg2)
+	}
+}
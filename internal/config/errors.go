@@ -15,3 +15,9 @@ var ErrValueTooSmall = E.New("VALUE_TOO_SMALL", "value too small")
 var ErrValueTooLarge = E.New("VALUE_TOO_LARGE", "value too large")
 var ErrCannotSetTargetValue = E.New("CANNOT_SET_TARGET_VALUE", "cannot set target value")
 var ErrNoSuchOption = E.New("NO_SUCH_OPTION", "no such option")
+var ErrMissingOptionValue = E.New("MISSING_OPTION_VALUE", "missing value for option")
+
+var ErrUnsupportedFileFormat = E.New("UNSUPPORTED_CONFIG_FILE_FORMAT", "unsupported configuration file format, must be .yaml, .yml, .json or .toml")
+var ErrFileReadFailed = E.New("CONFIG_FILE_READ_FAILED", "failed to read configuration file")
+var ErrInvalidFile = E.New("INVALID_CONFIG_FILE", "invalid configuration file")
+var ErrUnknownFileOption = E.New("UNKNOWN_CONFIG_FILE_OPTION", "unknown option in configuration file")
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/dns4acme/dns4acme/lang/E"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// FileFormat describes the syntax of a configuration file.
+type FileFormat string
+
+const (
+	FileFormatYAML FileFormat = "yaml"
+	FileFormatJSON FileFormat = "json"
+	FileFormatTOML FileFormat = "toml"
+)
+
+// FileFormatFromName determines the configuration file format from the file extension.
+func FileFormatFromName(fileName string) (FileFormat, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".yaml", ".yml":
+		return FileFormatYAML, nil
+	case ".json":
+		return FileFormatJSON, nil
+	case ".toml":
+		return FileFormatTOML, nil
+	default:
+		return "", ErrUnsupportedFileFormat.WithAttr(slog.String("file", fileName))
+	}
+}
+
+// fileValue is a single option value read from a configuration file.
+type fileValue struct {
+	path  Path
+	value reflect.Value
+	line  int
+}
+
+// ApplyFile reads the specified configuration file and applies the options contained within. The file format is
+// determined from the file extension. Nested objects in the file map onto the same option paths as the command line,
+// so a log.level key in a YAML file sets the same option as --log-level.
+func (p *Parser) ApplyFile(fileName string) error {
+	format, err := FileFormatFromName(fileName)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return ErrFileReadFailed.Wrap(err).WithAttr(slog.String("file", fileName))
+	}
+	return p.ApplyFileData(fileName, format, data)
+}
+
+// ApplyFileData applies the options from the configuration file contents passed in data. The fileName is only used
+// for error reporting.
+func (p *Parser) ApplyFileData(fileName string, format FileFormat, data []byte) error {
+	var values []fileValue
+	var err error
+	switch format {
+	case FileFormatYAML, FileFormatJSON:
+		// JSON is a subset of YAML, so we can use the YAML parser for both and get line numbers for free.
+		values, err = parseYAMLFile(data)
+	case FileFormatTOML:
+		values, err = parseTOMLFile(data)
+	default:
+		return ErrUnsupportedFileFormat.WithAttr(slog.String("file", fileName)).WithAttr(slog.String("format", string(format)))
+	}
+	if err != nil {
+		var typedErr E.Error
+		if errors.As(err, &typedErr) {
+			return typedErr.WithAttr(slog.String("file", fileName))
+		}
+		return ErrInvalidFile.Wrap(err).WithAttr(slog.String("file", fileName))
+	}
+	for _, value := range values {
+		if err := p.apply(value.path, value.value, SourceFile, fmt.Sprintf("%s:%d", fileName, value.line)); err != nil {
+			if E.Is(err, ErrNoSuchOption) {
+				err := ErrUnknownFileOption.
+					WithAttr(slog.String("file", fileName)).
+					WithAttr(slog.Int("line", value.line)).
+					WithAttr(slog.String("option", value.path.String()))
+				if suggestion := p.suggest(value.path); suggestion != "" {
+					err = err.WithAttr(slog.String("did_you_mean", suggestion))
+				}
+				return err
+			}
+			var typedErr E.Error
+			if errors.As(err, &typedErr) {
+				return typedErr.WithAttr(slog.String("file", fileName)).WithAttr(slog.Int("line", value.line))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func parseYAMLFile(data []byte) ([]fileValue, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, ErrInvalidFile.Wrap(err)
+	}
+	if len(document.Content) == 0 {
+		// Empty file
+		return nil, nil
+	}
+	var result []fileValue
+	if err := collectYAMLValues(document.Content[0], nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func collectYAMLValues(node *yaml.Node, path Path, result *[]fileValue) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if err := collectYAMLValues(node.Content[i+1], append(path.Copy(), parsePath(key.Value)...), result); err != nil {
+				return err
+			}
+		}
+		return nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind == yaml.AliasNode {
+				item = item.Alias
+			}
+			if item.Kind != yaml.ScalarNode {
+				return ErrInvalidFile.
+					Wrap(fmt.Errorf("lists may only contain scalar values")).
+					WithAttr(slog.Int("line", item.Line)).
+					WithAttr(slog.String("option", path.String()))
+			}
+			values = append(values, item.Value)
+		}
+		*result = append(*result, fileValue{path: path, value: reflect.ValueOf(values), line: node.Line})
+		return nil
+	case yaml.ScalarNode:
+		if node.Tag == "!!null" {
+			return nil
+		}
+		*result = append(*result, fileValue{path: path, value: reflect.ValueOf(node.Value), line: node.Line})
+		return nil
+	default:
+		return ErrInvalidFile.Wrap(fmt.Errorf("unexpected YAML node")).WithAttr(slog.Int("line", node.Line))
+	}
+}
+
+func parseTOMLFile(data []byte) ([]fileValue, error) {
+	parser := unstable.Parser{}
+	parser.Reset(data)
+	var result []fileValue
+	var prefix Path
+	for parser.NextExpression() {
+		expr := parser.Expression()
+		switch expr.Kind {
+		case unstable.Table:
+			prefix, _ = tomlKeyPath(&parser, expr.Key(), nil)
+		case unstable.ArrayTable:
+			_, line := tomlKeyPath(&parser, expr.Key(), nil)
+			return nil, ErrInvalidFile.
+				Wrap(fmt.Errorf("arrays of tables are not supported")).
+				WithAttr(slog.Int("line", line))
+		case unstable.KeyValue:
+			path, line := tomlKeyPath(&parser, expr.Key(), prefix)
+			if err := collectTOMLValues(&parser, expr.Value(), path, line, &result); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := parser.Error(); err != nil {
+		var parserErr *unstable.ParserError
+		if errors.As(err, &parserErr) && len(parserErr.Highlight) > 0 {
+			return nil, ErrInvalidFile.
+				Wrap(err).
+				WithAttr(slog.Int("line", parser.Shape(parser.Range(parserErr.Highlight)).Start.Line))
+		}
+		return nil, ErrInvalidFile.Wrap(err)
+	}
+	return result, nil
+}
+
+func tomlKeyPath(parser *unstable.Parser, key unstable.Iterator, prefix Path) (Path, int) {
+	path := prefix.Copy()
+	line := 0
+	for key.Next() {
+		node := key.Node()
+		if line == 0 {
+			line = parser.Shape(node.Raw).Start.Line
+		}
+		path = append(path, parsePath(string(node.Data))...)
+	}
+	return path, line
+}
+
+func collectTOMLValues(parser *unstable.Parser, node *unstable.Node, path Path, line int, result *[]fileValue) error {
+	switch node.Kind {
+	case unstable.InlineTable:
+		children := node.Children()
+		for children.Next() {
+			child := children.Node()
+			childPath, childLine := tomlKeyPath(parser, child.Key(), path)
+			if err := collectTOMLValues(parser, child.Value(), childPath, childLine, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	case unstable.Array:
+		var values []string
+		children := node.Children()
+		for children.Next() {
+			value, ok := tomlScalar(children.Node())
+			if !ok {
+				return ErrInvalidFile.
+					Wrap(fmt.Errorf("arrays may only contain scalar values")).
+					WithAttr(slog.Int("line", line)).
+					WithAttr(slog.String("option", path.String()))
+			}
+			values = append(values, value)
+		}
+		*result = append(*result, fileValue{path: path, value: reflect.ValueOf(values), line: line})
+		return nil
+	default:
+		value, ok := tomlScalar(node)
+		if !ok {
+			return ErrInvalidFile.
+				Wrap(fmt.Errorf("unsupported value type %s", node.Kind.String())).
+				WithAttr(slog.Int("line", line)).
+				WithAttr(slog.String("option", path.String()))
+		}
+		*result = append(*result, fileValue{path: path, value: reflect.ValueOf(value), line: line})
+		return nil
+	}
+}
+
+func tomlScalar(node *unstable.Node) (string, bool) {
+	switch node.Kind {
+	case unstable.String, unstable.Bool, unstable.Float, unstable.LocalDate, unstable.LocalTime,
+		unstable.LocalDateTime, unstable.DateTime:
+		return string(node.Data), true
+	case unstable.Integer:
+		return strings.ReplaceAll(string(node.Data), "_", ""), true
+	default:
+		return "", false
+	}
+}
+
+// ExtractFileOption removes the option with the specified name (e.g. --config) from the command line and returns the
+// remaining command line and the value of the option. Both the "--config file" and "--config=file" syntax are
+// supported.
+func ExtractFileOption(cmd []string, name string) ([]string, string, error) {
+	flag := "--" + name
+	result := make([]string, 0, len(cmd))
+	fileName := ""
+	for i := 0; i < len(cmd); i++ {
+		switch {
+		case cmd[i] == flag:
+			if i+1 >= len(cmd) {
+				return nil, "", ErrMissingOptionValue.WithAttr(slog.String("option", name))
+			}
+			fileName = cmd[i+1]
+			i++
+		case strings.HasPrefix(cmd[i], flag+"="):
+			fileName = strings.TrimPrefix(cmd[i], flag+"=")
+		default:
+			result = append(result, cmd[i])
+		}
+	}
+	return result, fileName, nil
+}
@@ -0,0 +1,103 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/internal/config"
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+type fileConfigStruct struct {
+	FieldA string   `config:"field-a"`
+	FieldB int      `config:"field-b"`
+	FieldC bool     `config:"field-c"`
+	FieldD []string `config:"field-d"`
+	Nested struct {
+		FieldE string `config:"field-e"`
+	} `config:"nested"`
+}
+
+func TestParser_ApplyFileData(t *testing.T) {
+	for name, tc := range map[string]struct {
+		format config.FileFormat
+		data   string
+	}{
+		"yaml": {
+			format: config.FileFormatYAML,
+			data:   "field-a: Hello world!\nfield_b: 42\nfield-c: true\nfield-d:\n  - a\n  - b\nnested:\n  field-e: nested\n",
+		},
+		"json": {
+			format: config.FileFormatJSON,
+			data:   `{"field-a": "Hello world!", "field-b": 42, "field-c": true, "field-d": ["a", "b"], "nested": {"field-e": "nested"}}`,
+		},
+		"toml": {
+			format: config.FileFormatTOML,
+			data:   "field-a = \"Hello world!\"\nfield-b = 4_2\nfield-c = true\nfield-d = [\"a\", \"b\"]\n[nested]\nfield-e = \"nested\"\n",
+		},
+		"toml-dotted": {
+			format: config.FileFormatTOML,
+			data:   "field-a = \"Hello world!\"\nfield-b = 42\nfield-c = true\nfield-d = [\"a\", \"b\"]\nnested.field-e = \"nested\"\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &fileConfigStruct{}
+			parser := config.New(cfg)
+			if err := parser.ApplyFileData("test."+string(tc.format), tc.format, []byte(tc.data)); err != nil {
+				t.Fatalf("ApplyFileData failed: %v", err)
+			}
+			if cfg.FieldA != "Hello world!" {
+				t.Fatalf("Incorrect field A: %s", cfg.FieldA)
+			}
+			if cfg.FieldB != 42 {
+				t.Fatalf("Incorrect field B: %d", cfg.FieldB)
+			}
+			if !cfg.FieldC {
+				t.Fatalf("Incorrect field C: %t", cfg.FieldC)
+			}
+			if len(cfg.FieldD) != 2 || cfg.FieldD[0] != "a" || cfg.FieldD[1] != "b" {
+				t.Fatalf("Incorrect field D: %v", cfg.FieldD)
+			}
+			if cfg.Nested.FieldE != "nested" {
+				t.Fatalf("Incorrect field E: %s", cfg.Nested.FieldE)
+			}
+		})
+	}
+}
+
+func TestParser_ApplyFileData_UnknownOption(t *testing.T) {
+	for name, tc := range map[string]struct {
+		format config.FileFormat
+		data   string
+	}{
+		"yaml": {
+			format: config.FileFormatYAML,
+			data:   "field-a: test\nnested:\n  field-x: test\n",
+		},
+		"toml": {
+			format: config.FileFormatTOML,
+			data:   "field-a = \"test\"\n[nested]\nfield-x = \"test\"\n",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := &fileConfigStruct{}
+			parser := config.New(cfg)
+			err := parser.ApplyFileData("test."+string(tc.format), tc.format, []byte(tc.data))
+			if err == nil {
+				t.Fatalf("Expected error, found none")
+			}
+			var typedErr E.Error
+			if !errors.As(err, &typedErr) || !E.Is(err, config.ErrUnknownFileOption) {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			line := typedErr.GetAttr("line")
+			if line == nil || line.Value.Int64() != 3 {
+				t.Fatalf("Incorrect line: %v", line)
+			}
+			file := typedErr.GetAttr("file")
+			if file == nil || file.Value.String() != "test."+string(tc.format) {
+				t.Fatalf("Incorrect file: %v", file)
+			}
+		})
+	}
+}
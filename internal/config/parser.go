@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"github.com/dns4acme/dns4acme/lang/E"
 	"log/slog"
+	"os"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -19,7 +21,7 @@ func New(cfg any) *Parser {
 	result := &Parser{
 		Root: val.Elem(),
 	}
-	if err := result.addTypeOptions(val, nil); err != nil {
+	if err := result.addTypeOptions(val, nil, nil); err != nil {
 		// TODO better error handling
 		panic(err)
 	}
@@ -35,17 +37,24 @@ func parsePath(path string) []string {
 type Parser struct {
 	Root    reflect.Value
 	Options []Option
+	// Args contains the positional arguments following the -- terminator in the last ApplyCMD call.
+	Args []string
 }
 
-func (p *Parser) addTypeOptions(t reflect.Value, path Path) error {
-	typedT := reflect.ValueOf(t.Interface())
+func (p *Parser) addTypeOptions(t reflect.Value, path Path, keys []string) error {
+	// Unwrap interfaces (e.g. map values) to their dynamic type. Using Elem() instead of Interface() keeps this working
+	// for fields promoted from unexported embedded structs.
+	typedT := t
+	if t.Kind() == reflect.Interface {
+		typedT = t.Elem()
+	}
 	switch typedT.Kind() {
 	case reflect.Ptr:
 		if t.IsNil() {
 			newValue := reflect.New(t.Type().Elem()).Interface()
 			t.Set(reflect.ValueOf(newValue))
 		}
-		return p.addTypeOptions(t.Elem(), path)
+		return p.addTypeOptions(t.Elem(), path, keys)
 	case reflect.Struct:
 		for i := 0; i < t.NumField(); i++ {
 			field := t.Field(i)
@@ -53,9 +62,10 @@ func (p *Parser) addTypeOptions(t reflect.Value, path Path) error {
 			configTag := fieldType.Tag.Get("config")
 			if configTag != "" {
 				newPath := append(path.Copy(), parsePath(configTag)...)
+				newKeys := append(slices.Clone(keys), configTag)
 				method := field.MethodByName("UnmarshalText")
 				if field.Kind() == reflect.Struct && !method.IsValid() {
-					if err := p.addTypeOptions(t.Field(i), newPath); err != nil {
+					if err := p.addTypeOptions(t.Field(i), newPath, newKeys); err != nil {
 						// TODO better error handling
 						return err
 					}
@@ -66,16 +76,19 @@ func (p *Parser) addTypeOptions(t reflect.Value, path Path) error {
 					}
 					p.Options = append(p.Options, Option{
 						Path:        newPath,
+						Keys:        newKeys,
 						Value:       field,
 						Field:       fieldType,
 						Default:     fieldType.Tag.Get("default"),
 						Description: fieldType.Tag.Get("description"),
+						Sensitive:   fieldType.Tag.Get("sensitive") == "true",
+						FileAllowed: fieldType.Tag.Get("file") == "true",
 					})
 				}
 			} else if fieldType.Anonymous {
 				switch (fieldType.Type).Kind() {
 				case reflect.Struct:
-					if err := p.addTypeOptions(field, path); err != nil {
+					if err := p.addTypeOptions(field, path, keys); err != nil {
 						// TODO better error handling
 						return err
 					}
@@ -85,8 +98,9 @@ func (p *Parser) addTypeOptions(t reflect.Value, path Path) error {
 					}
 					for _, key := range field.MapKeys() {
 						newPath := append(path.Copy(), parsePath(key.String())...)
+						newKeys := append(slices.Clone(keys), key.String())
 						// TODO check if the value is addressable, otherwise the Apply will panic.
-						if err := p.addTypeOptions(field.MapIndex(key), newPath); err != nil {
+						if err := p.addTypeOptions(field.MapIndex(key), newPath, newKeys); err != nil {
 							// TODO better error handling
 							return err
 						}
@@ -105,18 +119,27 @@ func (p *Parser) addTypeOptions(t reflect.Value, path Path) error {
 }
 
 func (p *Parser) ApplyDefaults() error {
-	for _, opt := range p.Options {
+	for i, opt := range p.Options {
 		if opt.Default != "" {
 			if err := opt.apply(reflect.ValueOf(opt.Default)); err != nil {
 				// TODO better error handling
 				return err
 			}
+			p.Options[i].Source = SourceDefault
+			p.Options[i].SourceDetail = ""
 		}
 	}
 	return nil
 }
 
+// ApplyEnv applies all environment variables starting with prefix. Options tagged with file:"true" can also be read
+// from a file by appending _FILE to the variable name, e.g. DNS4ACME_KUBERNETES_PASSWORD_FILE. Setting both the
+// variable and its _FILE counterpart is an error.
 func (p *Parser) ApplyEnv(prefix string, env []string) error {
+	names := make(map[string]struct{}, len(env))
+	for _, variable := range env {
+		names[strings.SplitN(variable, "=", 2)[0]] = struct{}{}
+	}
 	for _, variable := range env {
 		fields := strings.SplitN(variable, "=", 2)
 		if len(fields) != 2 {
@@ -130,51 +153,170 @@ func (p *Parser) ApplyEnv(prefix string, env []string) error {
 		}
 		key = strings.TrimPrefix(key, prefix)
 		path := parsePath(key)
-		if err := p.Apply(path, reflect.ValueOf(value)); err != nil {
+		if err := p.apply(path, reflect.ValueOf(value), SourceEnv, fields[0]); err != nil {
 			if !E.Is(err, ErrNoSuchOption) {
 				return err
 			}
+			if err := p.applyEnvFile(prefix, fields[0], path, value, names); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// applyEnvFile handles the _FILE variant of an environment variable if the option allows it. Variables that do not
+// match any option are ignored.
+func (p *Parser) applyEnvFile(prefix string, name string, path Path, fileName string, names map[string]struct{}) error {
+	if len(path) < 2 || !strings.EqualFold(path[len(path)-1], "file") {
+		return nil
+	}
+	optionPath := path[:len(path)-1]
+	opt, ok := p.findOption(optionPath)
+	if !ok || !opt.FileAllowed {
+		return nil
+	}
+	plainName := name[:len(name)-len("_FILE")]
+	if _, ok := names[plainName]; ok {
+		return ErrDuplicateOption.
+			WithAttr(slog.String("option", optionPath.String())).
+			WithAttr(slog.String("variable", plainName)).
+			WithAttr(slog.String("file_variable", name))
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return ErrFileReadFailed.Wrap(err).
+			WithAttr(slog.String("option", optionPath.String())).
+			WithAttr(slog.String("file", fileName))
+	}
+	// Files written by editors or "echo" typically end in a newline, which is never part of the secret.
+	value := strings.TrimRight(string(data), "\r\n")
+	return p.apply(optionPath, reflect.ValueOf(value), SourceEnv, name)
+}
+
+func (p *Parser) findOption(path Path) (Option, bool) {
+	index := p.findOptionIndex(path)
+	if index < 0 {
+		return Option{}, false
+	}
+	return p.Options[index], true
+}
+
+func (p *Parser) findOptionIndex(path Path) int {
+	for i, opt := range p.Options {
+		if opt.Path.Equals(path) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ApplyCMD applies the command line flags in cmd. The first element is the program name and is skipped. The following
+// syntax is supported:
+//
+//	--option value       sets the option, the value may start with a single dash (e.g. a negative number)
+//	--option=value       sets the option, the value may start with any character
+//	--flag [true|false]  sets a boolean option, the value is optional and defaults to true
+//	--no-flag            sets a boolean option to false
+//	--list a --list b    repeated flags append to list options instead of replacing them
+//	--                   stops processing flags, the remaining arguments are stored in Args
 func (p *Parser) ApplyCMD(cmd []string) error {
 	if len(cmd) == 0 {
-		// TODO better error handling
-		return fmt.Errorf("invalid command line arguments")
+		return ErrNoCLIOptions
 	}
 	cmd = cmd[1:]
+	p.Args = nil
 
+	seen := map[int]struct{}{}
 	for len(cmd) > 0 {
-		if !strings.HasPrefix(cmd[0], "--") {
-			return fmt.Errorf("expected flag, found: %s", cmd[0])
+		arg := cmd[0]
+		cmd = cmd[1:]
+		if arg == "--" {
+			p.Args = cmd
+			return nil
 		}
-		key := parsePath(strings.TrimPrefix(cmd[0], "--"))
-		if len(cmd) > 1 && !strings.HasPrefix(cmd[1], "--") {
-			if err := p.Apply(key, reflect.ValueOf(cmd[1])); err != nil {
-				// TODO better error handling
-				return err
+		if !strings.HasPrefix(arg, "--") {
+			return ErrUnexpectedCLIOption.WithAttr(slog.String("option", arg))
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		flag := "--" + name
+		index, negated := p.findFlag(parsePath(name))
+		if index < 0 {
+			err := ErrNoSuchOption.WithAttr(slog.String("option", flag))
+			if suggestion := p.suggest(parsePath(name)); suggestion != "" {
+				err = err.WithAttr(slog.String("did_you_mean", "--"+suggestion))
 			}
-			cmd = cmd[2:]
-		} else {
-			if err := p.Apply(key, reflect.ValueOf(true)); err != nil {
-				// TODO better error handling
-				return err
+			return err
+		}
+		opt := p.Options[index]
+		switch {
+		case negated:
+			if hasValue {
+				return ErrUnexpectedCLIOption.WithAttr(slog.String("option", flag)).WithAttr(slog.String("value", value))
+			}
+			value = "false"
+		case hasValue:
+		case opt.Value.Kind() == reflect.Bool:
+			value = "true"
+			if len(cmd) > 0 {
+				if _, err := strconv.ParseBool(cmd[0]); err == nil {
+					value = cmd[0]
+					cmd = cmd[1:]
+				}
+			}
+		default:
+			if len(cmd) == 0 || strings.HasPrefix(cmd[0], "--") {
+				return ErrMissingOptionValue.WithAttr(slog.String("option", flag))
 			}
+			value = cmd[0]
 			cmd = cmd[1:]
 		}
+
+		newValue := reflect.ValueOf(value)
+		if _, ok := seen[index]; ok && opt.Value.Kind() == reflect.Slice && opt.Value.Type().Elem().Kind() == reflect.String {
+			newValue = reflect.AppendSlice(
+				reflect.AppendSlice(reflect.MakeSlice(opt.Value.Type(), 0, opt.Value.Len()), opt.Value),
+				reflect.ValueOf(strings.Split(value, ",")).Convert(opt.Value.Type()),
+			)
+		}
+		seen[index] = struct{}{}
+		if err := p.apply(opt.Path, newValue, SourceFlag, flag); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// findFlag returns the index of the option matching the flag path. If the flag is a negated boolean flag (--no-x), the
+// second return value is true. If no option matches, the index is -1.
+func (p *Parser) findFlag(path Path) (int, bool) {
+	if index := p.findOptionIndex(path); index >= 0 {
+		return index, false
+	}
+	if len(path) > 1 && strings.EqualFold(path[0], "no") {
+		if index := p.findOptionIndex(path[1:]); index >= 0 && p.Options[index].Value.Kind() == reflect.Bool {
+			return index, true
+		}
+	}
+	return -1, false
+}
+
 func (p *Parser) Apply(path Path, value reflect.Value) error {
+	return p.apply(path, value, SourceAPI, "")
+}
+
+func (p *Parser) apply(path Path, value reflect.Value, source Source, sourceDetail string) error {
 	// TODO the performance of this is less than ideal
-	for _, opt := range p.Options {
+	for i, opt := range p.Options {
 		if !opt.Path.Equals(path) {
 			continue
 		}
-		return opt.apply(value)
+		if err := opt.apply(value); err != nil {
+			return err
+		}
+		p.Options[i].Source = source
+		p.Options[i].SourceDetail = sourceDetail
+		return nil
 	}
 	// TODO better error handling
 	return ErrNoSuchOption.WithAttr(slog.String("option", path.String()))
@@ -268,12 +410,42 @@ func (p Path) String() string {
 	return strings.Join(p, "-")
 }
 
+// Source describes where the current value of an option came from.
+type Source string
+
+const (
+	// SourceUnset indicates that the option has not been set.
+	SourceUnset Source = ""
+	// SourceDefault indicates that the option has been set from the default struct tag.
+	SourceDefault Source = "default"
+	// SourceFile indicates that the option has been set from a configuration file.
+	SourceFile Source = "file"
+	// SourceEnv indicates that the option has been set from an environment variable.
+	SourceEnv Source = "env"
+	// SourceFlag indicates that the option has been set from a command line flag.
+	SourceFlag Source = "flag"
+	// SourceAPI indicates that the option has been set by calling Parser.Apply directly.
+	SourceAPI Source = "api"
+)
+
 type Option struct {
-	Path        Path
+	Path Path
+	// Keys contains the config tags (or map keys) leading up to this option, which is used to build nested
+	// configuration files.
+	Keys        []string
 	Value       reflect.Value
 	Field       reflect.StructField
 	Default     string
 	Description string
+	// Sensitive is set from the sensitive:"true" struct tag and indicates that the value must not be printed.
+	Sensitive bool
+	// FileAllowed is set from the file:"true" struct tag and indicates that the value may be read from a file using
+	// the _FILE environment variable suffix.
+	FileAllowed bool
+	// Source indicates where the current value came from.
+	Source Source
+	// SourceDetail contains the environment variable, flag or file position the value came from.
+	SourceDetail string
 }
 
 func (o Option) apply(value reflect.Value) error {
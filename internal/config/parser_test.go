@@ -2,6 +2,9 @@ package config_test
 
 import (
 	"github.com/dns4acme/dns4acme/internal/config"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -53,3 +56,39 @@ func TestParser_Apply_NestedMap(t *testing.T) {
 		t.Fatalf("Incorrect field E: %s", cfg.NestedMap["test1"].FieldE)
 	}
 }
+
+type envFileConfigStruct struct {
+	Password string `config:"password" file:"true"`
+	Username string `config:"username"`
+}
+
+func TestParser_ApplyEnv_File(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(fileName, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatalf("Failed to write password file: %v", err)
+	}
+
+	cfg := &envFileConfigStruct{}
+	parser := config.New(cfg)
+	if err := parser.ApplyEnv("TEST_", []string{"TEST_PASSWORD_FILE=" + fileName, "TEST_USERNAME_FILE=" + fileName}); err != nil {
+		t.Fatalf("ApplyEnv failed: %v", err)
+	}
+	if cfg.Password != "s3cr3t" {
+		t.Fatalf("Incorrect password: %s", cfg.Password)
+	}
+	if cfg.Username != "" {
+		t.Fatalf("The username was read from a file even though it is not allowed: %s", cfg.Username)
+	}
+
+	cfg = &envFileConfigStruct{}
+	err := config.New(cfg).ApplyEnv("TEST_", []string{"TEST_PASSWORD=foo", "TEST_PASSWORD_FILE=" + fileName})
+	if !E.Is(err, config.ErrDuplicateOption) {
+		t.Fatalf("Setting both the variable and the _FILE variant did not result in an error: %v", err)
+	}
+
+	cfg = &envFileConfigStruct{}
+	err = config.New(cfg).ApplyEnv("TEST_", []string{"TEST_PASSWORD_FILE=" + fileName + ".nonexistent"})
+	if !E.Is(err, config.ErrFileReadFailed) {
+		t.Fatalf("Reading a nonexistent file did not result in an error: %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import "strings"
+
+// suggest returns the name of the option closest to path, or an empty string if no option is similar enough to be a
+// likely typo.
+func (p *Parser) suggest(path Path) string {
+	name := strings.ToLower(path.String())
+	best := ""
+	bestDistance := max(2, len(name)/3) + 1
+	for _, opt := range p.Options {
+		candidate := strings.ToLower(opt.Path.String())
+		if distance := levenshtein(name, candidate); distance < bestDistance {
+			best = opt.Path.String()
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,48 @@
+// Package secret generates and validates TSIG secrets and generates random identifiers for update keys.
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+// ErrGenerationFailed indicates that the system random number generator failed.
+var ErrGenerationFailed = E.New("SECRET_GENERATION_FAILED", "failed to generate secret")
+
+// ErrInvalidSecret indicates that a TSIG secret is not base64-encoded.
+var ErrInvalidSecret = E.New("INVALID_SECRET", "invalid TSIG secret, must be base64-encoded")
+
+// Generate creates a random base64-encoded 256-bit TSIG secret.
+func Generate() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", ErrGenerationFailed.Wrap(err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// Validate checks that a TSIG secret can be decoded the way the DNS server decodes it when verifying updates.
+func Validate(secret string) error {
+	data, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return ErrInvalidSecret.Wrap(err)
+	}
+	if len(data) == 0 {
+		return ErrInvalidSecret
+	}
+	return nil
+}
+
+// GenerateUUID creates a random version 4 UUID, which is also a valid DNS label.
+func GenerateUUID() (string, error) {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return "", ErrGenerationFailed.Wrap(err)
+	}
+	data[6] = (data[6] & 0x0f) | 0x40
+	data[8] = (data[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:16]), nil
+}
@@ -0,0 +1,7 @@
+package libdnsprovider
+
+import (
+	"github.com/dns4acme/dns4acme/lang/E"
+)
+
+var ErrUnsupportedRecord = E.New("LIBDNS_UNSUPPORTED_RECORD", "DNS4ACME only serves TXT records named _acme-challenge")
@@ -0,0 +1,153 @@
+// Package libdnsprovider implements the libdns interfaces on top of a DNS4ACME server, so that Caddy, certmagic and
+// other libdns users can solve DNS-01 challenges with an update key.
+package libdnsprovider
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/dns4acme/dns4acme/client"
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+// recordTTL is the TTL DNS4ACME answers TXT queries with.
+const recordTTL = 60 * time.Second
+
+// Provider sends TSIG-signed updates and queries to a DNS4ACME server. The zero value is not usable, Server, KeyName
+// and Secret must be set.
+type Provider struct {
+	// Server is the address of the DNS4ACME server in the host:port form.
+	Server string `json:"server,omitempty"`
+	// KeyName is the name of the update key.
+	KeyName string `json:"key_name,omitempty"`
+	// Secret is the base64-encoded secret of the update key.
+	Secret string `json:"secret,omitempty"`
+	// Algorithm is the TSIG algorithm, either hmac-sha256 (default) or hmac-sha512.
+	Algorithm string `json:"algorithm,omitempty"`
+	// TCP sends all messages over TCP instead of trying UDP first.
+	TCP bool `json:"tcp,omitempty"`
+	// Timeout is the maximum time to wait for a single response. Defaults to 10 seconds.
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// GetRecords returns the ACME challenge TXT records of zone. zone may either be the zone of the domain, e.g.
+// example.com., or the delegated _acme-challenge.example.com. zone.
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	fqdn := challengeFQDN(zone)
+	answers, err := c.Answers(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]libdns.Record, len(answers))
+	for i, answer := range answers {
+		records[i] = newRecord(fqdn, zone, answer)
+	}
+	return records, nil
+}
+
+// AppendRecords adds the TXT records to the ACME challenge answers.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	appended := make([]libdns.Record, 0, len(recs))
+	for _, rec := range recs {
+		rr := rec.RR()
+		fqdn, err := recordFQDN(rr, zone)
+		if err != nil {
+			return appended, err
+		}
+		if err := c.Present(ctx, fqdn, rr.Data); err != nil {
+			return appended, err
+		}
+		appended = append(appended, newRecord(fqdn, zone, rr.Data))
+	}
+	return appended, nil
+}
+
+// DeleteRecords removes the TXT records from the ACME challenge answers. Records with an empty value delete all answers
+// of their name. Records that do not exist are ignored.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+	c, err := p.client()
+	if err != nil {
+		return nil, err
+	}
+	var deleted []libdns.Record
+	for _, rec := range recs {
+		rr := rec.RR()
+		if (rr.Type != "" && rr.Type != "TXT") || (rr.TTL != 0 && rr.TTL != recordTTL) {
+			// DNS4ACME cannot have records matching this one.
+			continue
+		}
+		rr.Type = "TXT"
+		fqdn, err := recordFQDN(rr, zone)
+		if err != nil {
+			return deleted, err
+		}
+		answers, err := c.Answers(ctx, fqdn)
+		if err != nil {
+			return deleted, err
+		}
+		for _, answer := range answers {
+			if rr.Data != "" && answer != rr.Data {
+				continue
+			}
+			if err := c.CleanUp(ctx, fqdn, answer); err != nil {
+				return deleted, err
+			}
+			deleted = append(deleted, newRecord(fqdn, zone, answer))
+		}
+	}
+	return deleted, nil
+}
+
+func (p *Provider) client() (client.Client, error) {
+	return client.New(client.Config{
+		Server:    p.Server,
+		KeyName:   p.KeyName,
+		Secret:    p.Secret,
+		Algorithm: p.Algorithm,
+		TCP:       p.TCP,
+		Timeout:   p.Timeout,
+	})
+}
+
+// challengeFQDN returns the name of the ACME challenge record of zone, which may already be the challenge name.
+func challengeFQDN(zone string) string {
+	name := strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(zone), "."), "_acme-challenge.")
+	return "_acme-challenge." + name + "."
+}
+
+// recordFQDN returns the absolute name of rr and checks that DNS4ACME can serve it.
+func recordFQDN(rr libdns.RR, zone string) (string, error) {
+	fqdn := strings.ToLower(libdns.AbsoluteName(rr.Name, dns.Fqdn(zone)))
+	if rr.Type != "TXT" || !strings.HasPrefix(fqdn, "_acme-challenge.") {
+		return "", ErrUnsupportedRecord.
+			WithAttr(slog.String("name", fqdn)).
+			WithAttr(slog.String("type", rr.Type))
+	}
+	return fqdn, nil
+}
+
+func newRecord(fqdn string, zone string, value string) libdns.TXT {
+	return libdns.TXT{
+		Name: libdns.RelativeName(fqdn, dns.Fqdn(strings.ToLower(zone))),
+		TTL:  recordTTL,
+		Text: value,
+	}
+}
+
+// Interface guards.
+var (
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+)
@@ -0,0 +1,122 @@
+package libdnsprovider_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/netip"
+	"testing"
+
+	"github.com/dns4acme/dns4acme/backend"
+	"github.com/dns4acme/dns4acme/backend/inmemory"
+	"github.com/dns4acme/dns4acme/core"
+	"github.com/dns4acme/dns4acme/internal/testlogger"
+	"github.com/dns4acme/dns4acme/lang/E"
+	"github.com/dns4acme/dns4acme/libdnsprovider"
+	"github.com/libdns/libdns"
+)
+
+func startProvider(t *testing.T) *libdnsprovider.Provider {
+	t.Helper()
+	secret := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	addrPort := netip.MustParseAddrPort("127.0.0.1:30073")
+	provider, err := inmemory.Config{
+		Keys: map[string]*backend.ProviderKeyResponse{
+			"caddy": {Secret: secret, Zones: []string{"example.com"}},
+		},
+		Zones: map[string]*backend.ProviderZoneResponse{
+			"example.com": {},
+		},
+	}.Build(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to create backend: %v", err)
+	}
+	srv, err := core.New(core.Config{Listen: &addrPort, Nameservers: []string{"ns.example.com"}}, provider, testlogger.New(t))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	running, err := srv.Start(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := running.Stop(context.Background()); err != nil {
+			t.Fatalf("Failed to stop server: %v", err)
+		}
+	})
+	return &libdnsprovider.Provider{Server: addrPort.String(), KeyName: "caddy", Secret: secret}
+}
+
+func texts(t *testing.T, records []libdns.Record, name string) []string {
+	t.Helper()
+	result := make([]string, len(records))
+	for i, record := range records {
+		rr := record.RR()
+		if rr.Type != "TXT" || rr.Name != name {
+			t.Fatalf("Incorrect record: %v", rr)
+		}
+		result[i] = rr.Data
+	}
+	return result
+}
+
+func TestProvider(t *testing.T) {
+	provider := startProvider(t)
+	ctx := t.Context()
+
+	appended, err := provider.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: "first"},
+		libdns.TXT{Name: "_acme-challenge", Text: "second"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to append records: %v", err)
+	}
+	if len(appended) != 2 {
+		t.Fatalf("Incorrect appended records: %v", appended)
+	}
+
+	// certmagic uses the delegated zone if _acme-challenge is delegated to DNS4ACME.
+	records, err := provider.GetRecords(ctx, "_acme-challenge.example.com.")
+	if err != nil {
+		t.Fatalf("Failed to get records: %v", err)
+	}
+	if result := texts(t, records, "@"); len(result) != 2 || result[0] != "first" || result[1] != "second" {
+		t.Fatalf("Incorrect records: %v", result)
+	}
+
+	deleted, err := provider.DeleteRecords(ctx, "example.com.", []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: "first"},
+		libdns.TXT{Name: "_acme-challenge", Text: "missing"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to delete records: %v", err)
+	}
+	if result := texts(t, deleted, "_acme-challenge"); len(result) != 1 || result[0] != "first" {
+		t.Fatalf("Incorrect deleted records: %v", result)
+	}
+
+	// An empty value deletes all records of the name.
+	deleted, err = provider.DeleteRecords(ctx, "example.com.", []libdns.Record{libdns.RR{Name: "_acme-challenge"}})
+	if err != nil {
+		t.Fatalf("Failed to delete records: %v", err)
+	}
+	if result := texts(t, deleted, "_acme-challenge"); len(result) != 1 || result[0] != "second" {
+		t.Fatalf("Incorrect deleted records: %v", result)
+	}
+	if records, err = provider.GetRecords(ctx, "example.com."); err != nil || len(records) != 0 {
+		t.Fatalf("Incorrect records after deletion: %v (%v)", records, err)
+	}
+}
+
+func TestProviderUnsupportedRecords(t *testing.T) {
+	provider := startProvider(t)
+	ctx := t.Context()
+
+	for _, record := range []libdns.Record{
+		libdns.TXT{Name: "www", Text: "value"},
+		libdns.RR{Name: "_acme-challenge", Type: "A", Data: "192.0.2.1"},
+	} {
+		if _, err := provider.AppendRecords(ctx, "example.com.", []libdns.Record{record}); !E.Is(err, libdnsprovider.ErrUnsupportedRecord) {
+			t.Fatalf("Expected an unsupported record error for %v, got %v", record.RR(), err)
+		}
+	}
+}